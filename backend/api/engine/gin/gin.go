@@ -55,7 +55,7 @@ func (g *GinEngine) Initialize(r router.Router) {
 		corsConfig := cors.Config{
 			AllowOrigins:     g.config.Security.AllowedOrigins,
 			AllowMethods:     g.config.Security.AllowedMethods,
-			AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+			AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Session-ID", "X-Host-Token", "X-Team-Token", "Last-Event-ID"},
 			ExposeHeaders:    []string{"Content-Length"},
 			AllowCredentials: true,
 			MaxAge:           12 * time.Hour,
@@ -1,10 +1,11 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/jungtechou/valomap/api/handler"
@@ -14,30 +15,37 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// RevalidatedHeader reports when a served image was last checked against upstream
+const RevalidatedHeader = "X-Cache-Revalidated"
+
 // CacheHandler handles requests for cached resources
 type CacheHandler struct {
-	imageCachePath string
-	cacheService   cache.ImageCache
+	cacheService cache.ImageCache
 }
 
 // NewHandler creates a new cache handler
 func NewHandler(cacheService cache.ImageCache) Handler {
 	return &CacheHandler{
-		imageCachePath: "/home/appuser/images-cache", // Must match the path in the cache service
-		cacheService:   cacheService,
+		cacheService: cacheService,
 	}
 }
 
 // GetCachedImage godoc
 // @Summary Get a cached image
-// @Description Returns a cached image file by filename, serving content directly from the cache directory
+// @Description Returns a cached image file by filename, serving content directly from the cache directory. With w, h, fit or format a resized or converted variant is served instead, rendered on first request and cached on disk. Images are never scaled up, and WebP images are passed through unchanged.
 // @Tags cache
 // @Accept json
-// @Produce image/jpeg,image/png,image/gif
+// @Produce image/jpeg,image/png,image/gif,image/webp
 // @Param filename path string true "Image filename" example:"map_split.jpg"
+// @Param w query int false "Maximum width in pixels, one of cache.variant_widths" minimum(1) maximum(4096)
+// @Param h query int false "Maximum height in pixels, one of cache.variant_heights" minimum(1) maximum(4096)
+// @Param fit query string false "How the image fits a box given by w and h" Enums(contain, cover, fill) default(contain)
+// @Param format query string false "Output format" Enums(jpeg, png, webp)
 // @Success 200 {file} file "The requested image file"
-// @Failure 400 {object} map[string]string "Bad request - invalid filename"
+// @Failure 400 {object} map[string]string "Bad request - invalid filename or variant"
 // @Failure 404 {object} map[string]string "Image not found in cache"
+// @Failure 422 {object} map[string]string "The image cannot be converted to the requested format"
+// @Failure 500 {object} map[string]string "Cached image could not be read"
 // @Router /cache/{filename} [get]
 func (h *CacheHandler) GetCachedImage(c *gin.Context) {
 	logger := logrus.WithField("handler", "GetCachedImage")
@@ -49,30 +57,42 @@ func (h *CacheHandler) GetCachedImage(c *gin.Context) {
 		return
 	}
 
-	// Ensure the filename is safe (no path traversal)
-	cleanFilename := filepath.Base(filename)
-	if cleanFilename != filename {
-		logger.WithFields(logrus.Fields{
-			"original": filename,
-			"cleaned":  cleanFilename,
-		}).Warn("Attempted path traversal in cache request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filename"})
+	opts, err := variantOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Build the full path to the cached file
-	filePath := filepath.Join(h.imageCachePath, cleanFilename)
-
-	// Check if file exists
-	fileInfo, err := os.Stat(filePath)
-	if os.IsNotExist(err) {
-		logger.WithField("file", filePath).Info("Requested cache file not found")
+	// Look the file up in the cache, which rejects names outside its directory
+	var file *cache.CachedFile
+	if opts.IsZero() && opts.Fit == "" {
+		file, err = h.cacheService.Resolve(filename)
+	} else {
+		file, err = h.cacheService.Variant(filename, opts)
+	}
+	switch {
+	case errors.Is(err, cache.ErrInvalidVariant):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, cache.ErrUnsupportedVariant):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, cache.ErrInvalidName):
+		logger.WithField("filename", filename).Warn("Attempted path traversal in cache request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filename"})
+		return
+	case errors.Is(err, cache.ErrNotCached):
+		logger.WithField("filename", filename).Info("Requested cache file not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
+	case err != nil:
+		logger.WithError(err).Error("Failed to resolve cached file")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read cached file"})
+		return
 	}
 
 	// Get file extension to determine content type
-	ext := filepath.Ext(cleanFilename)
+	ext := filepath.Ext(file.Path)
 	var contentType string
 	switch strings.ToLower(ext) {
 	case ".jpg", ".jpeg":
@@ -90,12 +110,15 @@ func (h *CacheHandler) GetCachedImage(c *gin.Context) {
 	// Set cache control headers
 	c.Header("Cache-Control", "public, max-age=604800") // 7 days
 	c.Header("Content-Type", contentType)
-	c.Header("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-	c.Header("ETag", fmt.Sprintf(`"%x-%x"`, fileInfo.ModTime().Unix(), fileInfo.Size()))
+	c.Header("Content-Length", fmt.Sprintf("%d", file.Size))
+	etag := fmt.Sprintf(`"%x-%x"`, file.ModTime.Unix(), file.Size)
+	c.Header("ETag", etag)
+	if !file.RevalidatedAt.IsZero() {
+		c.Header(RevalidatedHeader, file.RevalidatedAt.UTC().Format(http.TimeFormat))
+	}
 
 	// Check If-None-Match header for 304 responses
 	if match := c.GetHeader("If-None-Match"); match != "" {
-		etag := fmt.Sprintf(`"%x-%x"`, fileInfo.ModTime().Unix(), fileInfo.Size())
 		if match == etag {
 			c.Status(http.StatusNotModified)
 			return
@@ -103,12 +126,70 @@ func (h *CacheHandler) GetCachedImage(c *gin.Context) {
 	}
 
 	// Serve the file
-	c.File(filePath)
+	c.File(file.Path)
+}
+
+// variantOptions reads the requested variant from the w, h, fit and format query parameters
+func variantOptions(c *gin.Context) (cache.VariantOptions, error) {
+	opts := cache.VariantOptions{
+		Fit:    c.Query("fit"),
+		Format: c.Query("format"),
+	}
+	dimensions := []struct {
+		param string
+		value *int
+	}{{"w", &opts.Width}, {"h", &opts.Height}}
+	for _, dimension := range dimensions {
+		query := c.Query(dimension.param)
+		if query == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(query)
+		if err != nil || parsed < 1 || parsed > cache.MaxVariantDimension {
+			return opts, fmt.Errorf("%s must be an integer between 1 and %d", dimension.param, cache.MaxVariantDimension)
+		}
+		*dimension.value = parsed
+	}
+	return opts, nil
+}
+
+// GetCacheStats godoc
+// @Summary Get image cache statistics
+// @Description Returns the number and size of cached images, the configured limits, and hit, miss and eviction counters since startup
+// @Tags cache
+// @Produce json
+// @Success 200 {object} cache.Metrics "Successfully retrieved cache statistics"
+// @Router /cache/stats [get]
+func (h *CacheHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cacheService.Metrics())
+}
+
+// ListCachedImages godoc
+// @Summary List cached images
+// @Description Lists the cached images, most recently used first, with their upstream URL and when they were cached and last revalidated
+// @Tags cache
+// @Produce json
+// @Success 200 {array} cache.ImageInfo "Successfully listed cached images"
+// @Router /cache/images [get]
+func (h *CacheHandler) ListCachedImages(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cacheService.Images())
 }
 
 // GetRouteInfos implements handler.Handler interface
 func (h *CacheHandler) GetRouteInfos() []handler.RouteInfo {
 	return []handler.RouteInfo{
+		{
+			Method:      http.MethodGet,
+			Path:        "/cache/stats",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.GetCacheStats,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/cache/images",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.ListCachedImages,
+		},
 		{
 			Method:      http.MethodGet,
 			Path:        "/cache/:filename",
@@ -122,4 +203,6 @@ func (h *CacheHandler) GetRouteInfos() []handler.RouteInfo {
 type Handler interface {
 	handler.Handler
 	GetCachedImage(c *gin.Context)
+	GetCacheStats(c *gin.Context)
+	ListCachedImages(c *gin.Context)
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	domain "github.com/jungtechou/valomap/domain/map"
@@ -14,6 +15,7 @@ import (
 	"github.com/jungtechou/valomap/service/cache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock the cache service
@@ -36,6 +38,32 @@ func (m *MockCacheService) CacheMapImages(ctx ctx.CTX, maps []domain.Map) ([]dom
 	return args.Get(0).([]domain.Map), args.Error(1)
 }
 
+func (m *MockCacheService) Resolve(name string) (*cache.CachedFile, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*cache.CachedFile), args.Error(1)
+}
+
+func (m *MockCacheService) Variant(name string, opts cache.VariantOptions) (*cache.CachedFile, error) {
+	args := m.Called(name, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*cache.CachedFile), args.Error(1)
+}
+
+func (m *MockCacheService) Metrics() cache.Metrics {
+	args := m.Called()
+	return args.Get(0).(cache.Metrics)
+}
+
+func (m *MockCacheService) Images() []cache.ImageInfo {
+	args := m.Called()
+	return args.Get(0).([]cache.ImageInfo)
+}
+
 func (m *MockCacheService) Shutdown() {
 	m.Called()
 }
@@ -60,10 +88,54 @@ func (m *MockImageCache) CacheMapImages(ctx ctx.CTX, maps []domain.Map) ([]domai
 	return args.Get(0).([]domain.Map), args.Error(1)
 }
 
+func (m *MockImageCache) Resolve(name string) (*cache.CachedFile, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*cache.CachedFile), args.Error(1)
+}
+
+func (m *MockImageCache) Variant(name string, opts cache.VariantOptions) (*cache.CachedFile, error) {
+	args := m.Called(name, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*cache.CachedFile), args.Error(1)
+}
+
+func (m *MockImageCache) Metrics() cache.Metrics {
+	args := m.Called()
+	return args.Get(0).(cache.Metrics)
+}
+
+func (m *MockImageCache) Images() []cache.ImageInfo {
+	args := m.Called()
+	return args.Get(0).([]cache.ImageInfo)
+}
+
 func (m *MockImageCache) Shutdown() {
 	m.Called()
 }
 
+// dirCache stands in for an image cache that has indexed every file in dir
+type dirCache struct {
+	cache.ImageCache
+	dir string
+}
+
+func (d dirCache) Resolve(name string) (*cache.CachedFile, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return nil, cache.ErrInvalidName
+	}
+	path := filepath.Join(d.dir, name)
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, cache.ErrNotCached
+	}
+	return &cache.CachedFile{Path: path, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
 func setupTestHandler() (*CacheHandler, string, error) {
 	// Create a temporary directory for test cache files
 	tempDir, err := os.MkdirTemp("", "cache-handler-test")
@@ -71,13 +143,9 @@ func setupTestHandler() (*CacheHandler, string, error) {
 		return nil, "", err
 	}
 
-	// Create a mock cache service
-	mockService := &MockCacheService{}
-
-	// Create the handler with the test path
+	// Create the handler with a cache serving the test directory
 	handler := &CacheHandler{
-		imageCachePath: tempDir,
-		cacheService:   mockService,
+		cacheService: dirCache{dir: tempDir},
 	}
 
 	return handler, tempDir, nil
@@ -253,9 +321,11 @@ func TestGetRouteInfos(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	routes := handler.GetRouteInfos()
-	assert.Len(t, routes, 1, "Should return 1 route")
+	assert.Len(t, routes, 3, "Should return 3 routes")
 	assert.Equal(t, http.MethodGet, routes[0].Method)
-	assert.Equal(t, "/cache/:filename", routes[0].Path)
+	assert.Equal(t, "/cache/stats", routes[0].Path)
+	assert.Equal(t, "/cache/images", routes[1].Path)
+	assert.Equal(t, "/cache/:filename", routes[2].Path)
 }
 
 func TestNewHandler(t *testing.T) {
@@ -273,11 +343,124 @@ func TestNewHandler(t *testing.T) {
 	// Type assertion to check the fields
 	cacheHandler, ok := handler.(*CacheHandler)
 	assert.True(t, ok)
-	assert.Equal(t, "/home/appuser/images-cache", cacheHandler.imageCachePath)
 	assert.Same(t, mockCache, cacheHandler.cacheService)
 
 	// Verify that we can call GetRouteInfos
 	routes := handler.GetRouteInfos()
-	assert.Len(t, routes, 1)
-	assert.Equal(t, "/cache/:filename", routes[0].Path)
+	assert.Len(t, routes, 3)
+	assert.Equal(t, "/cache/:filename", routes[2].Path)
+}
+
+func TestGetCachedImageResolveError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCache := new(MockImageCache)
+	mockCache.On("Resolve", "broken.png").Return(nil, fmt.Errorf("permission denied"))
+
+	router := gin.New()
+	router.GET("/cache/:filename", NewHandler(mockCache).GetCachedImage)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cache/broken.png", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockCache.AssertExpectations(t)
+}
+
+func TestGetCacheStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCache := new(MockImageCache)
+	mockCache.On("Metrics").Return(cache.Metrics{Entries: 2, Bytes: 2048, MaxBytes: 4096, Evictions: 1})
+
+	router := gin.New()
+	for _, route := range NewHandler(mockCache).GetRouteInfos() {
+		router.Handle(route.Method, route.Path, route.GetFlow()...)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cache/stats", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"entries":2,"bytes":2048,"maxEntries":0,"maxBytes":4096,"hits":0,"misses":0,"evictions":1,"evictedBytes":0,"quarantined":0}`, w.Body.String())
+	mockCache.AssertExpectations(t)
+}
+
+func TestListCachedImages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	revalidated := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockCache := new(MockImageCache)
+	mockCache.On("Images").Return([]cache.ImageInfo{
+		{Key: "map_1_splash", File: "map_1_splash.png", Size: 10, URL: "https://example.com/splash.png", RevalidatedAt: &revalidated},
+	})
+	mockCache.On("Resolve", "map_1_splash.png").Return(&cache.CachedFile{
+		Path:          "testdata/missing.png",
+		RevalidatedAt: revalidated,
+	}, nil)
+
+	router := gin.New()
+	for _, route := range NewHandler(mockCache).GetRouteInfos() {
+		router.Handle(route.Method, route.Path, route.GetFlow()...)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cache/images", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"revalidatedAt":"2025-01-02T03:04:05Z"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/cache/map_1_splash.png", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "Thu, 02 Jan 2025 03:04:05 GMT", w.Header().Get(RevalidatedHeader))
+	mockCache.AssertExpectations(t)
+}
+
+func TestGetCachedImageVariant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tempDir := t.TempDir()
+	require.NoError(t, createTestImage(tempDir, "thumb.jpg", []byte("thumbnail")))
+
+	mockCache := new(MockImageCache)
+	mockCache.On("Variant", "splash.png", cache.VariantOptions{Width: 320, Fit: "cover", Format: "jpeg"}).
+		Return(&cache.CachedFile{Path: filepath.Join(tempDir, "thumb.jpg"), Size: 9}, nil)
+	mockCache.On("Variant", "splash.png", cache.VariantOptions{Format: "webp"}).
+		Return(nil, fmt.Errorf("%w: WebP encoding is not available", cache.ErrUnsupportedVariant))
+	mockCache.On("Variant", "splash.png", cache.VariantOptions{Fit: "stretch"}).
+		Return(nil, fmt.Errorf("%w: unknown fit", cache.ErrInvalidVariant))
+
+	router := gin.New()
+	router.GET("/cache/:filename", NewHandler(mockCache).GetCachedImage)
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"w=320&fit=cover&format=jpeg", http.StatusOK},
+		{"format=webp", http.StatusUnprocessableEntity},
+		{"fit=stretch", http.StatusBadRequest},
+		{"w=0", http.StatusBadRequest},
+		{"h=wide", http.StatusBadRequest},
+		{"w=5000", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/cache/splash.png?"+tt.query, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, "thumbnail", w.Body.String())
+				assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+	mockCache.AssertExpectations(t)
 }
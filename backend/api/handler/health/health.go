@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/jungtechou/valomap/api/handler"
+	"github.com/jungtechou/valomap/pkg/httpclient"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +21,8 @@ type HealthResponse struct {
 	Timestamp time.Time `json:"timestamp" example:"2023-07-01T12:34:56Z"`
 	GoVersion string    `json:"go_version" example:"go1.24"`
 	Memory    Memory    `json:"memory"`
+	// Upstreams holds the circuit breaker of each upstream host contacted so far
+	Upstreams map[string]httpclient.BreakerStatus `json:"upstreams,omitempty"`
 }
 
 // Memory represents memory statistics
@@ -30,17 +33,24 @@ type Memory struct {
 	NumGC      uint32 `json:"num_gc" example:"10"`
 }
 
-// NewHandler creates a new health check handler
-func NewHandler() Handler {
-	return &HealthHandler{}
+// UpstreamMonitor reports the circuit breakers guarding upstream calls
+type UpstreamMonitor interface {
+	Breakers() map[string]httpclient.BreakerStatus
+}
+
+// NewHandler creates a new health check handler; upstreams may be nil
+func NewHandler(upstreams UpstreamMonitor) Handler {
+	return &HealthHandler{upstreams: upstreams}
 }
 
 // HealthHandler handles health check requests
-type HealthHandler struct{}
+type HealthHandler struct {
+	upstreams UpstreamMonitor
+}
 
 // HealthCheck godoc
 // @Summary Health Check
-// @Description Get the API health status with detailed system information
+// @Description Get the API health status with detailed system information. The status is "degraded" while the circuit breaker of an upstream is not closed.
 // @Tags system
 // @Produce json
 // @Success 200 {object} HealthResponse "Successful health check response"
@@ -66,6 +76,16 @@ func (h *HealthHandler) HealthCheck(c *gin.Context) {
 		},
 	}
 
+	// Upstream failures degrade the service rather than take it down
+	if h.upstreams != nil {
+		health.Upstreams = h.upstreams.Breakers()
+		for _, breaker := range health.Upstreams {
+			if breaker.State != httpclient.StateClosed {
+				health.Status = "degraded"
+			}
+		}
+	}
+
 	c.JSON(http.StatusOK, health)
 }
 
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jungtechou/valomap/pkg/httpclient"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,7 +26,7 @@ func setupTestRouter(handler Handler) *gin.Engine {
 
 func TestNewHandler(t *testing.T) {
 	// Create handler
-	handler := NewHandler()
+	handler := NewHandler(nil)
 
 	// Assertions
 	assert.NotNil(t, handler)
@@ -34,7 +35,7 @@ func TestNewHandler(t *testing.T) {
 
 func TestHealthCheck(t *testing.T) {
 	// Setup
-	handler := NewHandler()
+	handler := NewHandler(nil)
 	router := setupTestRouter(handler)
 
 	// Create request
@@ -58,9 +59,45 @@ func TestHealthCheck(t *testing.T) {
 	assert.NotZero(t, response.Memory)
 }
 
+// stubUpstreams reports fixed circuit breaker states
+type stubUpstreams map[string]httpclient.BreakerStatus
+
+func (s stubUpstreams) Breakers() map[string]httpclient.BreakerStatus {
+	return s
+}
+
+func TestHealthCheckUpstreams(t *testing.T) {
+	tests := []struct {
+		name      string
+		upstreams stubUpstreams
+		status    string
+	}{
+		{"closed breakers", stubUpstreams{"valorant-api.com": {State: httpclient.StateClosed}}, "ok"},
+		{"open breaker", stubUpstreams{
+			"valorant-api.com":       {State: httpclient.StateClosed},
+			"media.valorant-api.com": {State: httpclient.StateOpen, Failures: 5},
+		}, "degraded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupTestRouter(NewHandler(tt.upstreams))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/health", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			var response HealthResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.status, response.Status)
+			assert.Equal(t, map[string]httpclient.BreakerStatus(tt.upstreams), response.Upstreams)
+		})
+	}
+}
+
 func TestPing(t *testing.T) {
 	// Setup
-	handler := NewHandler()
+	handler := NewHandler(nil)
 	router := setupTestRouter(handler)
 
 	// Create request
@@ -84,7 +121,7 @@ func TestPing(t *testing.T) {
 
 func TestGetRouteInfos(t *testing.T) {
 	// Setup
-	handler := NewHandler()
+	handler := NewHandler(nil)
 
 	// Call function
 	routes := handler.GetRouteInfos()
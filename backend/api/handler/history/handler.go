@@ -0,0 +1,18 @@
+package history
+
+import (
+	"github.com/jungtechou/valomap/api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	_ Handler = (*HistoryHandler)(nil)
+)
+
+type Handler interface {
+	handler.Handler
+
+	// GetHistory returns a page of persisted roulette draws
+	GetHistory(c *gin.Context)
+}
@@ -0,0 +1,161 @@
+package history
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jungtechou/valomap/api/handler"
+	"github.com/jungtechou/valomap/api/middleware"
+	"github.com/jungtechou/valomap/service/history"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// dateLayout is accepted for from and to besides RFC 3339 timestamps
+const dateLayout = "2006-01-02"
+
+// ResponseError represents an API error response
+type ResponseError struct {
+	Error   string `json:"error" example:"invalid_query"`
+	Message string `json:"message,omitempty" example:"From must be before to"`
+	Code    int    `json:"code" example:"400"`
+}
+
+// NewHandler creates a new history handler instance
+func NewHandler(service history.Service) Handler {
+	return &HistoryHandler{service: service}
+}
+
+// HistoryHandler handles draw history requests
+type HistoryHandler struct {
+	service history.Service
+}
+
+// GetHistory godoc
+// @Summary List past roulette draws
+// @Description Returns persisted roulette results, newest first. A series is listed as one draw per map sharing a seed.
+// @Description Dates are RFC 3339 timestamps or plain dates (YYYY-MM-DD, UTC); from is inclusive and to is exclusive.
+// @Tags history
+// @Produce json
+// @Param session query string false "Only draws of this session" example:"scrim-night"
+// @Param map query string false "Only draws of this map, by UUID or display name" example:"Ascent"
+// @Param from query string false "Only draws at or after this time" example:"2025-01-01"
+// @Param to query string false "Only draws before this time" example:"2025-02-01T00:00:00Z"
+// @Param limit query integer false "Page size" default(50) maximum(200)
+// @Param offset query integer false "Number of draws to skip" default(0)
+// @Success 200 {object} history.Page "Successfully retrieved draw history"
+// @Failure 400 {object} ResponseError "Invalid date, limit or offset"
+// @Failure 503 {object} ResponseError "Draw history is not enabled or unavailable"
+// @Router /history [get]
+func (h *HistoryHandler) GetHistory(c *gin.Context) {
+	query := history.Query{
+		SessionID: c.Query("session"),
+		Map:       c.Query("map"),
+	}
+
+	var ok bool
+	if query.From, ok = parseTime(c, "from"); !ok {
+		return
+	}
+	if query.To, ok = parseTime(c, "to"); !ok {
+		return
+	}
+	if query.Limit, ok = parseInt(c, "limit"); !ok {
+		return
+	}
+	if query.Offset, ok = parseInt(c, "offset"); !ok {
+		return
+	}
+
+	page, err := h.service.List(middleware.GetRequestContext(c), query)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseTime reads an optional RFC 3339 timestamp or date query parameter.
+// It writes a 400 response and returns false when the value cannot be parsed.
+func parseTime(c *gin.Context, name string) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, true
+	}
+
+	c.JSON(http.StatusBadRequest, ResponseError{
+		Error:   "invalid_" + name,
+		Message: "The " + name + " parameter must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+		Code:    http.StatusBadRequest,
+	})
+	return time.Time{}, false
+}
+
+// parseInt reads an optional non-negative integer query parameter.
+// It writes a 400 response and returns false when the value is not one.
+func parseInt(c *gin.Context, name string) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		c.JSON(http.StatusBadRequest, ResponseError{
+			Error:   "invalid_" + name,
+			Message: "The " + name + " parameter must be a non-negative integer",
+			Code:    http.StatusBadRequest,
+		})
+		return 0, false
+	}
+	return n, true
+}
+
+// handleError maps history service errors to HTTP responses
+func (h *HistoryHandler) handleError(c *gin.Context, err error) {
+	logrus.WithError(err).WithField("handler", "history").Warn("History request failed")
+
+	// Clients get a stable code; the wrapped error, which may name database details, is only logged
+	statusCode := http.StatusServiceUnavailable
+	errCode := "history_unavailable"
+	errMsg := "Draw history unavailable"
+
+	switch {
+	case errors.Is(err, history.ErrInvalidQuery):
+		statusCode = http.StatusBadRequest
+		errCode = "invalid_query"
+		errMsg = "Invalid history query"
+	case errors.Is(err, history.ErrDisabled):
+		errCode = "history_disabled"
+		errMsg = "Draw history is not enabled on this server"
+	}
+
+	c.JSON(statusCode, ResponseError{
+		Error:   errCode,
+		Message: errMsg,
+		Code:    statusCode,
+	})
+}
+
+// GetRouteInfos implements handler.Handler interface
+func (h *HistoryHandler) GetRouteInfos() []handler.RouteInfo {
+	return []handler.RouteInfo{
+		{
+			Method:      http.MethodGet,
+			Path:        "/history",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.GetHistory,
+		},
+	}
+}
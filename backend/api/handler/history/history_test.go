@@ -0,0 +1,128 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock history service
+type mockHistoryService struct {
+	mock.Mock
+}
+
+func (m *mockHistoryService) List(ctx ctx.CTX, query history.Query) (*history.Page, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*history.Page), args.Error(1)
+}
+
+func setupRouter(handler Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	// Register routes
+	routes := handler.GetRouteInfos()
+	for _, route := range routes {
+		r.Handle(route.Method, route.Path, route.GetFlow()...)
+	}
+
+	return r
+}
+
+func TestGetRouteInfos(t *testing.T) {
+	handler := NewHandler(new(mockHistoryService))
+
+	routes := handler.GetRouteInfos()
+	assert.Len(t, routes, 1)
+	assert.Equal(t, http.MethodGet, routes[0].Method)
+	assert.Equal(t, "/history", routes[0].Path)
+	assert.NotNil(t, routes[0].Handler)
+}
+
+func TestGetHistory(t *testing.T) {
+	mockService := new(mockHistoryService)
+	page := &history.Page{
+		Draws: []history.Draw{{ID: "d1", MapUUID: "uuid1", MapName: "Ascent", Kind: history.KindSingle, Position: 1, Seed: 42}},
+		Total: 7,
+		Limit: 1,
+	}
+	expected := history.Query{
+		SessionID: "scrims",
+		Map:       "Ascent",
+		From:      time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:        time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC),
+		Limit:     1,
+		Offset:    6,
+	}
+	mockService.On("List", mock.Anything, expected).Return(page, nil)
+
+	router := setupRouter(NewHandler(mockService))
+	req, _ := http.NewRequest(http.MethodGet, "/history?session=scrims&map=Ascent&from=2025-01-01&to=2025-02-01T12:00:00Z&limit=1&offset=6", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, float64(7), body["total"])
+	draws := body["draws"].([]interface{})
+	assert.Len(t, draws, 1)
+	assert.Equal(t, "42", draws[0].(map[string]interface{})["seed"])
+	mockService.AssertExpectations(t)
+}
+
+func TestGetHistoryInvalidParams(t *testing.T) {
+	router := setupRouter(NewHandler(new(mockHistoryService)))
+
+	for _, query := range []string{"from=yesterday", "to=2025-13-01", "limit=ten", "offset=-1"} {
+		req, _ := http.NewRequest(http.MethodGet, "/history?"+query, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, query)
+	}
+}
+
+func TestGetHistoryErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		expectedCode  int
+		expectedError string
+	}{
+		{"Disabled", history.ErrDisabled, http.StatusServiceUnavailable, "history_disabled"},
+		{"InvalidQuery", fmt.Errorf("%w: to must be after from", history.ErrInvalidQuery), http.StatusBadRequest, "invalid_query"},
+		{"StoreFailure", errors.New("dial tcp 10.0.0.5:5432: connection refused"), http.StatusServiceUnavailable, "history_unavailable"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(mockHistoryService)
+			mockService.On("List", mock.Anything, mock.Anything).Return(nil, tc.err)
+
+			router := setupRouter(NewHandler(mockService))
+			req, _ := http.NewRequest(http.MethodGet, "/history", nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.expectedCode, resp.Code)
+			var body ResponseError
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, tc.expectedCode, body.Code)
+			assert.Equal(t, tc.expectedError, body.Error)
+			assert.NotContains(t, resp.Body.String(), "connection refused")
+		})
+	}
+}
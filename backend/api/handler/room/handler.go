@@ -0,0 +1,30 @@
+package room
+
+import (
+	"github.com/jungtechou/valomap/api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	_ Handler = (*RoomHandler)(nil)
+)
+
+type Handler interface {
+	handler.Handler
+
+	// CreateRoom opens a roulette room and returns its host token
+	CreateRoom(c *gin.Context)
+
+	// GetRoom returns the state of a room
+	GetRoom(c *gin.Context)
+
+	// Spin draws a map and broadcasts it to the room
+	Spin(c *gin.Context)
+
+	// Events streams room events as Server-Sent Events
+	Events(c *gin.Context)
+
+	// WebSocket streams room events over a WebSocket
+	WebSocket(c *gin.Context)
+}
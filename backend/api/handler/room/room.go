@@ -0,0 +1,472 @@
+package room
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jungtechou/valomap/api/handler"
+	"github.com/jungtechou/valomap/api/middleware"
+	"github.com/jungtechou/valomap/config"
+	"github.com/jungtechou/valomap/event"
+	"github.com/jungtechou/valomap/service/room"
+	"github.com/jungtechou/valomap/service/rotation"
+	"github.com/jungtechou/valomap/service/roulette"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// HostTokenHeader carries the token returned when the room was created
+	HostTokenHeader = "X-Host-Token"
+
+	// HeartbeatInterval is how often idle streams are pinged to keep proxies from closing them
+	HeartbeatInterval = 15 * time.Second
+
+	// RetryMillis is the reconnect delay suggested to EventSource clients
+	RetryMillis = 3000
+
+	// writeWait bounds a single WebSocket write
+	writeWait = 10 * time.Second
+)
+
+// ResponseError represents an API error response
+type ResponseError struct {
+	Error   string `json:"error" example:"room_not_found"`
+	Message string `json:"message,omitempty" example:"Room not found"`
+	Code    int    `json:"code" example:"404"`
+}
+
+// CreateRoomRequest is the body of a new room
+type CreateRoomRequest struct {
+	Name string `json:"name,omitempty" example:"Friday scrims"`
+}
+
+// SpinRequest is the roulette filter for a room spin
+type SpinRequest struct {
+	Standard bool               `json:"standard" example:"true"`
+	Banned   []string           `json:"banned" example:"7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+	Weights  map[string]float64 `json:"weights"`
+	Seed     *int64             `json:"seed,string,omitempty" example:"8675309"`
+	Pool     string             `json:"pool,omitempty" example:"competitive"`
+}
+
+// NewHandler creates a new room handler instance.
+// WebSocket upgrades are accepted from the configured CORS origins.
+func NewHandler(service room.Service, cfg *config.Config) Handler {
+	var origins []string
+	if cfg != nil {
+		origins = cfg.Security.AllowedOrigins
+	}
+
+	return &RoomHandler{
+		service: service,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return originAllowed(origins, r.Header.Get("Origin"))
+			},
+		},
+	}
+}
+
+// RoomHandler handles roulette room requests
+type RoomHandler struct {
+	service  room.Service
+	upgrader websocket.Upgrader
+}
+
+// CreateRoom godoc
+// @Summary Create a roulette room
+// @Description Opens a room whose spins are broadcast to every connected client.
+// @Description The response holds the host token required to spin; share only the room ID with players.
+// @Tags rooms
+// @Accept json
+// @Produce json
+// @Param request body CreateRoomRequest false "Room details"
+// @Success 201 {object} room.Created "Room created"
+// @Failure 400 {object} ResponseError "Invalid request body"
+// @Failure 503 {object} ResponseError "Server is shutting down"
+// @Router /room [post]
+func (h *RoomHandler) CreateRoom(c *gin.Context) {
+	var req CreateRoomRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ResponseError{
+				Error:   "invalid_request",
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	created, err := h.service.Create(middleware.GetRequestContext(c), req.Name)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetRoom godoc
+// @Summary Get a roulette room
+// @Description Returns the room's subscriber count, last event ID and last spin
+// @Tags rooms
+// @Produce json
+// @Param id path string true "Room ID"
+// @Success 200 {object} room.Room "Successfully retrieved room"
+// @Failure 404 {object} ResponseError "Room not found"
+// @Router /room/{id} [get]
+func (h *RoomHandler) GetRoom(c *gin.Context) {
+	r, err := h.service.Get(middleware.GetRequestContext(c), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+// Spin godoc
+// @Summary Spin the roulette for a room
+// @Description Draws a map with the given filter and broadcasts it to every client in the room as a roulette.spin event.
+// @Description Spins in a room avoid the room's recent picks like a roulette session.
+// @Tags rooms
+// @Accept json
+// @Produce json
+// @Param id path string true "Room ID"
+// @Param X-Host-Token header string true "Host token returned when the room was created"
+// @Param request body SpinRequest false "Roulette filter"
+// @Success 200 {object} event.Event "Spin broadcast"
+// @Failure 400 {object} ResponseError "Invalid request body, pool or weights"
+// @Failure 403 {object} ResponseError "Missing or wrong host token"
+// @Failure 404 {object} ResponseError "Room not found or no maps available"
+// @Failure 503 {object} ResponseError "Map service unavailable"
+// @Router /room/{id}/spin [post]
+func (h *RoomHandler) Spin(c *gin.Context) {
+	var req SpinRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ResponseError{
+				Error:   "invalid_request",
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	if req.Pool != "" && !rotation.IsPool(req.Pool) {
+		c.JSON(http.StatusBadRequest, ResponseError{
+			Error:   "invalid_pool",
+			Message: "Pool must be one of competitive, premier or all",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filter := roulette.MapFilter{
+		StandardOnly: req.Standard,
+		BannedMapIDs: req.Banned,
+		Weights:      req.Weights,
+		Pool:         req.Pool,
+	}
+
+	evt, err := h.service.Spin(middleware.GetRequestContext(c), c.Param("id"), c.GetHeader(HostTokenHeader), filter, req.Seed)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, evt)
+}
+
+// Events godoc
+// @Summary Stream room events
+// @Description Streams the room's events as Server-Sent Events. Each message carries the event ID, so a
+// @Description reconnecting EventSource resumes via the Last-Event-ID header and missed events are replayed.
+// @Description The stream ends after a room.closed event.
+// @Tags rooms
+// @Produce text/event-stream
+// @Param id path string true "Room ID"
+// @Param Last-Event-ID header string false "Last event ID the client received"
+// @Param lastEventId query string false "Last event ID the client received, for clients that cannot set headers"
+// @Success 200 {object} event.Event "Event stream"
+// @Failure 400 {object} ResponseError "Invalid last event ID"
+// @Failure 404 {object} ResponseError "Room not found"
+// @Router /room/{id}/events [get]
+func (h *RoomHandler) Events(c *gin.Context) {
+	lastEventID, ok := parseLastEventID(c)
+	if !ok {
+		return
+	}
+
+	reqCtx := middleware.GetRequestContext(c)
+	sub, err := h.service.Subscribe(reqCtx, c.Param("id"), lastEventID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer sub.Close()
+
+	// Streams outlive the server's write timeout
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", RetryMillis)
+	for _, evt := range sub.Replay {
+		if err := writeSSE(c.Writer, evt); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	logger := reqCtx.FieldLogger.WithField("room_id", c.Param("id"))
+	logger.WithField("replayed", len(sub.Replay)).Info("Room SSE client connected")
+
+	heartbeat := time.NewTicker(HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			logger.Info("Room SSE client disconnected")
+			return
+		case evt, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := writeSSE(c.Writer, evt); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// WebSocket godoc
+// @Summary Stream room events over WebSocket
+// @Description Upgrades to a WebSocket that receives the room's events as JSON text messages.
+// @Description Pass lastEventId when reconnecting to replay missed events. Messages sent by the client are ignored.
+// @Tags rooms
+// @Param id path string true "Room ID"
+// @Param lastEventId query string false "Last event ID the client received"
+// @Success 101 "Switching protocols"
+// @Failure 400 {object} ResponseError "Invalid last event ID"
+// @Failure 404 {object} ResponseError "Room not found"
+// @Router /room/{id}/ws [get]
+func (h *RoomHandler) WebSocket(c *gin.Context) {
+	lastEventID, ok := parseLastEventID(c)
+	if !ok {
+		return
+	}
+
+	reqCtx := middleware.GetRequestContext(c)
+	sub, err := h.service.Subscribe(reqCtx, c.Param("id"), lastEventID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer sub.Close()
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written the error response
+		reqCtx.FieldLogger.WithError(err).Warn("WebSocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	logger := reqCtx.FieldLogger.WithField("room_id", c.Param("id"))
+	logger.WithField("replayed", len(sub.Replay)).Info("Room WebSocket client connected")
+
+	// Read in the background so that close frames and dead peers are noticed
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		conn.SetReadLimit(512)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, evt := range sub.Replay {
+		if err := writeWS(conn, evt); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-gone:
+			logger.Info("Room WebSocket client disconnected")
+			return
+		case evt, ok := <-sub.Events:
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(writeWait))
+				return
+			}
+			if err := writeWS(conn, evt); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeSSE writes one event in the text/event-stream format
+func writeSSE(w http.ResponseWriter, evt event.Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", evt.ID, evt.Type, payload)
+	return err
+}
+
+// writeWS writes one event as a JSON text message
+func writeWS(conn *websocket.Conn, evt event.Event) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteJSON(evt)
+}
+
+// parseLastEventID reads the resume point from the Last-Event-ID header or the lastEventId query.
+// It writes a 400 response and returns false when the value is not an event ID.
+func parseLastEventID(c *gin.Context) (uint64, bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("lastEventId")
+	}
+	if value == "" {
+		return 0, true
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{
+			Error:   "invalid_last_event_id",
+			Message: "Last event ID must be a non-negative integer",
+			Code:    http.StatusBadRequest,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// originAllowed reports whether a WebSocket may be opened from the origin.
+// Requests without an Origin header do not come from a browser and are allowed.
+func originAllowed(allowedOrigins []string, origin string) bool {
+	if origin == "" || len(allowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// handleError maps room service errors to HTTP responses
+func (h *RoomHandler) handleError(c *gin.Context, err error) {
+	logrus.WithError(err).WithField("handler", "room").Warn("Room request failed")
+
+	statusCode := http.StatusInternalServerError
+	errMsg := "Failed to process room request"
+
+	switch {
+	case errors.Is(err, room.ErrRoomNotFound):
+		statusCode = http.StatusNotFound
+		errMsg = "Room not found"
+	case errors.Is(err, room.ErrNotHost):
+		statusCode = http.StatusForbidden
+		errMsg = "Only the room host can spin; send the host token in the " + HostTokenHeader + " header"
+	case errors.Is(err, room.ErrClosed):
+		statusCode = http.StatusServiceUnavailable
+		errMsg = "Server is shutting down"
+	case errors.Is(err, roulette.ErrEmptyMapList), errors.Is(err, roulette.ErrNoStandardMaps),
+		errors.Is(err, roulette.ErrNoFilteredMaps):
+		statusCode = http.StatusNotFound
+		errMsg = "No maps available after filtering"
+	case errors.Is(err, roulette.ErrInvalidWeight), errors.Is(err, roulette.ErrZeroWeights):
+		statusCode = http.StatusBadRequest
+		errMsg = "Map weights must be non-negative with at least one positive"
+	case errors.Is(err, roulette.ErrUnknownPool):
+		statusCode = http.StatusBadRequest
+		errMsg = "Unknown map pool"
+	case errors.Is(err, roulette.ErrNoActivePool), errors.Is(err, roulette.ErrEmptyPool):
+		statusCode = http.StatusNotFound
+		errMsg = "No maps in the active pool rotation"
+	case errors.Is(err, roulette.ErrAPIRequest), errors.Is(err, roulette.ErrAPIResponse):
+		statusCode = http.StatusServiceUnavailable
+		errMsg = "Map service unavailable"
+	}
+
+	c.JSON(statusCode, ResponseError{
+		Error:   err.Error(),
+		Message: errMsg,
+		Code:    statusCode,
+	})
+}
+
+// GetRouteInfos implements handler.Handler interface
+func (h *RoomHandler) GetRouteInfos() []handler.RouteInfo {
+	return []handler.RouteInfo{
+		{
+			Method:      http.MethodPost,
+			Path:        "/room",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.CreateRoom,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/room/:id",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.GetRoom,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/room/:id/spin",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.Spin,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/room/:id/events",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.Events,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/room/:id/ws",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.WebSocket,
+		},
+	}
+}
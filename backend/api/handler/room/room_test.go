@@ -0,0 +1,297 @@
+package room
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/event"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/room"
+	"github.com/jungtechou/valomap/service/roulette"
+	"github.com/jungtechou/valomap/service/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Mock room service
+type mockRoomService struct {
+	mock.Mock
+}
+
+func (m *mockRoomService) Create(ctx ctx.CTX, name string) (*room.Created, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*room.Created), args.Error(1)
+}
+
+func (m *mockRoomService) Get(ctx ctx.CTX, id string) (*room.Room, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*room.Room), args.Error(1)
+}
+
+func (m *mockRoomService) Spin(ctx ctx.CTX, id, hostToken string, filter roulette.MapFilter, seed *int64) (*event.Event, error) {
+	args := m.Called(ctx, id, hostToken, filter, seed)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*event.Event), args.Error(1)
+}
+
+func (m *mockRoomService) Subscribe(ctx ctx.CTX, id string, lastEventID uint64) (*room.Subscription, error) {
+	args := m.Called(ctx, id, lastEventID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*room.Subscription), args.Error(1)
+}
+
+func (m *mockRoomService) Close() {
+	m.Called()
+}
+
+func setupRouter(handler Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	// Register routes
+	routes := handler.GetRouteInfos()
+	for _, route := range routes {
+		r.Handle(route.Method, route.Path, route.GetFlow()...)
+	}
+
+	return r
+}
+
+// newTestServer serves the room routes backed by a real room service over three static maps
+func newTestServer(t *testing.T) (*httptest.Server, room.Service) {
+	maps := []domain.Map{
+		{UUID: "map1", DisplayName: "Map 1", TacticalDescription: "A/B Sites"},
+		{UUID: "map2", DisplayName: "Map 2", TacticalDescription: "A/B Sites"},
+		{UUID: "map3", DisplayName: "Map 3", TacticalDescription: "A/B Sites"},
+	}
+	rouletteService, cleanup := roulette.NewService(source.NewStaticSource(domain.MapPool{Maps: maps}), nil, nil, nil, nil, &config.Config{
+		Catalog: config.CatalogConfig{TTL: time.Hour},
+	})
+	t.Cleanup(cleanup)
+
+	roomService, closeRooms := room.NewService(rouletteService)
+	server := httptest.NewServer(setupRouter(NewHandler(roomService, &config.Config{
+		Security: config.SecurityConfig{AllowedOrigins: []string{"http://localhost:3000"}},
+	})))
+	t.Cleanup(func() {
+		closeRooms()
+		server.Close()
+	})
+	return server, roomService
+}
+
+// createRoom opens a room through the API
+func createRoom(t *testing.T, server *httptest.Server) room.Created {
+	resp, err := http.Post(server.URL+"/room", "application/json", strings.NewReader(`{"name":"Lobby"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created room.Created
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	return created
+}
+
+// spin triggers a spin through the API and returns the status code and event
+func spin(t *testing.T, server *httptest.Server, id, token string) (int, event.Event) {
+	req, _ := http.NewRequest("POST", server.URL+"/room/"+id+"/spin", strings.NewReader(`{"standard":true,"seed":"7"}`))
+	req.Header.Set(HostTokenHeader, token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var evt event.Event
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&evt))
+	}
+	return resp.StatusCode, evt
+}
+
+// readSSE reads the next event from an event stream, skipping comments and retry hints
+func readSSE(t *testing.T, reader *bufio.Reader) event.Event {
+	var data string
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+
+		switch {
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && data != "":
+			var evt event.Event
+			require.NoError(t, json.Unmarshal([]byte(data), &evt))
+			return evt
+		}
+	}
+}
+
+func TestGetRouteInfos(t *testing.T) {
+	handler := NewHandler(new(mockRoomService), nil)
+
+	routes := handler.GetRouteInfos()
+	assert.Len(t, routes, 5)
+	for _, route := range routes {
+		assert.NotNil(t, route.Handler)
+	}
+}
+
+func TestCreateAndSpin(t *testing.T) {
+	server, _ := newTestServer(t)
+	created := createRoom(t, server)
+	assert.Equal(t, "Lobby", created.Name)
+
+	status, _ := spin(t, server, created.ID, "")
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, evt := spin(t, server, created.ID, created.HostToken)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, event.TypeSpin, evt.Type)
+
+	resp, err := http.Get(server.URL + "/room/" + created.ID)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var r room.Room
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&r))
+	assert.Equal(t, evt.ID, r.LastEventID)
+	require.NotNil(t, r.LastSpin)
+	assert.Equal(t, int64(7), r.LastSpin.Seed)
+
+	status, _ = spin(t, server, "missing", created.HostToken)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestEvents_SSE(t *testing.T) {
+	server, _ := newTestServer(t)
+	created := createRoom(t, server)
+
+	resp, err := http.Get(server.URL + "/room/" + created.ID + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+
+	// The stream starts with the retained history
+	assert.Equal(t, event.TypeRoomOpened, readSSE(t, reader).Type)
+
+	_, spun := spin(t, server, created.ID, created.HostToken)
+	got := readSSE(t, reader)
+	assert.Equal(t, spun.ID, got.ID)
+	assert.Equal(t, event.TypeSpin, got.Type)
+
+	// A client reconnecting with Last-Event-ID only receives what it missed
+	_, missed := spin(t, server, created.ID, created.HostToken)
+	req, _ := http.NewRequest("GET", server.URL+"/room/"+created.ID+"/events", nil)
+	req.Header.Set("Last-Event-ID", fmt.Sprint(spun.ID))
+	resumed, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resumed.Body.Close()
+	assert.Equal(t, missed.ID, readSSE(t, bufio.NewReader(resumed.Body)).ID)
+
+	// Invalid resume points and unknown rooms are rejected before streaming
+	bad, err := http.Get(server.URL + "/room/" + created.ID + "/events?lastEventId=abc")
+	require.NoError(t, err)
+	bad.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, bad.StatusCode)
+
+	bad, err = http.Get(server.URL + "/room/missing/events")
+	require.NoError(t, err)
+	bad.Body.Close()
+	assert.Equal(t, http.StatusNotFound, bad.StatusCode)
+}
+
+func TestEvents_WebSocket(t *testing.T) {
+	server, roomService := newTestServer(t)
+	created := createRoom(t, server)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/room/" + created.ID + "/ws?lastEventId=1"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {"http://localhost:3000"}})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, spun := spin(t, server, created.ID, created.HostToken)
+
+	var got event.Event
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	require.NoError(t, conn.ReadJSON(&got))
+	assert.Equal(t, spun.ID, got.ID)
+
+	// Closing the rooms tells clients and ends the socket
+	roomService.Close()
+	require.NoError(t, conn.ReadJSON(&got))
+	assert.Equal(t, event.TypeRoomClosed, got.Type)
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+
+	// Foreign origins are refused
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {"http://evil.example"}})
+	assert.Error(t, err)
+	if resp != nil {
+		assert.NotEqual(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	assert.True(t, originAllowed(nil, "http://any.example"))
+	assert.True(t, originAllowed([]string{"http://a.example"}, ""))
+	assert.True(t, originAllowed([]string{"*"}, "http://any.example"))
+	assert.True(t, originAllowed([]string{"http://a.example"}, "http://a.example"))
+	assert.False(t, originAllowed([]string{"http://a.example"}, "http://b.example"))
+}
+
+func TestHandleError(t *testing.T) {
+	testCases := []struct {
+		err    error
+		status int
+	}{
+		{room.ErrRoomNotFound, http.StatusNotFound},
+		{room.ErrNotHost, http.StatusForbidden},
+		{room.ErrClosed, http.StatusServiceUnavailable},
+		{roulette.ErrNoFilteredMaps, http.StatusNotFound},
+		{roulette.ErrInvalidWeight, http.StatusBadRequest},
+		{roulette.ErrUnknownPool, http.StatusBadRequest},
+		{roulette.ErrNoActivePool, http.StatusNotFound},
+		{roulette.ErrAPIRequest, http.StatusServiceUnavailable},
+		{assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			mockService := new(mockRoomService)
+			mockService.On("Spin", mock.Anything, "room1", "token", roulette.MapFilter{}, (*int64)(nil)).
+				Return(nil, fmt.Errorf("%w: detail", tc.err))
+			router := setupRouter(NewHandler(mockService, nil))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/room/room1/spin", nil)
+			req.Header.Set(HostTokenHeader, "token")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code)
+			var resp ResponseError
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.status, resp.Code)
+		})
+	}
+}
@@ -16,6 +16,24 @@ type Handler interface {
 	// GetMap returns a random map, optionally filtered to standard maps only
 	GetMap(c *gin.Context)
 
+	// PostMap returns a random map using a JSON filter body
+	PostMap(c *gin.Context)
+
+	// GetSeries returns several distinct maps for a best-of series
+	GetSeries(c *gin.Context)
+
 	// GetAllMaps returns a list of all available maps
 	GetAllMaps(c *gin.Context)
+
+	// GetPool returns the maps in a pool's active rotation
+	GetPool(c *gin.Context)
+
+	// GetSessionHistory returns the recent picks a session is avoiding
+	GetSessionHistory(c *gin.Context)
+
+	// ResetSessionHistory forgets a session's recent picks
+	ResetSessionHistory(c *gin.Context)
+
+	// GetCatalogStatus returns the freshness of the in-memory map catalog
+	GetCatalogStatus(c *gin.Context)
 }
@@ -3,15 +3,31 @@ package roulette
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/jungtechou/valomap/api/handler"
-	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/api/middleware"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/service/rotation"
 	"github.com/jungtechou/valomap/service/roulette"
+	"github.com/jungtechou/valomap/service/session"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// SessionHeader carries the roulette session ID; the session query parameter is used when it is absent
+	SessionHeader = "X-Session-ID"
+
+	// DefaultSeriesCount is the series length when no count is given, i.e. a Bo3
+	DefaultSeriesCount = 3
+
+	// MaxSeriesCount caps the series length accepted by the API
+	MaxSeriesCount = 25
+)
+
 // ResponseError represents an API error response
 type ResponseError struct {
 	Error   string `json:"error" example:"service_unavailable"`
@@ -19,6 +35,13 @@ type ResponseError struct {
 	Code    int    `json:"code" example:"503"`
 }
 
+// RouletteResponse is a drawn map together with the data needed to reproduce the draw
+type RouletteResponse struct {
+	domain.Map
+	Seed       int64    `json:"seed,string" example:"8675309"`
+	Candidates []string `json:"candidates" example:"2c9d57ec-4431-9c5e-2939-8f9ef6dd5cba,7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+}
+
 // NewHandler creates a new roulette handler instance
 func NewHandler(service roulette.Service) Handler {
 	return &RouletteHandler{service: service}
@@ -29,22 +52,125 @@ type RouletteHandler struct {
 	service roulette.Service
 }
 
+// SeriesResponse is a drawn series together with the data needed to reproduce it
+type SeriesResponse struct {
+	Maps       []domain.Map `json:"maps"`
+	Seed       int64        `json:"seed,string" example:"8675309"`
+	Candidates []string     `json:"candidates" example:"2c9d57ec-4431-9c5e-2939-8f9ef6dd5cba,7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+}
+
+// RouletteRequest is the JSON body variant of the roulette query parameters
+type RouletteRequest struct {
+	Standard bool               `json:"standard" example:"true"`
+	Banned   []string           `json:"banned" example:"7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+	Weights  map[string]float64 `json:"weights"`
+	Seed     *int64             `json:"seed,string,omitempty" example:"8675309"`
+	Pool     string             `json:"pool,omitempty" example:"competitive"`
+	Session  string             `json:"session,omitempty" example:"scrim-night"`
+}
+
 // GetMap godoc
 // @Summary Get a random map
-// @Description Returns a randomly selected Valorant map, with optional filtering by map type and exclusions
+// @Description Returns a randomly selected Valorant map, with optional filtering by map type and exclusions.
+// @Description The response includes the seed and the ordered candidate list; passing the same seed with the same filter reproduces the draw.
+// @Description Maps can be weighted with weight[<uuid>]=N; unlisted maps weigh 1 and a weight of 0 excludes a map.
+// @Description With a session ID, the session's most recent picks are excluded or down-weighted.
 // @Tags maps
 // @Accept json
 // @Produce json
 // @Param standard query boolean false "Filter to only standard maps (maps with tactical description)" example:"true"
 // @Param banned query array false "List of map UUIDs to exclude from selection" collectionFormat:"multi" items.type:string
-// @Success 200 {object} domain.Map "Successfully retrieved random map"
+// @Param pool query string false "Limit to the active rotation of a pool" Enums(competitive, premier, all)
+// @Param seed query integer false "Seed for a reproducible draw; generated by the server when omitted" example:"8675309"
+// @Param weight[uuid] query number false "Selection weight for the map with the given UUID" example:"3"
+// @Param session query string false "Session ID whose recent picks are avoided" example:"scrim-night"
+// @Param X-Session-ID header string false "Session ID whose recent picks are avoided; takes precedence over the query"
+// @Success 200 {object} RouletteResponse "Successfully retrieved random map"
+// @Failure 400 {object} ResponseError "Invalid seed, pool or weights"
 // @Failure 404 {object} ResponseError "No maps available after filtering"
 // @Failure 503 {object} ResponseError "Map service unavailable"
 // @Failure 500 {object} ResponseError "Internal server error"
 // @Router /map/roulette [get]
 func (r *RouletteHandler) GetMap(c *gin.Context) {
-	logger := logrus.WithField("handler", "GetMap")
+	filter, seed, ok := parseQueryFilter(c)
+	if !ok {
+		return
+	}
+
+	r.drawMap(c, filter, seed)
+}
+
+// GetSeries godoc
+// @Summary Get a series of distinct maps
+// @Description Returns count distinct maps in play order for a best-of series, using the same filters as /map/roulette.
+// @Description Passing the same seed, filter and count reproduces the series.
+// @Tags maps
+// @Produce json
+// @Param count query integer false "Number of maps in the series" default(3) example:"5"
+// @Param standard query boolean false "Filter to only standard maps (maps with tactical description)" example:"true"
+// @Param banned query array false "List of map UUIDs to exclude from selection" collectionFormat:"multi" items.type:string
+// @Param pool query string false "Limit to the active rotation of a pool" Enums(competitive, premier, all)
+// @Param seed query integer false "Seed for a reproducible series; generated by the server when omitted" example:"8675309"
+// @Param weight[uuid] query number false "Selection weight for the map with the given UUID" example:"3"
+// @Param session query string false "Session ID whose recent picks are avoided" example:"scrim-night"
+// @Param X-Session-ID header string false "Session ID whose recent picks are avoided; takes precedence over the query"
+// @Success 200 {object} SeriesResponse "Successfully drew a series"
+// @Failure 400 {object} ResponseError "Invalid count, seed, pool or weights"
+// @Failure 404 {object} ResponseError "No maps available after filtering"
+// @Failure 422 {object} ResponseError "Fewer eligible maps than requested"
+// @Failure 503 {object} ResponseError "Map service unavailable"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /map/roulette/series [get]
+func (r *RouletteHandler) GetSeries(c *gin.Context) {
+	logger := logrus.WithField("handler", "GetSeries")
 
+	count := DefaultSeriesCount
+	if countQuery := c.Query("count"); countQuery != "" {
+		parsed, err := strconv.Atoi(countQuery)
+		if err != nil || parsed < 1 || parsed > MaxSeriesCount {
+			c.JSON(http.StatusBadRequest, ResponseError{
+				Error:   "invalid_count",
+				Message: "Count must be an integer between 1 and " + strconv.Itoa(MaxSeriesCount),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		count = parsed
+	}
+
+	filter, seed, ok := parseQueryFilter(c)
+	if !ok {
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"count":         count,
+		"standard_only": filter.StandardOnly,
+		"banned_maps":   len(filter.BannedMapIDs),
+	}).Info("Processing map series request")
+
+	result, err := r.service.DrawSeries(middleware.GetRequestContext(c), filter, count, seed)
+	if err != nil {
+		r.handleError(c, err, filter.StandardOnly)
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"count": len(result.Maps),
+		"seed":  result.Seed,
+	}).Info("Successfully drew map series")
+
+	r.setCatalogAgeHeader(c)
+	c.JSON(http.StatusOK, SeriesResponse{
+		Maps:       result.Maps,
+		Seed:       result.Seed,
+		Candidates: result.Candidates,
+	})
+}
+
+// parseQueryFilter reads the roulette filter and seed from the query string.
+// It writes a 400 response and returns false when a parameter is invalid.
+func parseQueryFilter(c *gin.Context) (roulette.MapFilter, *int64, bool) {
 	// Parse the standard query parameter
 	standardQuery := c.Query("standard")
 	standardOnly := standardQuery == "true" || standardQuery == "1"
@@ -60,33 +186,128 @@ func (r *RouletteHandler) GetMap(c *gin.Context) {
 	filter := roulette.MapFilter{
 		StandardOnly: standardOnly,
 		BannedMapIDs: bannedMapIDs,
+		Pool:         c.Query("pool"),
+		SessionID:    sessionID(c),
+	}
+
+	// Validate the optional pool query parameter
+	if filter.Pool != "" && !rotation.IsPool(filter.Pool) {
+		invalidPool(c)
+		return filter, nil, false
+	}
+
+	// Parse the optional weight[<uuid>] query parameters
+	if weightQuery := c.QueryMap("weight"); len(weightQuery) > 0 {
+		filter.Weights = make(map[string]float64, len(weightQuery))
+		for uuid, value := range weightQuery {
+			weight, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ResponseError{
+					Error:   "invalid_weight",
+					Message: "Weight for map " + uuid + " must be a number",
+					Code:    http.StatusBadRequest,
+				})
+				return filter, nil, false
+			}
+			filter.Weights[uuid] = weight
+		}
 	}
 
+	// Parse the optional seed query parameter
+	var seed *int64
+	if seedQuery := c.Query("seed"); seedQuery != "" {
+		parsed, err := strconv.ParseInt(seedQuery, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ResponseError{
+				Error:   "invalid_seed",
+				Message: "Seed must be a 64-bit integer",
+				Code:    http.StatusBadRequest,
+			})
+			return filter, nil, false
+		}
+		seed = &parsed
+	}
+
+	return filter, seed, true
+}
+
+// PostMap godoc
+// @Summary Get a random map using a JSON filter
+// @Description Same as GET /map/roulette, but takes the filter, weights and seed as a JSON body.
+// @Description The seed is a string so that 64-bit values survive JavaScript clients.
+// @Tags maps
+// @Accept json
+// @Produce json
+// @Param request body RouletteRequest true "Roulette filter"
+// @Param X-Session-ID header string false "Session ID whose recent picks are avoided; takes precedence over the body"
+// @Success 200 {object} RouletteResponse "Successfully retrieved random map"
+// @Failure 400 {object} ResponseError "Invalid request body or weights"
+// @Failure 404 {object} ResponseError "No maps available after filtering"
+// @Failure 503 {object} ResponseError "Map service unavailable"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /map/roulette [post]
+func (r *RouletteHandler) PostMap(c *gin.Context) {
+	var req RouletteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{
+			Error:   "invalid_request",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	filter := roulette.MapFilter{
+		StandardOnly: req.Standard,
+		BannedMapIDs: req.Banned,
+		Weights:      req.Weights,
+		Pool:         req.Pool,
+		SessionID:    req.Session,
+	}
+	if id := sessionID(c); id != "" {
+		filter.SessionID = id
+	}
+
+	r.drawMap(c, filter, req.Seed)
+}
+
+// drawMap runs a roulette draw and writes the result or error response
+func (r *RouletteHandler) drawMap(c *gin.Context, filter roulette.MapFilter, seed *int64) {
+	logger := logrus.WithField("handler", "GetMap")
+
 	// Log the request
 	logger.WithFields(logrus.Fields{
-		"standard_only": standardOnly,
-		"banned_maps":   len(bannedMapIDs),
+		"standard_only": filter.StandardOnly,
+		"banned_maps":   len(filter.BannedMapIDs),
+		"weighted_maps": len(filter.Weights),
+		"session_id":    filter.SessionID,
 	}).Info("Processing map roulette request")
 
 	// Create request context
-	reqCtx := ctx.Background()
+	reqCtx := middleware.GetRequestContext(c)
 
-	// Get a random map with the specified filter
-	randomMap, err := r.service.GetRandomMap(reqCtx, filter)
+	// Draw a map with the specified filter and seed
+	result, err := r.service.DrawMap(reqCtx, filter, seed)
 	if err != nil {
-		r.handleError(c, err, standardOnly)
+		r.handleError(c, err, filter.StandardOnly)
 		return
 	}
 
 	// Log successful response
 	logger.WithFields(logrus.Fields{
-		"map_name":      randomMap.DisplayName,
-		"standard_only": standardOnly,
-		"banned_maps":   len(bannedMapIDs),
+		"map_name":      result.Map.DisplayName,
+		"seed":          result.Seed,
+		"standard_only": filter.StandardOnly,
+		"banned_maps":   len(filter.BannedMapIDs),
 	}).Info("Successfully retrieved random map")
 
 	// Return the result
-	c.JSON(http.StatusOK, randomMap)
+	r.setCatalogAgeHeader(c)
+	c.JSON(http.StatusOK, RouletteResponse{
+		Map:        result.Map,
+		Seed:       result.Seed,
+		Candidates: result.Candidates,
+	})
 }
 
 // handleError processes service errors and returns appropriate HTTP responses
@@ -109,6 +330,24 @@ func (r *RouletteHandler) handleError(c *gin.Context, err error, standardOnly bo
 	case errors.Is(err, roulette.ErrNoFilteredMaps):
 		statusCode = http.StatusNotFound
 		errMsg = "All available maps have been banned"
+	case errors.Is(err, roulette.ErrInvalidWeight):
+		statusCode = http.StatusBadRequest
+		errMsg = "Map weights must be non-negative numbers"
+	case errors.Is(err, roulette.ErrZeroWeights):
+		statusCode = http.StatusBadRequest
+		errMsg = "At least one eligible map needs a positive weight"
+	case errors.Is(err, roulette.ErrUnknownPool):
+		statusCode = http.StatusBadRequest
+		errMsg = "Unknown map pool"
+	case errors.Is(err, roulette.ErrNoActivePool), errors.Is(err, roulette.ErrEmptyPool):
+		statusCode = http.StatusNotFound
+		errMsg = "No maps in the active pool rotation"
+	case errors.Is(err, roulette.ErrInvalidCount):
+		statusCode = http.StatusBadRequest
+		errMsg = "Series count must be at least 1"
+	case errors.Is(err, roulette.ErrNotEnoughMaps):
+		statusCode = http.StatusUnprocessableEntity
+		errMsg = "Not enough maps match the filter for this series"
 	case errors.Is(err, roulette.ErrAPIRequest), errors.Is(err, roulette.ErrAPIResponse):
 		statusCode = http.StatusServiceUnavailable
 		errMsg = "Map service unavailable"
@@ -123,11 +362,14 @@ func (r *RouletteHandler) handleError(c *gin.Context, err error, standardOnly bo
 
 // GetAllMaps godoc
 // @Summary Get all maps
-// @Description Returns a list of all available Valorant maps with their details and images
+// @Description Returns a list of all available Valorant maps with their details and images, optionally limited to a pool's active rotation
 // @Tags maps
 // @Accept json
 // @Produce json
+// @Param pool query string false "Limit to the active rotation of a pool" Enums(competitive, premier, all)
 // @Success 200 {array} domain.Map "Successfully retrieved all maps"
+// @Failure 400 {object} ResponseError "Unknown pool"
+// @Failure 404 {object} ResponseError "No active rotation for the pool"
 // @Failure 503 {object} ResponseError "Map service unavailable"
 // @Failure 500 {object} ResponseError "Internal server error"
 // @Router /map/all [get]
@@ -136,7 +378,18 @@ func (r *RouletteHandler) GetAllMaps(c *gin.Context) {
 	logger.Info("Processing get all maps request")
 
 	// Create request context
-	reqCtx := ctx.Background()
+	reqCtx := middleware.GetRequestContext(c)
+
+	// A pool other than "all" lists that pool's rotation instead
+	if pool := c.Query("pool"); pool != "" && pool != domain.PoolAll {
+		mapPool, ok := r.lookupPool(c, pool)
+		if !ok {
+			return
+		}
+		r.setCatalogAgeHeader(c)
+		c.JSON(http.StatusOK, mapPool.Maps)
+		return
+	}
 
 	// Get all maps
 	maps, err := r.service.GetAllMaps(reqCtx)
@@ -165,9 +418,193 @@ func (r *RouletteHandler) GetAllMaps(c *gin.Context) {
 	logger.WithField("map_count", len(maps)).Info("Successfully retrieved all maps")
 
 	// Return the result
+	r.setCatalogAgeHeader(c)
 	c.JSON(http.StatusOK, maps)
 }
 
+// GetPool godoc
+// @Summary Get the active map pool
+// @Description Returns the season and maps of a pool's active rotation
+// @Tags maps
+// @Produce json
+// @Param pool query string false "Pool to list" Enums(competitive, premier, all) default(competitive)
+// @Success 200 {object} domain.MapPool "Successfully retrieved the map pool"
+// @Failure 400 {object} ResponseError "Unknown pool"
+// @Failure 404 {object} ResponseError "No active rotation for the pool"
+// @Failure 503 {object} ResponseError "Map service unavailable"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /map/pool [get]
+func (r *RouletteHandler) GetPool(c *gin.Context) {
+	pool := c.DefaultQuery("pool", domain.PoolCompetitive)
+
+	mapPool, ok := r.lookupPool(c, pool)
+	if !ok {
+		return
+	}
+
+	r.setCatalogAgeHeader(c)
+	c.JSON(http.StatusOK, mapPool)
+}
+
+// lookupPool resolves a pool's active rotation, writing the error response and returning false on failure
+func (r *RouletteHandler) lookupPool(c *gin.Context, pool string) (*domain.MapPool, bool) {
+	if !rotation.IsPool(pool) {
+		invalidPool(c)
+		return nil, false
+	}
+
+	mapPool, err := r.service.GetPool(middleware.GetRequestContext(c), pool)
+	if err != nil {
+		logrus.WithError(err).WithField("pool", pool).Error("Failed to get map pool")
+
+		statusCode := http.StatusInternalServerError
+		errMsg := "Failed to retrieve map pool"
+
+		switch {
+		case errors.Is(err, roulette.ErrNoActivePool):
+			statusCode = http.StatusNotFound
+			errMsg = "No active rotation for this pool"
+		case errors.Is(err, roulette.ErrAPIRequest), errors.Is(err, roulette.ErrAPIResponse):
+			statusCode = http.StatusServiceUnavailable
+			errMsg = "Map service unavailable"
+		}
+
+		c.JSON(statusCode, ResponseError{
+			Error:   err.Error(),
+			Message: errMsg,
+			Code:    statusCode,
+		})
+		return nil, false
+	}
+
+	return mapPool, true
+}
+
+// invalidPool rejects pool names other than competitive, premier and all
+func invalidPool(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, ResponseError{
+		Error:   "invalid_pool",
+		Message: "Pool must be one of competitive, premier or all",
+		Code:    http.StatusBadRequest,
+	})
+}
+
+// GetSessionHistory godoc
+// @Summary Get session history
+// @Description Returns the recent picks a roulette session is currently avoiding
+// @Tags maps
+// @Produce json
+// @Param session query string false "Session ID" example:"scrim-night"
+// @Param X-Session-ID header string false "Session ID; takes precedence over the query"
+// @Success 200 {object} roulette.SessionHistory "Successfully retrieved session history"
+// @Failure 400 {object} ResponseError "Missing session ID"
+// @Failure 501 {object} ResponseError "Session history is not enabled"
+// @Failure 503 {object} ResponseError "Session store unavailable"
+// @Router /map/session/history [get]
+func (r *RouletteHandler) GetSessionHistory(c *gin.Context) {
+	id := sessionID(c)
+	if id == "" {
+		r.missingSession(c)
+		return
+	}
+
+	history, err := r.service.SessionHistory(middleware.GetRequestContext(c), id)
+	if err != nil {
+		r.handleSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// ResetSessionHistory godoc
+// @Summary Reset session history
+// @Description Forgets a roulette session's recent picks so every map is eligible again
+// @Tags maps
+// @Param session query string false "Session ID" example:"scrim-night"
+// @Param X-Session-ID header string false "Session ID; takes precedence over the query"
+// @Success 204 "Session history reset"
+// @Failure 400 {object} ResponseError "Missing session ID"
+// @Failure 501 {object} ResponseError "Session history is not enabled"
+// @Failure 503 {object} ResponseError "Session store unavailable"
+// @Router /map/session/history [delete]
+func (r *RouletteHandler) ResetSessionHistory(c *gin.Context) {
+	id := sessionID(c)
+	if id == "" {
+		r.missingSession(c)
+		return
+	}
+
+	if err := r.service.ResetSession(middleware.GetRequestContext(c), id); err != nil {
+		r.handleSessionError(c, err)
+		return
+	}
+
+	logrus.WithField("session_id", id).Info("Reset session history")
+	c.Status(http.StatusNoContent)
+}
+
+// missingSession rejects session requests without an ID
+func (r *RouletteHandler) missingSession(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, ResponseError{
+		Error:   "missing_session",
+		Message: "Provide a session ID in the " + SessionHeader + " header or the session query parameter",
+		Code:    http.StatusBadRequest,
+	})
+}
+
+// handleSessionError maps session store errors to HTTP responses
+func (r *RouletteHandler) handleSessionError(c *gin.Context, err error) {
+	logrus.WithError(err).Error("Failed to access session history")
+
+	statusCode := http.StatusInternalServerError
+	errMsg := "Failed to access session history"
+
+	switch {
+	case errors.Is(err, roulette.ErrNoSessions):
+		statusCode = http.StatusNotImplemented
+		errMsg = "Session history is not enabled"
+	case errors.Is(err, session.ErrStoreUnavailable):
+		statusCode = http.StatusServiceUnavailable
+		errMsg = "Session store unavailable"
+	}
+
+	c.JSON(statusCode, ResponseError{
+		Error:   err.Error(),
+		Message: errMsg,
+		Code:    statusCode,
+	})
+}
+
+// sessionID reads the session ID from the header, falling back to the query string
+func sessionID(c *gin.Context) string {
+	if id := c.GetHeader(SessionHeader); id != "" {
+		return id
+	}
+	return c.Query("session")
+}
+
+// GetCatalogStatus godoc
+// @Summary Get map catalog status
+// @Description Returns the freshness of the in-memory map catalog, including its age and the last refresh error
+// @Tags maps
+// @Produce json
+// @Success 200 {object} catalog.Status "Successfully retrieved catalog status"
+// @Router /map/catalog [get]
+func (r *RouletteHandler) GetCatalogStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, r.service.CatalogStatus())
+}
+
+// setCatalogAgeHeader reports how old the served map data is, in whole seconds
+func (r *RouletteHandler) setCatalogAgeHeader(c *gin.Context) {
+	status := r.service.CatalogStatus()
+	if status.LastRefreshed.IsZero() {
+		return
+	}
+	age := int64(time.Since(status.LastRefreshed).Seconds())
+	c.Header("X-Map-Catalog-Age", strconv.FormatInt(age, 10))
+}
+
 // GetRouteInfos implements handler.Handler interface
 func (r *RouletteHandler) GetRouteInfos() []handler.RouteInfo {
 	return []handler.RouteInfo{
@@ -177,21 +614,59 @@ func (r *RouletteHandler) GetRouteInfos() []handler.RouteInfo {
 			Middlewares: []gin.HandlerFunc{},
 			Handler:     r.GetMap,
 		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/map/roulette",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     r.PostMap,
+		},
 		{
 			Method:      http.MethodGet,
 			Path:        "/map/roulette/standard",
 			Middlewares: []gin.HandlerFunc{},
 			Handler: func(c *gin.Context) {
-				// Force standard mode for the /standard endpoint
-				c.Request.URL.RawQuery = "standard=true"
+				// Force standard mode for the /standard endpoint, keeping bans and seed
+				query := c.Request.URL.Query()
+				query.Set("standard", "true")
+				c.Request.URL.RawQuery = query.Encode()
 				r.GetMap(c)
 			},
 		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/map/roulette/series",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     r.GetSeries,
+		},
 		{
 			Method:      http.MethodGet,
 			Path:        "/map/all",
 			Middlewares: []gin.HandlerFunc{},
 			Handler:     r.GetAllMaps,
 		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/map/pool",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     r.GetPool,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/map/catalog",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     r.GetCatalogStatus,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/map/session/history",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     r.GetSessionHistory,
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/map/session/history",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     r.ResetSessionHistory,
+		},
 	}
 }
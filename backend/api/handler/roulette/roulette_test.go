@@ -3,14 +3,19 @@ package roulette
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	domain "github.com/jungtechou/valomap/domain/map"
 	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/catalog"
 	"github.com/jungtechou/valomap/service/roulette"
+	"github.com/jungtechou/valomap/service/session"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -18,6 +23,7 @@ import (
 // Mock roulette service
 type mockRouletteService struct {
 	mock.Mock
+	catalogStatus catalog.Status
 }
 
 func (m *mockRouletteService) GetRandomMap(ctx ctx.CTX, filter roulette.MapFilter) (*domain.Map, error) {
@@ -28,11 +34,60 @@ func (m *mockRouletteService) GetRandomMap(ctx ctx.CTX, filter roulette.MapFilte
 	return args.Get(0).(*domain.Map), args.Error(1)
 }
 
+func (m *mockRouletteService) DrawMap(ctx ctx.CTX, filter roulette.MapFilter, seed *int64) (*roulette.DrawResult, error) {
+	args := m.Called(ctx, filter, seed)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*roulette.DrawResult), args.Error(1)
+}
+
+func (m *mockRouletteService) DrawMapUnrecorded(ctx ctx.CTX, filter roulette.MapFilter, seed *int64) (*roulette.DrawResult, error) {
+	args := m.Called(ctx, filter, seed)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*roulette.DrawResult), args.Error(1)
+}
+
+func (m *mockRouletteService) DrawSeries(ctx ctx.CTX, filter roulette.MapFilter, count int, seed *int64) (*roulette.SeriesResult, error) {
+	args := m.Called(ctx, filter, count, seed)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*roulette.SeriesResult), args.Error(1)
+}
+
 func (m *mockRouletteService) GetAllMaps(ctx ctx.CTX) ([]domain.Map, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]domain.Map), args.Error(1)
 }
 
+func (m *mockRouletteService) GetPool(ctx ctx.CTX, name string) (*domain.MapPool, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.MapPool), args.Error(1)
+}
+
+func (m *mockRouletteService) SessionHistory(ctx ctx.CTX, sessionID string) (*roulette.SessionHistory, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*roulette.SessionHistory), args.Error(1)
+}
+
+func (m *mockRouletteService) ResetSession(ctx ctx.CTX, sessionID string) error {
+	args := m.Called(ctx, sessionID)
+	return args.Error(0)
+}
+
+func (m *mockRouletteService) CatalogStatus() catalog.Status {
+	return m.catalogStatus
+}
+
 func setupRouter(handler Handler) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -62,11 +117,15 @@ func TestGetMap_Success(t *testing.T) {
 	// Setup
 	mockService := new(mockRouletteService)
 
-	// Mock the GetRandomMap call
-	testMap := &domain.Map{
-		UUID:        "test-map-id",
-		DisplayName: "Test Map",
-		DisplayIcon: "test-icon-url",
+	// Mock the DrawMap call
+	testMap := &roulette.DrawResult{
+		Map: domain.Map{
+			UUID:        "test-map-id",
+			DisplayName: "Test Map",
+			DisplayIcon: "test-icon-url",
+		},
+		Seed:       42,
+		Candidates: []string{"test-map-id"},
 	}
 
 	// Define the expected filter
@@ -76,7 +135,7 @@ func TestGetMap_Success(t *testing.T) {
 	}
 
 	// Setup mock to match any context with the expected filter
-	mockService.On("GetRandomMap", mock.Anything, expectedFilter).Return(testMap, nil)
+	mockService.On("DrawMap", mock.Anything, expectedFilter, (*int64)(nil)).Return(testMap, nil)
 
 	// Create handler and router
 	handler := NewHandler(mockService)
@@ -100,6 +159,8 @@ func TestGetMap_Success(t *testing.T) {
 	// Verify response
 	assert.Equal(t, "test-map-id", response["uuid"])
 	assert.Equal(t, "Test Map", response["displayName"])
+	assert.Equal(t, "42", response["seed"])
+	assert.Equal(t, []interface{}{"test-map-id"}, response["candidates"])
 
 	// Verify mock expectations
 	mockService.AssertExpectations(t)
@@ -109,8 +170,8 @@ func TestGetMap_Error(t *testing.T) {
 	// Setup
 	mockService := new(mockRouletteService)
 
-	// Mock the GetRandomMap call to return an error
-	mockService.On("GetRandomMap", mock.Anything, mock.Anything).Return(nil, errors.New("service error"))
+	// Mock the DrawMap call to return an error
+	mockService.On("DrawMap", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("service error"))
 
 	// Create handler and router
 	handler := NewHandler(mockService)
@@ -212,41 +273,49 @@ func TestGetRouteInfos(t *testing.T) {
 	routes := handler.GetRouteInfos()
 
 	// Assertions
-	assert.Len(t, routes, 3) // Expect 3 routes: /map/roulette, /map/roulette/standard, and /map/all
+	assert.Len(t, routes, 9) // Expect 9 routes: GET and POST /map/roulette, /map/roulette/standard, /map/roulette/series, /map/all, /map/pool, /map/catalog and GET and DELETE /map/session/history
 
 	// Verify routes
-	assert.Equal(t, http.MethodGet, routes[0].Method)
-	assert.Equal(t, "/map/roulette", routes[0].Path)
-	assert.NotNil(t, routes[0].Handler)
-	assert.Empty(t, routes[0].Middlewares)
-
-	assert.Equal(t, http.MethodGet, routes[1].Method)
-	assert.Equal(t, "/map/roulette/standard", routes[1].Path)
-	assert.NotNil(t, routes[1].Handler)
-	assert.Empty(t, routes[1].Middlewares)
-
-	assert.Equal(t, http.MethodGet, routes[2].Method)
-	assert.Equal(t, "/map/all", routes[2].Path)
-	assert.NotNil(t, routes[2].Handler)
-	assert.Empty(t, routes[2].Middlewares)
+	expected := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/map/roulette"},
+		{http.MethodPost, "/map/roulette"},
+		{http.MethodGet, "/map/roulette/standard"},
+		{http.MethodGet, "/map/roulette/series"},
+		{http.MethodGet, "/map/all"},
+		{http.MethodGet, "/map/pool"},
+		{http.MethodGet, "/map/catalog"},
+		{http.MethodGet, "/map/session/history"},
+		{http.MethodDelete, "/map/session/history"},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.method, routes[i].Method)
+		assert.Equal(t, e.path, routes[i].Path)
+		assert.NotNil(t, routes[i].Handler)
+		assert.Empty(t, routes[i].Middlewares)
+	}
 }
 
 func TestStandardMapEndpoint(t *testing.T) {
 	// Setup
 	mockService := new(mockRouletteService)
 
-	// Mock the GetRandomMap call
-	testMap := &domain.Map{
-		UUID:        "standard-map-id",
-		DisplayName: "Standard Map",
-		DisplayIcon: "standard-icon-url",
+	// Mock the DrawMap call
+	testMap := &roulette.DrawResult{
+		Map: domain.Map{
+			UUID:        "standard-map-id",
+			DisplayName: "Standard Map",
+			DisplayIcon: "standard-icon-url",
+		},
 	}
 
 	// Define the expected filter (standard=true is enforced by the endpoint)
 	// Use mock.Anything for the BannedMapIDs to avoid nil vs empty slice comparison issues
-	mockService.On("GetRandomMap", mock.Anything, mock.MatchedBy(func(filter roulette.MapFilter) bool {
+	mockService.On("DrawMap", mock.Anything, mock.MatchedBy(func(filter roulette.MapFilter) bool {
 		return filter.StandardOnly == true
-	})).Return(testMap, nil)
+	}), mock.Anything).Return(testMap, nil)
 
 	// Create handler and router
 	handler := NewHandler(mockService)
@@ -275,6 +344,356 @@ func TestStandardMapEndpoint(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestGetMap_Seed(t *testing.T) {
+	// Setup
+	mockService := new(mockRouletteService)
+
+	seed := int64(8675309)
+	testMap := &roulette.DrawResult{
+		Map:        domain.Map{UUID: "map2", DisplayName: "Map Two"},
+		Seed:       seed,
+		Candidates: []string{"map1", "map2"},
+	}
+	mockService.On("DrawMap", mock.Anything, mock.MatchedBy(func(filter roulette.MapFilter) bool {
+		return filter.StandardOnly && len(filter.BannedMapIDs) == 1
+	}), &seed).Return(testMap, nil)
+
+	// Create handler and router
+	handler := NewHandler(mockService)
+	router := setupRouter(handler)
+
+	// The seed and bans survive the /standard rewrite
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/map/roulette/standard?seed=8675309&banned=map3", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response RouletteResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "map2", response.UUID)
+	assert.Equal(t, seed, response.Seed)
+	assert.Equal(t, []string{"map1", "map2"}, response.Candidates)
+
+	// An invalid seed is rejected before reaching the service
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/roulette?seed=abc", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetMap_Weights(t *testing.T) {
+	// Setup
+	mockService := new(mockRouletteService)
+
+	testMap := &roulette.DrawResult{
+		Map:        domain.Map{UUID: "map1", DisplayName: "Map One"},
+		Candidates: []string{"map1", "map2"},
+	}
+	expectedFilter := roulette.MapFilter{
+		Weights: map[string]float64{"map1": 3, "map2": 0.5},
+	}
+	mockService.On("DrawMap", mock.Anything, expectedFilter, (*int64)(nil)).Return(testMap, nil)
+
+	// Create handler and router
+	handler := NewHandler(mockService)
+	router := setupRouter(handler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/map/roulette?weight[map1]=3&weight[map2]=0.5", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A weight that is not a number is rejected before reaching the service
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/roulette?weight[map1]=lots", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response ResponseError
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid_weight", response.Error)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestPostMap(t *testing.T) {
+	// Setup
+	mockService := new(mockRouletteService)
+
+	seed := int64(8675309)
+	testMap := &roulette.DrawResult{
+		Map:        domain.Map{UUID: "map2", DisplayName: "Map Two"},
+		Seed:       seed,
+		Candidates: []string{"map2", "map3"},
+	}
+	expectedFilter := roulette.MapFilter{
+		StandardOnly: true,
+		BannedMapIDs: []string{"map1"},
+		Weights:      map[string]float64{"map2": 3},
+	}
+	mockService.On("DrawMap", mock.Anything, expectedFilter, &seed).Return(testMap, nil)
+
+	// Create handler and router
+	handler := NewHandler(mockService)
+	router := setupRouter(handler)
+
+	body := `{"standard":true,"banned":["map1"],"weights":{"map2":3},"seed":"8675309"}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/map/roulette", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response RouletteResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "map2", response.UUID)
+	assert.Equal(t, seed, response.Seed)
+
+	// Malformed bodies are rejected
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/map/roulette", strings.NewReader(`{"weights":{"map2":"heavy"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetMap_Session(t *testing.T) {
+	// Setup
+	mockService := new(mockRouletteService)
+
+	testMap := &roulette.DrawResult{Map: domain.Map{UUID: "map1"}}
+	mockService.On("DrawMap", mock.Anything, roulette.MapFilter{SessionID: "from-header"}, (*int64)(nil)).Return(testMap, nil).Once()
+	mockService.On("DrawMap", mock.Anything, roulette.MapFilter{SessionID: "from-query"}, (*int64)(nil)).Return(testMap, nil).Once()
+
+	// Create handler and router
+	handler := NewHandler(mockService)
+	router := setupRouter(handler)
+
+	// The header wins over the query parameter
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/map/roulette?session=from-query", nil)
+	req.Header.Set(SessionHeader, "from-header")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/roulette?session=from-query", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestSessionHistoryEndpoints(t *testing.T) {
+	// Setup
+	mockService := new(mockRouletteService)
+
+	history := &roulette.SessionHistory{
+		SessionID: "scrim",
+		Recent:    []string{"map2", "map1"},
+		Limit:     3,
+		Mode:      "exclude",
+	}
+	mockService.On("SessionHistory", mock.Anything, "scrim").Return(history, nil)
+	mockService.On("SessionHistory", mock.Anything, "broken").Return(nil, session.ErrStoreUnavailable)
+	mockService.On("ResetSession", mock.Anything, "scrim").Return(nil)
+	mockService.On("ResetSession", mock.Anything, "disabled").Return(roulette.ErrNoSessions)
+
+	// Create handler and router
+	handler := NewHandler(mockService)
+	router := setupRouter(handler)
+
+	// View history
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/map/session/history", nil)
+	req.Header.Set(SessionHeader, "scrim")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response roulette.SessionHistory
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, *history, response)
+
+	// Reset history
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/map/session/history?session=scrim", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Errors map to status codes
+	testCases := []struct {
+		method         string
+		url            string
+		expectedStatus int
+	}{
+		{"GET", "/map/session/history", http.StatusBadRequest},
+		{"DELETE", "/map/session/history", http.StatusBadRequest},
+		{"GET", "/map/session/history?session=broken", http.StatusServiceUnavailable},
+		{"DELETE", "/map/session/history?session=disabled", http.StatusNotImplemented},
+	}
+	for _, tc := range testCases {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(tc.method, tc.url, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.expectedStatus, w.Code, "%s %s", tc.method, tc.url)
+	}
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetSeries(t *testing.T) {
+	// Setup
+	mockService := new(mockRouletteService)
+
+	seed := int64(8675309)
+	series := &roulette.SeriesResult{
+		Maps:       []domain.Map{{UUID: "map3"}, {UUID: "map1"}, {UUID: "map2"}, {UUID: "map5"}, {UUID: "map4"}},
+		Seed:       seed,
+		Candidates: []string{"map1", "map2", "map3", "map4", "map5"},
+	}
+	expectedFilter := roulette.MapFilter{StandardOnly: true, BannedMapIDs: []string{"map6"}}
+	mockService.On("DrawSeries", mock.Anything, expectedFilter, 5, &seed).Return(series, nil)
+	mockService.On("DrawSeries", mock.Anything, roulette.MapFilter{}, DefaultSeriesCount, (*int64)(nil)).
+		Return(nil, fmt.Errorf("%w: requested 3 maps but only 2 are eligible", roulette.ErrNotEnoughMaps))
+
+	// Create handler and router
+	handler := NewHandler(mockService)
+	router := setupRouter(handler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/map/roulette/series?count=5&standard=true&banned=map6&seed=8675309", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response SeriesResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, series.Maps, response.Maps)
+	assert.Equal(t, seed, response.Seed)
+	assert.Equal(t, series.Candidates, response.Candidates)
+
+	// The default count is used when none is given; a small pool is a client error
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/roulette/series", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Invalid counts are rejected before reaching the service
+	for _, count := range []string{"0", "-1", "abc", "26"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/map/roulette/series?count="+count, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "count=%s", count)
+	}
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetMap_Pool(t *testing.T) {
+	// Setup
+	mockService := new(mockRouletteService)
+
+	testMap := &roulette.DrawResult{Map: domain.Map{UUID: "map1"}}
+	mockService.On("DrawMap", mock.Anything, roulette.MapFilter{Pool: domain.PoolPremier}, (*int64)(nil)).Return(testMap, nil)
+
+	// Create handler and router
+	handler := NewHandler(mockService)
+	router := setupRouter(handler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/map/roulette?pool=premier", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Unknown pools are rejected before reaching the service
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/roulette?pool=casual", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetPool(t *testing.T) {
+	// Setup
+	mockService := new(mockRouletteService)
+
+	competitive := &domain.MapPool{
+		Name:          domain.PoolCompetitive,
+		Season:        "Current",
+		EffectiveFrom: time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC),
+		Maps:          []domain.Map{{UUID: "map1"}, {UUID: "map2"}},
+	}
+	mockService.On("GetPool", mock.Anything, domain.PoolCompetitive).Return(competitive, nil)
+	mockService.On("GetPool", mock.Anything, domain.PoolPremier).Return(nil, roulette.ErrNoActivePool)
+	mockService.On("GetAllMaps", mock.Anything).Return([]domain.Map{{UUID: "map1"}, {UUID: "map2"}, {UUID: "map3"}}, nil)
+
+	// Create handler and router
+	handler := NewHandler(mockService)
+	router := setupRouter(handler)
+
+	// The pool endpoint defaults to competitive
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/map/pool", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var pool domain.MapPool
+	err := json.Unmarshal(w.Body.Bytes(), &pool)
+	assert.NoError(t, err)
+	assert.Equal(t, *competitive, pool)
+
+	// /map/all honours the pool filter
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/all?pool=competitive", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var maps []domain.Map
+	err = json.Unmarshal(w.Body.Bytes(), &maps)
+	assert.NoError(t, err)
+	assert.Len(t, maps, 2)
+
+	// pool=all lists every map
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/all?pool=all", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &maps)
+	assert.NoError(t, err)
+	assert.Len(t, maps, 3)
+
+	// Errors map to status codes
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/pool?pool=premier", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/all?pool=casual", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
 func TestHandleError(t *testing.T) {
 	// Setup test cases for different error types
 	testCases := []struct {
@@ -313,6 +732,42 @@ func TestHandleError(t *testing.T) {
 			expectedStatus: http.StatusServiceUnavailable,
 			expectedMsg:    "Map service unavailable",
 		},
+		{
+			name:           "ErrInvalidWeight",
+			err:            roulette.ErrInvalidWeight,
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "Map weights must be non-negative numbers",
+		},
+		{
+			name:           "ErrZeroWeights",
+			err:            roulette.ErrZeroWeights,
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "At least one eligible map needs a positive weight",
+		},
+		{
+			name:           "ErrUnknownPool",
+			err:            roulette.ErrUnknownPool,
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "Unknown map pool",
+		},
+		{
+			name:           "ErrEmptyPool",
+			err:            roulette.ErrEmptyPool,
+			expectedStatus: http.StatusNotFound,
+			expectedMsg:    "No maps in the active pool rotation",
+		},
+		{
+			name:           "ErrInvalidCount",
+			err:            roulette.ErrInvalidCount,
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "Series count must be at least 1",
+		},
+		{
+			name:           "ErrNotEnoughMaps",
+			err:            roulette.ErrNotEnoughMaps,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedMsg:    "Not enough maps match the filter for this series",
+		},
 		{
 			name:           "Generic error",
 			err:            errors.New("generic error"),
@@ -354,3 +809,41 @@ func TestHandleError(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCatalogStatus(t *testing.T) {
+	// Setup
+	lastRefreshed := time.Now().Add(-90 * time.Second)
+	mockService := &mockRouletteService{
+		catalogStatus: catalog.Status{
+			MapCount:      2,
+			LastRefreshed: lastRefreshed,
+			Age:           "1m30s",
+		},
+	}
+	mockService.On("GetAllMaps", mock.Anything).Return([]domain.Map{{UUID: "map1"}, {UUID: "map2"}}, nil)
+
+	// Create handler and router
+	handler := NewHandler(mockService)
+	router := setupRouter(handler)
+
+	// Status endpoint reports the catalog snapshot
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/map/catalog", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response catalog.Status
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, response.MapCount)
+	assert.Equal(t, "1m30s", response.Age)
+
+	// Map responses carry the catalog age header
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/map/all", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "90", w.Header().Get("X-Map-Catalog-Age"))
+}
@@ -0,0 +1,18 @@
+package stats
+
+import (
+	"github.com/jungtechou/valomap/api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	_ Handler = (*StatsHandler)(nil)
+)
+
+type Handler interface {
+	handler.Handler
+
+	// GetMapStats returns per-map draw and ban counts with a fairness check
+	GetMapStats(c *gin.Context)
+}
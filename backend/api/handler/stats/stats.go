@@ -0,0 +1,173 @@
+package stats
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jungtechou/valomap/api/handler"
+	"github.com/jungtechou/valomap/api/middleware"
+	"github.com/jungtechou/valomap/service/stats"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// dateLayout is accepted for from and to besides RFC 3339 timestamps
+const dateLayout = "2006-01-02"
+
+// ResponseError represents an API error response
+type ResponseError struct {
+	Error   string `json:"error" example:"invalid_window"`
+	Message string `json:"message,omitempty" example:"Window must be a duration such as 24h or 7d"`
+	Code    int    `json:"code" example:"400"`
+}
+
+// NewHandler creates a new stats handler instance
+func NewHandler(service stats.Service) Handler {
+	return &StatsHandler{service: service, now: time.Now}
+}
+
+// StatsHandler handles map statistics requests
+type StatsHandler struct {
+	service stats.Service
+	now     func() time.Time
+}
+
+// GetMapStats godoc
+// @Summary Get map pick statistics
+// @Description Returns how often each map was drawn and banned, globally or for one session, with a chi-squared test of the roulette against uniform selection.
+// @Description The time range is either window (e.g. 24h, 7d), ending at to or now, or explicit from and to bounds.
+// @Description Dates are RFC 3339 timestamps or plain dates (YYYY-MM-DD, UTC); from is inclusive and to is exclusive.
+// @Tags stats
+// @Produce json
+// @Param session query string false "Only draws of this session" example:"scrim-night"
+// @Param window query string false "Only draws within this duration, in hours (h) or days (d)" example:"7d"
+// @Param from query string false "Only draws at or after this time" example:"2025-01-01"
+// @Param to query string false "Only draws before this time" example:"2025-02-01T00:00:00Z"
+// @Success 200 {object} stats.Report "Successfully computed statistics"
+// @Failure 400 {object} ResponseError "Invalid window or dates"
+// @Failure 503 {object} ResponseError "Draw history is not enabled or unavailable"
+// @Router /stats/maps [get]
+func (h *StatsHandler) GetMapStats(c *gin.Context) {
+	query := stats.Query{SessionID: c.Query("session")}
+
+	var ok bool
+	if query.From, ok = parseTime(c, "from"); !ok {
+		return
+	}
+	if query.To, ok = parseTime(c, "to"); !ok {
+		return
+	}
+
+	if windowQuery := c.Query("window"); windowQuery != "" {
+		window, err := parseWindow(windowQuery)
+		if err != nil || !query.From.IsZero() {
+			c.JSON(http.StatusBadRequest, ResponseError{
+				Error:   "invalid_window",
+				Message: "Window must be a positive duration such as 24h or 7d, and cannot be combined with from",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+
+		end := query.To
+		if end.IsZero() {
+			end = h.now()
+		}
+		query.From = end.Add(-window)
+	}
+
+	report, err := h.service.MapStats(middleware.GetRequestContext(c), query)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseWindow parses a Go duration or a whole number of days such as 7d
+func parseWindow(value string) (time.Duration, error) {
+	var window time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if window, err = time.ParseDuration(value); err != nil {
+			return 0, err
+		}
+	}
+
+	if window <= 0 {
+		return 0, errors.New("window must be positive")
+	}
+	return window, nil
+}
+
+// parseTime reads an optional RFC 3339 timestamp or date query parameter.
+// It writes a 400 response and returns false when the value cannot be parsed.
+func parseTime(c *gin.Context, name string) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, true
+	}
+
+	c.JSON(http.StatusBadRequest, ResponseError{
+		Error:   "invalid_" + name,
+		Message: "The " + name + " parameter must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+		Code:    http.StatusBadRequest,
+	})
+	return time.Time{}, false
+}
+
+// handleError maps stats service errors to HTTP responses
+func (h *StatsHandler) handleError(c *gin.Context, err error) {
+	logrus.WithError(err).WithField("handler", "stats").Warn("Stats request failed")
+
+	// Clients get a stable code; the wrapped error, which may name database details, is only logged
+	statusCode := http.StatusServiceUnavailable
+	errCode := "history_unavailable"
+	errMsg := "Draw history unavailable"
+
+	switch {
+	case errors.Is(err, stats.ErrInvalidQuery):
+		statusCode = http.StatusBadRequest
+		errCode = "invalid_query"
+		errMsg = "Invalid stats query"
+	case errors.Is(err, stats.ErrDisabled):
+		errCode = "history_disabled"
+		errMsg = "Draw history is not enabled on this server"
+	}
+
+	c.JSON(statusCode, ResponseError{
+		Error:   errCode,
+		Message: errMsg,
+		Code:    statusCode,
+	})
+}
+
+// GetRouteInfos implements handler.Handler interface
+func (h *StatsHandler) GetRouteInfos() []handler.RouteInfo {
+	return []handler.RouteInfo{
+		{
+			Method:      http.MethodGet,
+			Path:        "/stats/maps",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.GetMapStats,
+		},
+	}
+}
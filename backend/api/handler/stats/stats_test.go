@@ -0,0 +1,134 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock stats service
+type mockStatsService struct {
+	mock.Mock
+}
+
+func (m *mockStatsService) MapStats(ctx ctx.CTX, query stats.Query) (*stats.Report, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*stats.Report), args.Error(1)
+}
+
+func setupRouter(handler Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	// Register routes
+	routes := handler.GetRouteInfos()
+	for _, route := range routes {
+		r.Handle(route.Method, route.Path, route.GetFlow()...)
+	}
+
+	return r
+}
+
+func TestGetRouteInfos(t *testing.T) {
+	handler := NewHandler(new(mockStatsService))
+
+	routes := handler.GetRouteInfos()
+	assert.Len(t, routes, 1)
+	assert.Equal(t, http.MethodGet, routes[0].Method)
+	assert.Equal(t, "/stats/maps", routes[0].Path)
+	assert.NotNil(t, routes[0].Handler)
+}
+
+func TestGetMapStats(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected stats.Query
+	}{
+		{"Global", "", stats.Query{}},
+		{"Session", "?session=scrims", stats.Query{SessionID: "scrims"}},
+		{"WindowEndsNow", "?window=7d", stats.Query{From: now.Add(-7 * 24 * time.Hour)}},
+		{"WindowEndsAtTo", "?window=36h&to=2025-03-01", stats.Query{From: to.Add(-36 * time.Hour), To: to}},
+		{"Range", "?from=2025-01-01&to=2025-03-01T00:00:00Z", stats.Query{From: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), To: to}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(mockStatsService)
+			report := &stats.Report{Draws: 2, Picks: 2, Maps: []stats.MapStats{{MapUUID: "uuid1", Drawn: 2, DrawRate: 1}}}
+			mockService.On("MapStats", mock.Anything, tc.expected).Return(report, nil)
+
+			h := NewHandler(mockService).(*StatsHandler)
+			h.now = func() time.Time { return now }
+			router := setupRouter(h)
+
+			req, _ := http.NewRequest(http.MethodGet, "/stats/maps"+tc.query, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			var body stats.Report
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, *report, body)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetMapStatsInvalidParams(t *testing.T) {
+	router := setupRouter(NewHandler(new(mockStatsService)))
+
+	for _, query := range []string{"window=week", "window=-1h", "window=0d", "window=7d&from=2025-01-01", "from=yesterday", "to=2025-02-30"} {
+		req, _ := http.NewRequest(http.MethodGet, "/stats/maps?"+query, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, query)
+	}
+}
+
+func TestGetMapStatsErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		expectedCode  int
+		expectedError string
+	}{
+		{"Disabled", stats.ErrDisabled, http.StatusServiceUnavailable, "history_disabled"},
+		{"InvalidQuery", fmt.Errorf("%w: to must be after from", stats.ErrInvalidQuery), http.StatusBadRequest, "invalid_query"},
+		{"StoreFailure", errors.New("dial tcp 10.0.0.5:5432: connection refused"), http.StatusServiceUnavailable, "history_unavailable"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(mockStatsService)
+			mockService.On("MapStats", mock.Anything, mock.Anything).Return(nil, tc.err)
+
+			router := setupRouter(NewHandler(mockService))
+			req, _ := http.NewRequest(http.MethodGet, "/stats/maps", nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.expectedCode, resp.Code)
+			var body ResponseError
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, tc.expectedError, body.Error)
+			assert.NotContains(t, resp.Body.String(), "connection refused")
+		})
+	}
+}
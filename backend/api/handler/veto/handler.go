@@ -0,0 +1,30 @@
+package veto
+
+import (
+	"github.com/jungtechou/valomap/api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	_ Handler = (*VetoHandler)(nil)
+)
+
+type Handler interface {
+	handler.Handler
+
+	// CreateVeto starts a veto session for two teams
+	CreateVeto(c *gin.Context)
+
+	// GetVeto returns the current state of a veto session
+	GetVeto(c *gin.Context)
+
+	// Ban bans a map for the team whose turn it is
+	Ban(c *gin.Context)
+
+	// Pick picks a map for the team whose turn it is
+	Pick(c *gin.Context)
+
+	// Side chooses the starting side on the last picked map
+	Side(c *gin.Context)
+}
@@ -0,0 +1,314 @@
+package veto
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jungtechou/valomap/api/handler"
+	"github.com/jungtechou/valomap/api/middleware"
+	"github.com/jungtechou/valomap/service/rotation"
+	"github.com/jungtechou/valomap/service/roulette"
+	"github.com/jungtechou/valomap/service/veto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// TeamTokenHeader carries the acting team's token returned when the veto was created
+	TeamTokenHeader = "X-Team-Token"
+)
+
+// ResponseError represents an API error response
+type ResponseError struct {
+	Error   string `json:"error" example:"not_your_turn"`
+	Message string `json:"message,omitempty" example:"It is not this team's turn"`
+	Code    int    `json:"code" example:"409"`
+}
+
+// CreateVetoRequest is the body of a new veto session
+type CreateVetoRequest struct {
+	Teams  [2]string `json:"teams" example:"Team Liquid,Fnatic"`
+	Format string    `json:"format,omitempty" example:"bo3"`
+	Pool   string    `json:"pool,omitempty" example:"competitive"`
+	Seed   *int64    `json:"seed,string,omitempty" example:"8675309"`
+}
+
+// TurnRequest is the body of a ban, pick or side turn
+type TurnRequest struct {
+	Team string `json:"team" binding:"required" example:"Team Liquid"`
+	Map  string `json:"map,omitempty" example:"7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+	Side string `json:"side,omitempty" example:"attack"`
+}
+
+// NewHandler creates a new veto handler instance
+func NewHandler(service veto.Service) Handler {
+	return &VetoHandler{service: service}
+}
+
+// VetoHandler handles veto session requests
+type VetoHandler struct {
+	service veto.Service
+}
+
+// CreateVeto godoc
+// @Summary Create a veto session
+// @Description Starts a map veto for two teams. The first team acts first and turns alternate between teams.
+// @Description The format is a preset (bo1, bo3, bo5) or a dash-separated list of ban, pick and decider steps, e.g. ban-ban-pick-pick-ban-ban-decider.
+// @Description Each pick is followed by a side choice for the other team; the decider is drawn by the server from the remaining maps.
+// @Description The response includes one secret token per team, in the order of teams; each team sends its own in the X-Team-Token header to take its turns.
+// @Tags veto
+// @Accept json
+// @Produce json
+// @Param request body CreateVetoRequest true "Veto session"
+// @Success 201 {object} veto.Created "Veto session created"
+// @Failure 400 {object} ResponseError "Invalid teams, format or pool"
+// @Failure 404 {object} ResponseError "No active rotation for the pool"
+// @Failure 422 {object} ResponseError "Pool has too few maps for the format"
+// @Failure 503 {object} ResponseError "Map service unavailable"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /veto [post]
+func (h *VetoHandler) CreateVeto(c *gin.Context) {
+	var req CreateVetoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+
+	if req.Pool != "" && !rotation.IsPool(req.Pool) {
+		c.JSON(http.StatusBadRequest, ResponseError{
+			Error:   "invalid_pool",
+			Message: "Pool must be one of competitive, premier or all",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	sess, err := h.service.Create(middleware.GetRequestContext(c), veto.CreateRequest{
+		Teams:  req.Teams,
+		Format: req.Format,
+		Pool:   req.Pool,
+		Seed:   req.Seed,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"veto_id": sess.ID,
+		"format":  sess.Format,
+		"pool":    sess.Pool,
+	}).Info("Created veto session")
+
+	c.JSON(http.StatusCreated, sess)
+}
+
+// GetVeto godoc
+// @Summary Get a veto session
+// @Description Returns the turns taken so far, the next turn, the remaining maps and the resulting map order.
+// @Description A decider draw that failed after the last turn is retried first.
+// @Tags veto
+// @Produce json
+// @Param id path string true "Veto session ID"
+// @Success 200 {object} veto.Session "Successfully retrieved veto session"
+// @Failure 404 {object} ResponseError "Veto session not found"
+// @Router /veto/{id} [get]
+func (h *VetoHandler) GetVeto(c *gin.Context) {
+	sess, err := h.service.Get(middleware.GetRequestContext(c), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sess)
+}
+
+// Ban godoc
+// @Summary Ban a map
+// @Description Bans a map for the team whose turn it is
+// @Tags veto
+// @Accept json
+// @Produce json
+// @Param id path string true "Veto session ID"
+// @Param X-Team-Token header string true "Token of the acting team, returned when the veto was created"
+// @Param request body TurnRequest true "Team and map UUID"
+// @Success 200 {object} veto.Session "Map banned"
+// @Failure 400 {object} ResponseError "Invalid request body"
+// @Failure 403 {object} ResponseError "Missing or wrong team token"
+// @Failure 404 {object} ResponseError "Veto session not found"
+// @Failure 409 {object} ResponseError "Not this team's turn, not a ban turn, or map unavailable"
+// @Router /veto/{id}/ban [post]
+func (h *VetoHandler) Ban(c *gin.Context) {
+	h.act(c, veto.ActionBan)
+}
+
+// Pick godoc
+// @Summary Pick a map
+// @Description Picks a map for the team whose turn it is; the other team then chooses a side on it
+// @Tags veto
+// @Accept json
+// @Produce json
+// @Param id path string true "Veto session ID"
+// @Param X-Team-Token header string true "Token of the acting team, returned when the veto was created"
+// @Param request body TurnRequest true "Team and map UUID"
+// @Success 200 {object} veto.Session "Map picked"
+// @Failure 400 {object} ResponseError "Invalid request body"
+// @Failure 403 {object} ResponseError "Missing or wrong team token"
+// @Failure 404 {object} ResponseError "Veto session not found"
+// @Failure 409 {object} ResponseError "Not this team's turn, not a pick turn, or map unavailable"
+// @Router /veto/{id}/pick [post]
+func (h *VetoHandler) Pick(c *gin.Context) {
+	h.act(c, veto.ActionPick)
+}
+
+// Side godoc
+// @Summary Choose a side
+// @Description Chooses the starting side on the most recently picked or drawn map
+// @Tags veto
+// @Accept json
+// @Produce json
+// @Param id path string true "Veto session ID"
+// @Param X-Team-Token header string true "Token of the acting team, returned when the veto was created"
+// @Param request body TurnRequest true "Team and side (attack or defense)"
+// @Success 200 {object} veto.Session "Side chosen"
+// @Failure 400 {object} ResponseError "Invalid request body or side"
+// @Failure 403 {object} ResponseError "Missing or wrong team token"
+// @Failure 404 {object} ResponseError "Veto session not found"
+// @Failure 409 {object} ResponseError "Not this team's turn or not a side turn"
+// @Router /veto/{id}/side [post]
+func (h *VetoHandler) Side(c *gin.Context) {
+	h.act(c, veto.ActionSide)
+}
+
+// act takes a turn of the given action and writes the updated session
+func (h *VetoHandler) act(c *gin.Context, action string) {
+	var req TurnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+
+	id := c.Param("id")
+	sess, err := h.service.Act(middleware.GetRequestContext(c), id, veto.Turn{
+		Team:    req.Team,
+		Token:   c.GetHeader(TeamTokenHeader),
+		Action:  action,
+		MapUUID: req.Map,
+		Side:    req.Side,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"veto_id": id,
+		"team":    req.Team,
+		"action":  action,
+		"status":  sess.Status,
+	}).Info("Veto turn taken")
+
+	c.JSON(http.StatusOK, sess)
+}
+
+// invalidRequest rejects a body that cannot be decoded
+func invalidRequest(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, ResponseError{
+		Error:   "invalid_request",
+		Message: err.Error(),
+		Code:    http.StatusBadRequest,
+	})
+}
+
+// handleError maps veto service errors to HTTP responses
+func (h *VetoHandler) handleError(c *gin.Context, err error) {
+	logrus.WithError(err).WithField("handler", "veto").Warn("Veto request failed")
+
+	statusCode := http.StatusInternalServerError
+	errMsg := "Failed to process veto"
+
+	switch {
+	case errors.Is(err, veto.ErrSessionNotFound):
+		statusCode = http.StatusNotFound
+		errMsg = "Veto session not found"
+	case errors.Is(err, veto.ErrInvalidTeams):
+		statusCode = http.StatusBadRequest
+		errMsg = "A veto needs two distinct team names"
+	case errors.Is(err, veto.ErrInvalidFormat):
+		statusCode = http.StatusBadRequest
+		errMsg = "Format must be bo1, bo3, bo5 or a dash-separated list of ban, pick and decider"
+	case errors.Is(err, veto.ErrInvalidSide):
+		statusCode = http.StatusBadRequest
+		errMsg = "Side must be attack or defense"
+	case errors.Is(err, veto.ErrVetoComplete):
+		statusCode = http.StatusConflict
+		errMsg = "The veto is already complete"
+	case errors.Is(err, veto.ErrNotYourTurn):
+		statusCode = http.StatusConflict
+		errMsg = "It is not this team's turn"
+	case errors.Is(err, veto.ErrWrongToken):
+		statusCode = http.StatusForbidden
+		errMsg = "Send the acting team's token in the " + TeamTokenHeader + " header"
+	case errors.Is(err, veto.ErrWrongAction):
+		statusCode = http.StatusConflict
+		errMsg = "This action is not expected now"
+	case errors.Is(err, veto.ErrMapUnavailable):
+		statusCode = http.StatusConflict
+		errMsg = "The map is not available in this veto"
+	case errors.Is(err, veto.ErrPoolTooSmall):
+		statusCode = http.StatusUnprocessableEntity
+		errMsg = "The map pool has too few maps for this format"
+	case errors.Is(err, roulette.ErrUnknownPool):
+		statusCode = http.StatusBadRequest
+		errMsg = "Unknown map pool"
+	case errors.Is(err, roulette.ErrNoActivePool):
+		statusCode = http.StatusNotFound
+		errMsg = "No active rotation for this pool"
+	case errors.Is(err, roulette.ErrAPIRequest), errors.Is(err, roulette.ErrAPIResponse):
+		statusCode = http.StatusServiceUnavailable
+		errMsg = "Map service unavailable"
+	}
+
+	c.JSON(statusCode, ResponseError{
+		Error:   err.Error(),
+		Message: errMsg,
+		Code:    statusCode,
+	})
+}
+
+// GetRouteInfos implements handler.Handler interface
+func (h *VetoHandler) GetRouteInfos() []handler.RouteInfo {
+	return []handler.RouteInfo{
+		{
+			Method:      http.MethodPost,
+			Path:        "/veto",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.CreateVeto,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/veto/:id",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.GetVeto,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/veto/:id/ban",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.Ban,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/veto/:id/pick",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.Pick,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/veto/:id/side",
+			Middlewares: []gin.HandlerFunc{},
+			Handler:     h.Side,
+		},
+	}
+}
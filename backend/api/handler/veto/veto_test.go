@@ -0,0 +1,201 @@
+package veto
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/roulette"
+	"github.com/jungtechou/valomap/service/veto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock veto service
+type mockVetoService struct {
+	mock.Mock
+}
+
+func (m *mockVetoService) Create(ctx ctx.CTX, req veto.CreateRequest) (*veto.Created, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*veto.Created), args.Error(1)
+}
+
+func (m *mockVetoService) Get(ctx ctx.CTX, id string) (*veto.Session, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*veto.Session), args.Error(1)
+}
+
+func (m *mockVetoService) Act(ctx ctx.CTX, id string, turn veto.Turn) (*veto.Session, error) {
+	args := m.Called(ctx, id, turn)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*veto.Session), args.Error(1)
+}
+
+func setupRouter(handler Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	// Register routes
+	routes := handler.GetRouteInfos()
+	for _, route := range routes {
+		r.Handle(route.Method, route.Path, route.GetFlow()...)
+	}
+
+	return r
+}
+
+func TestGetRouteInfos(t *testing.T) {
+	handler := NewHandler(new(mockVetoService))
+
+	routes := handler.GetRouteInfos()
+	assert.Len(t, routes, 5)
+
+	paths := make(map[string]bool)
+	for _, route := range routes {
+		paths[route.Method+" "+route.Path] = true
+		assert.NotNil(t, route.Handler)
+	}
+	for _, expected := range []string{"POST /veto", "GET /veto/:id", "POST /veto/:id/ban", "POST /veto/:id/pick", "POST /veto/:id/side"} {
+		assert.True(t, paths[expected], "missing route %s", expected)
+	}
+}
+
+func TestCreateVeto(t *testing.T) {
+	mockService := new(mockVetoService)
+	seed := int64(8675309)
+	created := &veto.Created{
+		Session: veto.Session{ID: "veto1", Teams: [2]string{"Alpha", "Bravo"}, Format: "ban-pick-decider", Status: veto.StatusInProgress},
+		Tokens:  [2]string{"token-a", "token-b"},
+	}
+	mockService.On("Create", mock.Anything, veto.CreateRequest{
+		Teams:  [2]string{"Alpha", "Bravo"},
+		Format: "bo1",
+		Pool:   "premier",
+		Seed:   &seed,
+	}).Return(created, nil)
+	mockService.On("Create", mock.Anything, veto.CreateRequest{Teams: [2]string{"Alpha", "Alpha"}}).
+		Return(nil, veto.ErrInvalidTeams)
+
+	router := setupRouter(NewHandler(mockService))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/veto", strings.NewReader(`{"teams":["Alpha","Bravo"],"format":"bo1","pool":"premier","seed":"8675309"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var sess veto.Created
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &sess))
+	assert.Equal(t, "veto1", sess.ID)
+	assert.Equal(t, [2]string{"token-a", "token-b"}, sess.Tokens)
+
+	testCases := []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"teams":["Alpha","Alpha"]}`, http.StatusBadRequest, veto.ErrInvalidTeams.Error()},
+		{`{"teams":["Alpha","Bravo"],"pool":"casual"}`, http.StatusBadRequest, "invalid_pool"},
+		{`not json`, http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/veto", strings.NewReader(tc.body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.body)
+		var resp ResponseError
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, tc.code, resp.Error)
+	}
+}
+
+func TestTurns(t *testing.T) {
+	mockService := new(mockVetoService)
+	updated := &veto.Session{ID: "veto1", Status: veto.StatusInProgress}
+	mockService.On("Act", mock.Anything, "veto1", veto.Turn{Team: "Alpha", Token: "token-a", Action: veto.ActionBan, MapUUID: "map1"}).Return(updated, nil)
+	mockService.On("Act", mock.Anything, "veto1", veto.Turn{Team: "Bravo", Token: "token-b", Action: veto.ActionPick, MapUUID: "map2"}).Return(updated, nil)
+	mockService.On("Act", mock.Anything, "veto1", veto.Turn{Team: "Alpha", Token: "token-a", Action: veto.ActionSide, Side: veto.SideAttack}).Return(updated, nil)
+	mockService.On("Get", mock.Anything, "veto1").Return(updated, nil)
+
+	router := setupRouter(NewHandler(mockService))
+
+	requests := []struct {
+		method string
+		path   string
+		token  string
+		body   string
+	}{
+		{"POST", "/veto/veto1/ban", "token-a", `{"team":"Alpha","map":"map1"}`},
+		{"POST", "/veto/veto1/pick", "token-b", `{"team":"Bravo","map":"map2"}`},
+		{"POST", "/veto/veto1/side", "token-a", `{"team":"Alpha","side":"attack"}`},
+		{"GET", "/veto/veto1", "", ``},
+	}
+	for _, r := range requests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		req.Header.Set(TeamTokenHeader, r.token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, r.path)
+	}
+
+	// A turn without a team is rejected before reaching the service
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/veto/veto1/ban", strings.NewReader(`{"map":"map1"}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandleError(t *testing.T) {
+	testCases := []struct {
+		err    error
+		status int
+	}{
+		{veto.ErrSessionNotFound, http.StatusNotFound},
+		{veto.ErrInvalidTeams, http.StatusBadRequest},
+		{veto.ErrInvalidFormat, http.StatusBadRequest},
+		{veto.ErrInvalidSide, http.StatusBadRequest},
+		{veto.ErrVetoComplete, http.StatusConflict},
+		{veto.ErrNotYourTurn, http.StatusConflict},
+		{veto.ErrWrongToken, http.StatusForbidden},
+		{veto.ErrWrongAction, http.StatusConflict},
+		{veto.ErrMapUnavailable, http.StatusConflict},
+		{veto.ErrPoolTooSmall, http.StatusUnprocessableEntity},
+		{roulette.ErrUnknownPool, http.StatusBadRequest},
+		{roulette.ErrNoActivePool, http.StatusNotFound},
+		{roulette.ErrAPIRequest, http.StatusServiceUnavailable},
+		{assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			mockService := new(mockVetoService)
+			mockService.On("Act", mock.Anything, "veto1", mock.Anything).Return(nil, fmt.Errorf("%w: detail", tc.err))
+			router := setupRouter(NewHandler(mockService))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/veto/veto1/ban", strings.NewReader(`{"team":"Alpha","map":"map1"}`))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code)
+			var resp ResponseError
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.status, resp.Code)
+		})
+	}
+}
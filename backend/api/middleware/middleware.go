@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -156,12 +157,16 @@ func Recovery() gin.HandlerFunc {
 // RequestContext adds a context.Context to the Gin context
 func RequestContext() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Create a new context
-		requestCtx := ctx.Background()
-
-		// Generate a request ID and add it to the context
+		// Generate a request ID and derive the context from the incoming request
+		// so that cancellation and deadlines propagate to downstream calls
 		requestID := uuid.New().String()
-		requestCtx = ctx.WithValue(requestCtx, ctx.RequestIDKey, requestID)
+		parent := context.Background()
+		if c.Request != nil {
+			parent = c.Request.Context()
+		}
+		parent = context.WithValue(parent, ctx.RequestIDKey, requestID)
+		parent = context.WithValue(parent, ctx.StartTimeKey, time.Now())
+		requestCtx := ctx.FromContext(parent)
 
 		// Add client IP
 		requestCtx = ctx.WithValue(requestCtx, ctx.ClientIPKey, c.ClientIP())
@@ -180,7 +185,10 @@ func RequestContext() gin.HandlerFunc {
 func GetRequestContext(c *gin.Context) ctx.CTX {
 	requestCtx, exists := c.Get("requestCtx")
 	if !exists {
-		// Create a new context if not found
+		// Fall back to the request's own context if the middleware did not run
+		if c.Request != nil {
+			return ctx.FromContext(c.Request.Context())
+		}
 		return ctx.Background()
 	}
 
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	"github.com/jungtechou/valomap/service/cache"
+)
+
+const cacheUsage = `Usage: valomap cache <command> [flags]
+
+Works on the directory configured as cache.directory.
+
+Commands:
+  stats    count the cached images and their size
+  purge    delete every cached image; they are downloaded again on demand
+  verify   list cached images that cannot be decoded
+
+Flags:
+  --json     print JSON (stats, verify)
+  --remove   delete the images that fail verification (verify)
+`
+
+// cacheCommand inspects or cleans the configured image cache directory
+func cacheCommand(args []string) int {
+	cfg, err := config.Load()
+	if err != nil {
+		return exitCode(err, cacheUsage)
+	}
+	return exitCode(runCache(cfg.Cache, args, os.Stdout), cacheUsage)
+}
+
+// runCache runs a cache command against the image cache described by settings
+func runCache(settings config.CacheConfig, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing cache command", errUsage)
+	}
+	command := args[0]
+
+	flags := newFlagSet("cache " + command)
+	asJSON := flags.Bool("json", false, "print JSON")
+	remove := flags.Bool("remove", false, "delete images that fail verification")
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+
+	dir := settings.Directory
+	if dir == "" {
+		dir = cache.DefaultPath
+	}
+
+	switch command {
+	case "stats":
+		stats, err := cache.Stats(dir)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(out, stats)
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Directory:\t%s\n", stats.Dir)
+		fmt.Fprintf(w, "Images:\t%d\n", stats.Files)
+		if settings.MaxSize > 0 {
+			fmt.Fprintf(w, "Size:\t%s of %s\n", formatBytes(stats.Bytes), formatBytes(settings.MaxSize))
+		} else {
+			fmt.Fprintf(w, "Size:\t%s\n", formatBytes(stats.Bytes))
+		}
+		if stats.Files > 0 {
+			fmt.Fprintf(w, "Oldest:\t%s\n", stats.Oldest.UTC().Format(time.RFC3339))
+			fmt.Fprintf(w, "Newest:\t%s\n", stats.Newest.UTC().Format(time.RFC3339))
+		}
+		return w.Flush()
+
+	case "purge":
+		removed, err := cache.Purge(dir)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Removed %d cached images from %s\n", removed, dir)
+		return nil
+
+	case "verify":
+		corrupt, err := cache.Verify(dir, *remove)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			if corrupt == nil {
+				corrupt = []cache.CorruptImage{}
+			}
+			return writeJSON(out, corrupt)
+		}
+
+		if len(corrupt) == 0 {
+			fmt.Fprintln(out, "All cached images are valid")
+			return nil
+		}
+		for _, image := range corrupt {
+			fmt.Fprintf(out, "%s: %s\n", image.Name, image.Reason)
+		}
+		if *remove {
+			fmt.Fprintf(out, "Removed %d corrupt images\n", len(corrupt))
+			return nil
+		}
+		return fmt.Errorf("%d corrupt images; run with --remove to delete them", len(corrupt))
+
+	default:
+		return fmt.Errorf("%w: unknown cache command %q", errUsage, command)
+	}
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// writeJSON prints v as indented JSON
+func writeJSON(out io.Writer, v any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
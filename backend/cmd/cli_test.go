@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	roulettehandler "github.com/jungtechou/valomap/api/handler/roulette"
+	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/roulette"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMaps = []domain.Map{
+	{UUID: "map-1", DisplayName: "Ascent"},
+	{UUID: "map-2", DisplayName: "Bind"},
+}
+
+// fakeRoulette records the filter of the last draw and returns testMaps
+type fakeRoulette struct {
+	roulette.Service
+	filter roulette.MapFilter
+	count  int
+	seed   *int64
+}
+
+func (f *fakeRoulette) GetAllMaps(ctx.CTX) ([]domain.Map, error) {
+	return testMaps, nil
+}
+
+func (f *fakeRoulette) GetPool(_ ctx.CTX, name string) (*domain.MapPool, error) {
+	return &domain.MapPool{Name: name, Maps: testMaps[:1]}, nil
+}
+
+func (f *fakeRoulette) DrawMap(_ ctx.CTX, filter roulette.MapFilter, seed *int64) (*roulette.DrawResult, error) {
+	f.filter, f.count, f.seed = filter, 1, seed
+	return &roulette.DrawResult{Map: testMaps[1], Seed: 42, Candidates: []string{"map-1", "map-2"}}, nil
+}
+
+func (f *fakeRoulette) DrawSeries(_ ctx.CTX, filter roulette.MapFilter, count int, seed *int64) (*roulette.SeriesResult, error) {
+	f.filter, f.count, f.seed = filter, count, seed
+	return &roulette.SeriesResult{Maps: testMaps, Seed: 42, Candidates: []string{"map-1", "map-2"}}, nil
+}
+
+func TestRun(t *testing.T) {
+	assert.Equal(t, 0, run([]string{"help"}))
+	assert.Equal(t, 2, run([]string{"sideways"}))
+	assert.Equal(t, 2, run([]string{"serve", "extra"}))
+	assert.Equal(t, 2, run([]string{"cache"}))
+	assert.Equal(t, 0, run([]string{"cache", "stats", "--help"}))
+}
+
+func TestRunMaps(t *testing.T) {
+	service := &fakeRoulette{}
+	mapsOutput := func(args ...string) string {
+		var out bytes.Buffer
+		require.NoError(t, runMaps(service, true, args, &out))
+		return out.String()
+	}
+
+	assert.Regexp(t, `NAME +UUID\nAscent +map-1\nBind +map-2\n`, mapsOutput("list"))
+	assert.NotContains(t, mapsOutput("list", "--pool", "premier"), "Bind")
+
+	output := mapsOutput("roll", "--standard", "--banned", "map-1,map-3", "--banned", "map-4",
+		"--weight", "map-2=3", "--seed", "7", "--pool", "competitive", "--session", "scrims")
+	assert.Contains(t, output, "Bind")
+	assert.Contains(t, output, "Seed: 42")
+	assert.Equal(t, roulette.MapFilter{
+		StandardOnly: true,
+		BannedMapIDs: []string{"map-1", "map-3", "map-4"},
+		Weights:      map[string]float64{"map-2": 3},
+		Pool:         "competitive",
+		SessionID:    "scrims",
+	}, service.filter)
+	require.NotNil(t, service.seed)
+	assert.Equal(t, int64(7), *service.seed)
+
+	var series roulettehandler.SeriesResponse
+	require.NoError(t, json.Unmarshal([]byte(mapsOutput("roll", "--count", "2", "--json")), &series))
+	assert.Equal(t, 2, service.count)
+	assert.Nil(t, service.seed)
+	assert.Equal(t, int64(42), series.Seed)
+	assert.Len(t, series.Maps, 2)
+
+	var out bytes.Buffer
+	for _, args := range [][]string{
+		nil,
+		{"shuffle"},
+		{"list", "--pool", "casual"},
+		{"roll", "--seed", "abc"},
+		{"roll", "--count", "0"},
+		{"roll", "--weight", "map-1"},
+	} {
+		assert.ErrorIs(t, runMaps(service, true, args, &out), errUsage, "%v", args)
+	}
+
+	// A session cannot outlive the command without Redis
+	service.filter = roulette.MapFilter{}
+	assert.ErrorIs(t, runMaps(service, false, []string{"roll", "--session", "scrims"}, &out), errUsage)
+	assert.Empty(t, service.filter.SessionID)
+}
+
+func TestRunCache(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "valid.png"), []byte("\x89PNG\r\n\x1a\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.jpg"), []byte("not an image"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "maps-snapshot.json"), []byte("{}"), 0o644))
+
+	settings := config.CacheConfig{Directory: dir, MaxSize: 2048}
+
+	var out bytes.Buffer
+	require.NoError(t, runCache(settings, []string{"stats"}, &out))
+	assert.Regexp(t, `Images: +2`, out.String())
+	assert.Regexp(t, `Size: +20 B of 2\.0 KiB`, out.String())
+
+	out.Reset()
+	err := runCache(settings, []string{"verify"}, &out)
+	assert.Error(t, err)
+	assert.Contains(t, out.String(), "broken.jpg")
+
+	out.Reset()
+	require.NoError(t, runCache(settings, []string{"verify", "--remove", "--json"}, &out))
+	assert.Contains(t, out.String(), `"broken.jpg"`)
+	assert.NoFileExists(t, filepath.Join(dir, "broken.jpg"))
+
+	out.Reset()
+	require.NoError(t, runCache(settings, []string{"purge"}, &out))
+	assert.NoFileExists(t, filepath.Join(dir, "valid.png"))
+	assert.FileExists(t, filepath.Join(dir, "maps-snapshot.json"))
+
+	assert.ErrorIs(t, runCache(settings, nil, &out), errUsage)
+	assert.ErrorIs(t, runCache(settings, []string{"shrink"}, &out), errUsage)
+}
+
+func TestRunConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.Port = "3000"
+	cfg.Redis.Password = "hunter2"
+	cfg.Database.DSN = "postgres://valomap:secret@db/valomap"
+
+	var out bytes.Buffer
+	require.NoError(t, runConfig(cfg, []string{"print"}, &out))
+	assert.Contains(t, out.String(), "port: \"3000\"")
+	assert.Contains(t, out.String(), "REDACTED")
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.NotContains(t, out.String(), "secret")
+	assert.Equal(t, "hunter2", cfg.Redis.Password)
+
+	assert.ErrorIs(t, runConfig(cfg, nil, &out), errUsage)
+	assert.ErrorIs(t, runConfig(cfg, []string{"print", "extra"}, &out), errUsage)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jungtechou/valomap/config"
+	"gopkg.in/yaml.v3"
+)
+
+const configUsage = `Usage: valomap config print
+
+Prints the effective configuration, after defaults, config file and environment
+variables are applied, as YAML that can be used as a config file. Passwords are
+replaced with REDACTED.
+`
+
+// configCommand prints the effective configuration
+func configCommand(args []string) int {
+	cfg, err := config.Load()
+	if err != nil {
+		return exitCode(err, configUsage)
+	}
+	return exitCode(runConfig(cfg, args, os.Stdout), configUsage)
+}
+
+// runConfig runs a config command
+func runConfig(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing config command", errUsage)
+	}
+	if args[0] != "print" {
+		return fmt.Errorf("%w: unknown config command %q", errUsage, args[0])
+	}
+	if err := parseFlags(newFlagSet("config print"), args[1:]); err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg.Redacted()); err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	return encoder.Close()
+}
@@ -1,9 +1,36 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/jungtechou/valomap/di/app"
 )
 
+// command is a valomap subcommand. run receives the arguments after the command
+// name and returns the process exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands are listed in this order by the usage message
+var commands = []command{
+	{"serve", "start the HTTP server (the default)", serve},
+	{"prewarm", "download every map image into the cache and exit", prewarm},
+	{"cache", "inspect or clean the image cache: stats, purge, verify", cacheCommand},
+	{"maps", "list maps or roll the roulette locally: list, roll", maps},
+	{"config", "print the effective configuration with secrets redacted", configCommand},
+	{"migrate", "manage database migrations: up, down, status", migrate},
+}
+
+// errUsage reports a missing or unknown subcommand or argument
+var errUsage = errors.New("invalid usage")
+
 // @title Valomap API
 // @version 1.0
 // @description Valorant Map Picker API - A service for randomly selecting maps for Valorant games
@@ -16,5 +43,90 @@ import (
 // @BasePath /api/v1
 // @schemes http https
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run dispatches to a subcommand; without one the server is started
+func run(args []string) int {
+	if len(args) == 0 {
+		return serve(nil)
+	}
+
+	switch args[0] {
+	case "help", "-h", "--help":
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+	printUsage(os.Stderr)
+	return 2
+}
+
+// printUsage lists the subcommands
+func printUsage(out io.Writer) {
+	fmt.Fprintln(out, "Usage: valomap [command] [arguments]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-9s%s\n", cmd.name, cmd.summary)
+	}
+}
+
+// serve runs the HTTP server until it is stopped
+func serve(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: valomap serve")
+		return 2
+	}
 	app.Run()
+	return 0
+}
+
+// exitCode reports the error of a subcommand and maps it to an exit code,
+// printing usage for invalid arguments and help requests
+func exitCode(err error, usage string) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		fmt.Fprint(os.Stdout, usage)
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	default:
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+}
+
+// newFlagSet returns a flag set for a subcommand that returns errors instead of
+// printing them, so that exitCode reports them with the command's usage
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("valomap "+name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// parseFlags parses the flags of a subcommand, turning parse errors into usage errors
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, flags.Arg(0))
+	}
+	return nil
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	roulettehandler "github.com/jungtechou/valomap/api/handler/roulette"
+	"github.com/jungtechou/valomap/config"
+	diservice "github.com/jungtechou/valomap/di/service"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/rotation"
+	"github.com/jungtechou/valomap/service/roulette"
+)
+
+const mapsUsage = `Usage: valomap maps <command> [flags]
+
+Commands:
+  list   list every map, or the active rotation of --pool
+  roll   draw maps locally, with the same filters as GET /api/v1/map/roulette
+
+Flags:
+  --pool NAME       competitive, premier or all
+  --standard        only standard maps (roll)
+  --banned UUID     exclude a map; repeatable or comma-separated (roll)
+  --weight UUID=N   selection weight of a map; repeatable (roll)
+  --seed N          reproduce an earlier draw (roll)
+  --count N         draw a series of N distinct maps (roll)
+  --session ID      avoid the session's recent picks and add this one (roll);
+                    needs Redis, since a session cannot outlive the command otherwise
+  --json            print the API's JSON response
+
+Rolls are not recorded in the draw history or its statistics.
+`
+
+// listFlag collects a repeatable, comma-separated flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// weightFlag collects repeatable UUID=N selection weights
+type weightFlag map[string]float64
+
+func (w weightFlag) String() string {
+	return fmt.Sprint(map[string]float64(w))
+}
+
+func (w weightFlag) Set(value string) error {
+	uuid, raw, ok := strings.Cut(value, "=")
+	if !ok || uuid == "" {
+		return fmt.Errorf("weight %q must look like UUID=N", value)
+	}
+	weight, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fmt.Errorf("weight for map %s must be a number", uuid)
+	}
+	w[uuid] = weight
+	return nil
+}
+
+// maps lists maps or rolls the roulette with the services the server would use,
+// except for the draw history, which only records draws served by the API, and
+// the image cache, as no command here needs images
+func maps(args []string) int {
+	cfg, err := config.Load()
+	if err != nil {
+		return exitCode(err, mapsUsage)
+	}
+
+	client := diservice.ProvideHTTPClient(diservice.ProvideTransport(cfg))
+	rot, err := diservice.ProvideRotation(cfg, diservice.ProvideSeasons())
+	if err != nil {
+		return exitCode(err, mapsUsage)
+	}
+	sessions, closeSessions := diservice.ProvideSessionStore(cfg)
+	defer closeSessions()
+
+	source := diservice.ProvideMapSource(cfg, client, diservice.ProvideMapPool())
+	service, cleanup := roulette.NewService(source, nil, sessions, nil, rot, cfg)
+	defer cleanup()
+
+	return exitCode(runMaps(service, cfg.Redis.Enabled, args, os.Stdout), mapsUsage)
+}
+
+// runMaps runs a maps command against the roulette service. Sessions are only
+// accepted when their history is kept outside the process.
+func runMaps(service roulette.Service, sessions bool, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing maps command", errUsage)
+	}
+	command := args[0]
+	if command != "list" && command != "roll" {
+		return fmt.Errorf("%w: unknown maps command %q", errUsage, command)
+	}
+
+	var banned listFlag
+	weights := weightFlag{}
+	flags := newFlagSet("maps " + command)
+	pool := flags.String("pool", "", "competitive, premier or all")
+	asJSON := flags.Bool("json", false, "print JSON")
+	standard := flags.Bool("standard", false, "only standard maps")
+	seed := flags.String("seed", "", "seed of an earlier draw")
+	count := flags.Int("count", 1, "number of distinct maps")
+	session := flags.String("session", "", "session whose recent picks are avoided")
+	flags.Var(&banned, "banned", "map UUIDs to exclude")
+	flags.Var(weights, "weight", "UUID=N selection weights")
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+
+	if *pool != "" && !rotation.IsPool(*pool) {
+		return fmt.Errorf("%w: unknown pool %q", errUsage, *pool)
+	}
+	if *session != "" && !sessions {
+		return fmt.Errorf("%w: --session needs Redis to be enabled", errUsage)
+	}
+	c := ctx.Background()
+
+	if command == "list" {
+		var list []domain.Map
+		if *pool != "" && *pool != domain.PoolAll {
+			mapPool, err := service.GetPool(c, *pool)
+			if err != nil {
+				return err
+			}
+			list = mapPool.Maps
+		} else {
+			all, err := service.GetAllMaps(c)
+			if err != nil {
+				return err
+			}
+			list = all
+		}
+
+		if *asJSON {
+			return writeJSON(out, list)
+		}
+		return printMaps(out, list)
+	}
+
+	filter := roulette.MapFilter{
+		StandardOnly: *standard,
+		BannedMapIDs: banned,
+		Pool:         *pool,
+		SessionID:    *session,
+	}
+	if len(weights) > 0 {
+		filter.Weights = weights
+	}
+
+	var seedValue *int64
+	if *seed != "" {
+		parsed, err := strconv.ParseInt(*seed, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: seed must be a 64-bit integer", errUsage)
+		}
+		seedValue = &parsed
+	}
+
+	if *count < 1 {
+		return fmt.Errorf("%w: count must be at least 1", errUsage)
+	}
+	if *count > 1 {
+		result, err := service.DrawSeries(c, filter, *count, seedValue)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(out, roulettehandler.SeriesResponse{Maps: result.Maps, Seed: result.Seed, Candidates: result.Candidates})
+		}
+		if err := printMaps(out, result.Maps); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Seed: %d\n", result.Seed)
+		return nil
+	}
+
+	result, err := service.DrawMap(c, filter, seedValue)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(out, roulettehandler.RouletteResponse{Map: result.Map, Seed: result.Seed, Candidates: result.Candidates})
+	}
+	if err := printMaps(out, []domain.Map{result.Map}); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Seed: %d\n", result.Seed)
+	return nil
+}
+
+// printMaps writes a table of map names and UUIDs
+func printMaps(out io.Writer, list []domain.Map) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tUUID")
+	for _, m := range list {
+		fmt.Fprintf(w, "%s\t%s\n", m.DisplayName, m.UUID)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	"github.com/jungtechou/valomap/pkg/database"
+	"github.com/jungtechou/valomap/service/history"
+)
+
+const migrateUsage = `Usage: valomap migrate <command> [flags]
+
+Commands:
+  up       apply pending migrations
+  down     revert the latest migration, or every migration after --to
+  status   list migrations and whether they are applied
+
+Flags:
+  --dry-run  print the SQL instead of running it (up, down)
+  --to N     version to roll back to (down)
+`
+
+// migrations are every schema change of the database, in version order
+var migrations = history.Migrations
+
+// migrate runs the migrate subcommand and returns the process exit code
+func migrate(args []string) int {
+	cfg, err := config.Load()
+	if err != nil {
+		return exitCode(err, migrateUsage)
+	}
+
+	return exitCode(runMigrate(cfg, args, os.Stdout), migrateUsage)
+}
+
+// runMigrate runs a migrate command against the configured database, which
+// need not be enabled for the server
+func runMigrate(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing migrate command", errUsage)
+	}
+	command := args[0]
+	if command != "up" && command != "down" && command != "status" {
+		return fmt.Errorf("%w: unknown migrate command %q", errUsage, command)
+	}
+
+	flags := newFlagSet("migrate " + command)
+	dryRun := flags.Bool("dry-run", false, "print the SQL instead of running it")
+	to := flags.Int64("to", -1, "version to roll back to")
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+
+	db, err := database.Open(cfg.Database.Driver, cfg.Database.DSN, database.Options{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dialect, err := database.DialectFor(cfg.Database.Driver)
+	if err != nil {
+		return err
+	}
+	manager := database.NewMigrationManager(db, dialect)
+	manager.SetLockTimeout(cfg.Database.MigrationLockTimeout)
+	if *dryRun {
+		manager.SetDryRun(out)
+	}
+
+	switch command {
+	case "up":
+		return manager.ApplyMigrations(migrations)
+	case "down":
+		target := *to
+		if target < 0 {
+			if target, err = previousVersion(manager); err != nil {
+				return err
+			}
+		}
+		return manager.RollbackTo(migrations, target)
+	default:
+		return printStatus(manager, out)
+	}
+}
+
+// previousVersion returns the version below the latest applied migration, so
+// that rolling back to it reverts one step
+func previousVersion(manager *database.MigrationManager) (int64, error) {
+	statuses, err := manager.Status(migrations)
+	if err != nil {
+		return 0, err
+	}
+
+	var latest, previous int64
+	for _, status := range statuses {
+		if status.Applied {
+			latest, previous = status.ID, latest
+		}
+	}
+	if latest == 0 {
+		return 0, nil
+	}
+	return previous, nil
+}
+
+// printStatus writes a table of the migrations and their state
+func printStatus(manager *database.MigrationManager, out io.Writer) error {
+	statuses, err := manager.Status(migrations)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT\tDOWN")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		if status.Modified {
+			state = "modified"
+		}
+		if status.Unknown {
+			state = "unknown"
+		}
+
+		down := "no"
+		if status.Reversible {
+			down = "yes"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\t%s\n", status.ID, status.Name, state, appliedAt, down)
+	}
+	return w.Flush()
+}
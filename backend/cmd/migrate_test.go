@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jungtechou/valomap/config"
+	"github.com/jungtechou/valomap/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMigrate(t *testing.T) {
+	cfg := &config.Config{Database: config.DatabaseConfig{
+		Driver: database.DriverSQLite,
+		DSN:    filepath.Join(t.TempDir(), "valomap.db"),
+	}}
+	run := func(args ...string) string {
+		var out bytes.Buffer
+		require.NoError(t, runMigrate(cfg, args, &out))
+		return out.String()
+	}
+	states := func() []string {
+		var out []string
+		for _, line := range strings.Split(strings.TrimSpace(run("status")), "\n")[1:] {
+			out = append(out, strings.Fields(line)[2])
+		}
+		return out
+	}
+
+	// A dry run prints the pending SQL only
+	assert.Contains(t, run("up", "--dry-run"), "CREATE TABLE IF NOT EXISTS draw_history")
+	assert.Equal(t, []string{"pending", "pending"}, states())
+
+	run("up")
+	assert.Equal(t, []string{"applied", "applied"}, states())
+	assert.Regexp(t, `0001 +create_draw_history +applied`, run("status"))
+
+	// Down reverts one version at a time unless given a target
+	assert.Contains(t, run("down", "--dry-run"), "DROP COLUMN candidates")
+	run("down")
+	assert.Equal(t, []string{"applied", "pending"}, states())
+	run("up")
+	run("down", "--to", "0")
+	assert.Equal(t, []string{"pending", "pending"}, states())
+	run("down")
+}
+
+func TestRunMigrateUsage(t *testing.T) {
+	cfg := &config.Config{Database: config.DatabaseConfig{Driver: "oracle"}}
+	var out bytes.Buffer
+
+	assert.ErrorIs(t, runMigrate(cfg, nil, &out), errUsage)
+	assert.ErrorIs(t, runMigrate(cfg, []string{"sideways"}, &out), errUsage)
+	assert.Error(t, runMigrate(cfg, []string{"up", "--bogus"}, &out))
+	assert.ErrorIs(t, runMigrate(cfg, []string{"up"}, &out), database.ErrUnsupportedDriver)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jungtechou/valomap/config"
+	diservice "github.com/jungtechou/valomap/di/service"
+	"github.com/jungtechou/valomap/service/cache"
+)
+
+const prewarmUsage = `Usage: valomap prewarm
+
+Downloads the splash and icon of every map from the configured map API into the
+image cache, then exits. Images already cached are skipped.
+`
+
+// prewarm runs the map image prewarmer once
+func prewarm(args []string) int {
+	if err := parseFlags(newFlagSet("prewarm"), args); err != nil {
+		return exitCode(err, prewarmUsage)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return exitCode(err, prewarmUsage)
+	}
+
+	client := diservice.ProvideHTTPClient(diservice.ProvideTransport(cfg))
+	imageCache, err := diservice.ProvideImageCache(cfg, client)
+	if err != nil {
+		return exitCode(err, prewarmUsage)
+	}
+	defer imageCache.Shutdown()
+
+	if err := cache.NewMapPrewarmer(imageCache, client, cfg.API.MapAPIURL).PrewarmMapImages(); err != nil {
+		return exitCode(err, prewarmUsage)
+	}
+
+	fmt.Fprintln(os.Stdout, "Map images cached")
+	return 0
+}
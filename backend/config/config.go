@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -10,51 +12,144 @@ import (
 	"github.com/spf13/viper"
 )
 
+// ErrInvalidConfig is returned by Load for settings the server cannot run with
+var ErrInvalidConfig = errors.New("invalid configuration")
+
 // Config holds all configuration for the server
 type Config struct {
-	Server   ServerConfig
-	Logging  LoggingConfig
-	API      APIConfig
-	Redis    RedisConfig
-	Security SecurityConfig
+	Server   ServerConfig   `yaml:"server"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	API      APIConfig      `yaml:"api"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Security SecurityConfig `yaml:"security"`
+	Catalog  CatalogConfig  `yaml:"catalog"`
+	Session  SessionConfig  `yaml:"session"`
+	Rotation RotationConfig `yaml:"rotation"`
+	Database DatabaseConfig `yaml:"database"`
+	Cache    CacheConfig    `yaml:"cache"`
+	Upstream UpstreamConfig `yaml:"upstream"`
 }
 
 // ServerConfig holds all server-related configuration
 type ServerConfig struct {
-	Port            string
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	ShutdownTimeout time.Duration
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
 }
 
 // LoggingConfig holds all logging-related configuration
 type LoggingConfig struct {
-	Level        string
-	Format       string
-	ReportCaller bool
+	Level        string `yaml:"level"`
+	Format       string `yaml:"format"`
+	ReportCaller bool   `yaml:"report_caller"`
 }
 
 // APIConfig holds all API-related configuration
 type APIConfig struct {
-	BasePath       string
-	Version        string
-	MapAPIURL      string
-	RequestTimeout time.Duration
+	BasePath       string        `yaml:"base_path"`
+	Version        string        `yaml:"version"`
+	MapAPIURL      string        `yaml:"map_api_url"`
+	RequestTimeout time.Duration `yaml:"request_timeout"`
 }
 
 // RedisConfig holds all Redis-related configuration
 type RedisConfig struct {
-	Enabled  bool
-	Address  string
-	Password string
-	DB       int
+	Enabled  bool   `yaml:"enabled"`
+	Address  string `yaml:"address"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+// DatabaseConfig holds all database-related configuration.
+// Driver is sqlite for local development or postgres in production.
+type DatabaseConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	Driver          string        `yaml:"driver"`
+	DSN             string        `yaml:"dsn"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// MigrationLockTimeout bounds how long startup waits while another instance migrates
+	MigrationLockTimeout time.Duration `yaml:"migration_lock_timeout"`
+}
+
+// CacheConfig holds all map image cache-related configuration
+type CacheConfig struct {
+	Directory string `yaml:"directory"`
+	// MaxSize is the byte budget of the cached images; 0 means unlimited
+	MaxSize int64 `yaml:"max_size"`
+	// MaxEntries limits the number of cached images; 0 means unlimited
+	MaxEntries int `yaml:"max_entries"`
+	// Workers is the number of concurrent background downloads
+	Workers int `yaml:"workers"`
+	// QueueSize is how many background downloads may wait for a worker
+	QueueSize      int           `yaml:"queue_size"`
+	PrewarmTimeout time.Duration `yaml:"prewarm_timeout"`
+	// RevalidateInterval is how often cached images are checked against upstream; 0 disables it
+	RevalidateInterval time.Duration `yaml:"revalidate_interval"`
+	// VariantWidths are the sizes images can be resized to, also offered in the
+	// srcset of map images; empty disables resizing
+	VariantWidths []int `yaml:"variant_widths"`
+	// VariantHeights are the heights images can be resized to, alone or together
+	// with a width; empty allows resizing by width only
+	VariantHeights []int `yaml:"variant_heights"`
+}
+
+// UpstreamConfig holds the retry and circuit breaker settings of calls to upstream APIs
+type UpstreamConfig struct {
+	// MaxRetries is how many times a failed request is retried; 0 disables retries
+	MaxRetries     int           `yaml:"max_retries"`
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `yaml:"retry_max_delay"`
+	// MaxRetryAfter caps how long a rate-limited request waits for Retry-After
+	MaxRetryAfter time.Duration `yaml:"max_retry_after"`
+	// AttemptTimeout bounds the wait for the response headers of each attempt
+	AttemptTimeout time.Duration `yaml:"attempt_timeout"`
+	// BreakerThreshold is how many failed requests in a row open a host's circuit
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+}
+
+// CatalogConfig holds all map catalog-related configuration
+type CatalogConfig struct {
+	TTL             time.Duration `yaml:"ttl"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	SnapshotPath    string        `yaml:"snapshot_path"`
+	// FetchTimeout bounds each load of the map list and its images; 0 means no limit
+	FetchTimeout time.Duration `yaml:"fetch_timeout"`
+	// StartupTimeout is how long startup waits for the first load before going on
+	// without it; 0 does not wait
+	StartupTimeout time.Duration `yaml:"startup_timeout"`
+}
+
+// SessionConfig holds all roulette session history-related configuration
+type SessionConfig struct {
+	HistorySize  int           `yaml:"history_size"`
+	TTL          time.Duration `yaml:"ttl"`
+	Mode         string        `yaml:"mode"`
+	RecentWeight float64       `yaml:"recent_weight"`
+}
+
+// RotationConfig holds all competitive map rotation-related configuration.
+// Inline seasons take precedence over the rotation file.
+type RotationConfig struct {
+	Path    string         `yaml:"path"`
+	Seasons []SeasonConfig `yaml:"seasons"`
+}
+
+// SeasonConfig describes one dated map rotation, as read from the config or a rotation file
+type SeasonConfig struct {
+	Name      string   `mapstructure:"name" yaml:"name"`
+	Pool      string   `mapstructure:"pool" yaml:"pool"`
+	Effective string   `mapstructure:"effective" yaml:"effective"`
+	Maps      []string `mapstructure:"maps" yaml:"maps"`
 }
 
 // SecurityConfig holds all security-related configuration
 type SecurityConfig struct {
-	AllowedOrigins []string
-	AllowedMethods []string
-	MaxBodySize    int64
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods"`
+	MaxBodySize    int64    `yaml:"max_body_size"`
 }
 
 // Load loads the configuration from environment variables, files, and defaults
@@ -113,6 +208,58 @@ func Load() (*Config, error) {
 			AllowedMethods: v.GetStringSlice("security.allowed_methods"),
 			MaxBodySize:    v.GetInt64("security.max_body_size"),
 		},
+		Catalog: CatalogConfig{
+			TTL:             v.GetDuration("catalog.ttl"),
+			RefreshInterval: v.GetDuration("catalog.refresh_interval"),
+			SnapshotPath:    v.GetString("catalog.snapshot_path"),
+			FetchTimeout:    v.GetDuration("catalog.fetch_timeout"),
+			StartupTimeout:  v.GetDuration("catalog.startup_timeout"),
+		},
+		Session: SessionConfig{
+			HistorySize:  v.GetInt("session.history_size"),
+			TTL:          v.GetDuration("session.ttl"),
+			Mode:         v.GetString("session.mode"),
+			RecentWeight: v.GetFloat64("session.recent_weight"),
+		},
+		Rotation: RotationConfig{
+			Path: v.GetString("rotation.path"),
+		},
+		Database: DatabaseConfig{
+			Enabled:              v.GetBool("database.enabled"),
+			Driver:               v.GetString("database.driver"),
+			DSN:                  v.GetString("database.dsn"),
+			MaxOpenConns:         v.GetInt("database.max_open_conns"),
+			ConnMaxLifetime:      v.GetDuration("database.conn_max_lifetime"),
+			MigrationLockTimeout: v.GetDuration("database.migration_lock_timeout"),
+		},
+		Cache: CacheConfig{
+			Directory:          v.GetString("cache.directory"),
+			MaxSize:            v.GetInt64("cache.max_size"),
+			MaxEntries:         v.GetInt("cache.max_entries"),
+			Workers:            v.GetInt("cache.workers"),
+			QueueSize:          v.GetInt("cache.queue_size"),
+			PrewarmTimeout:     v.GetDuration("cache.prewarm_timeout"),
+			RevalidateInterval: v.GetDuration("cache.revalidate_interval"),
+			VariantWidths:      v.GetIntSlice("cache.variant_widths"),
+			VariantHeights:     v.GetIntSlice("cache.variant_heights"),
+		},
+		Upstream: UpstreamConfig{
+			MaxRetries:       v.GetInt("upstream.max_retries"),
+			RetryBaseDelay:   v.GetDuration("upstream.retry_base_delay"),
+			RetryMaxDelay:    v.GetDuration("upstream.retry_max_delay"),
+			MaxRetryAfter:    v.GetDuration("upstream.max_retry_after"),
+			AttemptTimeout:   v.GetDuration("upstream.attempt_timeout"),
+			BreakerThreshold: v.GetInt("upstream.breaker_threshold"),
+			BreakerCooldown:  v.GetDuration("upstream.breaker_cooldown"),
+		},
+	}
+
+	if err := v.UnmarshalKey("rotation.seasons", &config.Rotation.Seasons, viper.DecodeHook(timeToStringHook)); err != nil {
+		return nil, fmt.Errorf("failed to read rotation seasons: %w", err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
 	}
 
 	setupLogger(config.Logging)
@@ -120,6 +267,24 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// validate rejects settings that are out of range rather than letting them
+// surface as failed requests
+func (c *Config) validate() error {
+	switch c.Session.Mode {
+	case "", "exclude", "downweight":
+	default:
+		return fmt.Errorf("%w: session.mode must be exclude or downweight, got %q", ErrInvalidConfig, c.Session.Mode)
+	}
+	if !(c.Session.RecentWeight > 0 && c.Session.RecentWeight <= 1) {
+		return fmt.Errorf("%w: session.recent_weight must be greater than 0 and at most 1, got %v", ErrInvalidConfig, c.Session.RecentWeight)
+	}
+	// The migration lock holds one PostgreSQL connection while migrations use another
+	if c.Database.Driver == "postgres" && c.Database.MaxOpenConns == 1 {
+		return fmt.Errorf("%w: database.max_open_conns must be at least 2 for postgres", ErrInvalidConfig)
+	}
+	return nil
+}
+
 // setDefaults sets the default configuration values
 func setDefaults(v *viper.Viper) {
 	// Server defaults
@@ -149,6 +314,58 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("security.allowed_origins", []string{"*"})
 	v.SetDefault("security.allowed_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
 	v.SetDefault("security.max_body_size", 1024*1024*8) // 8MB
+
+	// Catalog defaults
+	v.SetDefault("catalog.ttl", 10*time.Minute)
+	v.SetDefault("catalog.refresh_interval", 5*time.Minute)
+	v.SetDefault("catalog.snapshot_path", "/home/appuser/images-cache/maps-snapshot.json")
+	v.SetDefault("catalog.fetch_timeout", time.Minute)
+	v.SetDefault("catalog.startup_timeout", 10*time.Second)
+
+	// Session defaults
+	v.SetDefault("session.history_size", 3)
+	v.SetDefault("session.ttl", 24*time.Hour)
+	v.SetDefault("session.mode", "exclude")
+	v.SetDefault("session.recent_weight", 0.25)
+
+	// Rotation defaults
+	v.SetDefault("rotation.path", "")
+
+	// Database defaults
+	v.SetDefault("database.enabled", false)
+	v.SetDefault("database.driver", "sqlite")
+	v.SetDefault("database.dsn", "/home/appuser/images-cache/valomap.db")
+	v.SetDefault("database.max_open_conns", 10)
+	v.SetDefault("database.conn_max_lifetime", 30*time.Minute)
+	v.SetDefault("database.migration_lock_timeout", time.Minute)
+
+	// Cache defaults
+	v.SetDefault("cache.directory", "/home/appuser/images-cache")
+	v.SetDefault("cache.max_size", 512*1024*1024) // 512MB
+	v.SetDefault("cache.max_entries", 0)
+	v.SetDefault("cache.workers", 3)
+	v.SetDefault("cache.queue_size", 10)
+	v.SetDefault("cache.prewarm_timeout", time.Minute)
+	v.SetDefault("cache.revalidate_interval", 24*time.Hour)
+	v.SetDefault("cache.variant_widths", []int{320, 640, 1280})
+	v.SetDefault("cache.variant_heights", []int{180, 360, 720})
+
+	// Upstream defaults
+	v.SetDefault("upstream.max_retries", 3)
+	v.SetDefault("upstream.retry_base_delay", 200*time.Millisecond)
+	v.SetDefault("upstream.retry_max_delay", 5*time.Second)
+	v.SetDefault("upstream.max_retry_after", 30*time.Second)
+	v.SetDefault("upstream.attempt_timeout", 4*time.Second)
+	v.SetDefault("upstream.breaker_threshold", 5)
+	v.SetDefault("upstream.breaker_cooldown", 30*time.Second)
+}
+
+// timeToStringHook keeps YAML dates as strings so season dates are parsed in one place
+func timeToStringHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if t, ok := data.(time.Time); ok && to.Kind() == reflect.String {
+		return t.Format(time.RFC3339), nil
+	}
+	return data, nil
 }
 
 // setupLogger configures the global logger based on configuration
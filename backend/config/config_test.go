@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -106,6 +107,41 @@ func TestSetDefaults(t *testing.T) {
 	assert.Equal(t, []string{"*"}, v.GetStringSlice("security.allowed_origins"))
 	assert.Contains(t, v.GetStringSlice("security.allowed_methods"), "GET")
 	assert.Equal(t, int64(1024*1024*8), v.GetInt64("security.max_body_size"))
+
+	assert.Equal(t, 10*time.Minute, v.GetDuration("catalog.ttl"))
+	assert.Equal(t, 5*time.Minute, v.GetDuration("catalog.refresh_interval"))
+	assert.Equal(t, "/home/appuser/images-cache/maps-snapshot.json", v.GetString("catalog.snapshot_path"))
+
+	assert.Equal(t, 3, v.GetInt("session.history_size"))
+	assert.Equal(t, 24*time.Hour, v.GetDuration("session.ttl"))
+	assert.Equal(t, "exclude", v.GetString("session.mode"))
+	assert.Equal(t, 0.25, v.GetFloat64("session.recent_weight"))
+
+	assert.Equal(t, "", v.GetString("rotation.path"))
+
+	assert.Equal(t, false, v.GetBool("database.enabled"))
+	assert.Equal(t, "sqlite", v.GetString("database.driver"))
+	assert.Equal(t, "/home/appuser/images-cache/valomap.db", v.GetString("database.dsn"))
+	assert.Equal(t, 10, v.GetInt("database.max_open_conns"))
+	assert.Equal(t, 30*time.Minute, v.GetDuration("database.conn_max_lifetime"))
+	assert.Equal(t, time.Minute, v.GetDuration("database.migration_lock_timeout"))
+
+	assert.Equal(t, "/home/appuser/images-cache", v.GetString("cache.directory"))
+	assert.Equal(t, int64(512*1024*1024), v.GetInt64("cache.max_size"))
+	assert.Equal(t, 0, v.GetInt("cache.max_entries"))
+	assert.Equal(t, 3, v.GetInt("cache.workers"))
+	assert.Equal(t, 10, v.GetInt("cache.queue_size"))
+	assert.Equal(t, time.Minute, v.GetDuration("cache.prewarm_timeout"))
+	assert.Equal(t, 24*time.Hour, v.GetDuration("cache.revalidate_interval"))
+	assert.Equal(t, []int{320, 640, 1280}, v.GetIntSlice("cache.variant_widths"))
+	assert.Equal(t, []int{180, 360, 720}, v.GetIntSlice("cache.variant_heights"))
+	assert.Equal(t, 3, v.GetInt("upstream.max_retries"))
+	assert.Equal(t, 200*time.Millisecond, v.GetDuration("upstream.retry_base_delay"))
+	assert.Equal(t, 5*time.Second, v.GetDuration("upstream.retry_max_delay"))
+	assert.Equal(t, 30*time.Second, v.GetDuration("upstream.max_retry_after"))
+	assert.Equal(t, 4*time.Second, v.GetDuration("upstream.attempt_timeout"))
+	assert.Equal(t, 5, v.GetInt("upstream.breaker_threshold"))
+	assert.Equal(t, 30*time.Second, v.GetDuration("upstream.breaker_cooldown"))
 }
 
 func TestSetupLogger(t *testing.T) {
@@ -159,6 +195,70 @@ func TestLoad(t *testing.T) {
 	assert.Equal(t, "info", config.Logging.Level)
 }
 
+func TestLoad_RotationSeasons(t *testing.T) {
+	// Load reads config.yaml from the working directory
+	dir := t.TempDir()
+	configYAML := `
+rotation:
+  seasons:
+    - name: Test Season
+      pool: competitive
+      effective: 2025-01-08
+      maps: [map1, map2]
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(configYAML), 0o644))
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	config, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []SeasonConfig{{
+		Name:      "Test Season",
+		Pool:      "competitive",
+		Effective: "2025-01-08T00:00:00Z",
+		Maps:      []string{"map1", "map2"},
+	}}, config.Rotation.Seasons)
+}
+
+func TestLoad_Validation(t *testing.T) {
+	// Load reads config.yaml from the working directory
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		name   string
+		config string
+		valid  bool
+	}{
+		{"unknown mode", "session:\n  mode: shuffle\n", false},
+		{"zero weight", "session:\n  recent_weight: 0\n", false},
+		{"negative weight", "session:\n  recent_weight: -0.5\n", false},
+		{"weight above one", "session:\n  recent_weight: 1.5\n", false},
+		{"downweight at full weight", "session:\n  mode: downweight\n  recent_weight: 1\n", true},
+		{"single postgres connection", "database:\n  driver: postgres\n  max_open_conns: 1\n", false},
+		{"unlimited postgres connections", "database:\n  driver: postgres\n  max_open_conns: 0\n", true},
+		{"single sqlite connection", "database:\n  driver: sqlite\n  max_open_conns: 1\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(tt.config), 0o644))
+
+			_, err := Load()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidConfig)
+			}
+		})
+	}
+}
+
 // Helper function to set or unset an environment variable
 func setEnvOrUnset(key, value string) {
 	if value == "" {
@@ -213,3 +313,31 @@ func splitKeyValue(env string) (string, string, bool) {
 	}
 	return env, "", false
 }
+
+func TestRedacted(t *testing.T) {
+	cfg := Config{
+		Redis:    RedisConfig{Address: "localhost:6379", Password: "hunter2"},
+		Database: DatabaseConfig{DSN: "postgres://valomap:hunter2@db:5432/valomap?sslmode=disable"},
+	}
+
+	redactedCfg := cfg.Redacted()
+	assert.Equal(t, "REDACTED", redactedCfg.Redis.Password)
+	assert.Equal(t, "postgres://valomap:REDACTED@db:5432/valomap?sslmode=disable", redactedCfg.Database.DSN)
+	assert.Equal(t, "localhost:6379", redactedCfg.Redis.Address)
+
+	// The original is left untouched
+	assert.Equal(t, "hunter2", cfg.Redis.Password)
+
+	for dsn, expected := range map[string]string{
+		"host=db user=valomap password=hunter2 dbname=valomap": "host=db user=valomap password=REDACTED dbname=valomap",
+		"host=db password='hunter 2' dbname=valomap":           "host=db password=REDACTED dbname=valomap",
+		"postgres://valomap@db/valomap":                        "postgres://valomap@db/valomap",
+		"/home/appuser/images-cache/valomap.db":                "/home/appuser/images-cache/valomap.db",
+		"":                                                     "",
+	} {
+		assert.Equal(t, expected, redactDSN(dsn), dsn)
+	}
+
+	// Empty secrets stay empty
+	assert.Empty(t, Config{}.Redacted().Redis.Password)
+}
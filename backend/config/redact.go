@@ -0,0 +1,34 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// redacted replaces secrets in printed configuration
+const redacted = "REDACTED"
+
+// dsnPassword matches the password of a key=value PostgreSQL DSN
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+
+// Redacted returns a copy of the configuration that is safe to print, with the
+// Redis password and any database password replaced
+func (c Config) Redacted() Config {
+	if c.Redis.Password != "" {
+		c.Redis.Password = redacted
+	}
+	c.Database.DSN = redactDSN(c.Database.DSN)
+	return c
+}
+
+// redactDSN hides the password of a URL or key=value DSN; file paths pass through
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			return u.String()
+		}
+		return dsn
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
@@ -5,14 +5,24 @@ import (
 	"github.com/jungtechou/valomap/api/handler"
 	"github.com/jungtechou/valomap/api/handler/cache"
 	"github.com/jungtechou/valomap/api/handler/health"
+	"github.com/jungtechou/valomap/api/handler/history"
+	"github.com/jungtechou/valomap/api/handler/room"
 	"github.com/jungtechou/valomap/api/handler/roulette"
+	"github.com/jungtechou/valomap/api/handler/stats"
+	"github.com/jungtechou/valomap/api/handler/veto"
+	"github.com/jungtechou/valomap/pkg/httpclient"
 	cachesvc "github.com/jungtechou/valomap/service/cache"
 )
 
 // HandlerSet contains all API handler providers
 var HandlerSet = wire.NewSet(
 	health.NewHandler,
+	wire.Bind(new(health.UpstreamMonitor), new(*httpclient.Transport)),
 	roulette.NewHandler,
+	veto.NewHandler,
+	room.NewHandler,
+	history.NewHandler,
+	stats.NewHandler,
 	wire.Struct(new(CacheHandlerParams), "*"),
 	ProvideCacheHandler,
 )
@@ -28,10 +38,14 @@ func ProvideCacheHandler(params CacheHandlerParams) cache.Handler {
 }
 
 // NewHandlers provides all API handlers
-func NewHandlers(health health.Handler, roulette roulette.Handler, cache cache.Handler) []handler.Handler {
+func NewHandlers(health health.Handler, roulette roulette.Handler, veto veto.Handler, room room.Handler, history history.Handler, stats stats.Handler, cache cache.Handler) []handler.Handler {
 	return []handler.Handler{
 		health,
 		roulette,
+		veto,
+		room,
+		history,
+		stats,
 		cache,
 	}
 }
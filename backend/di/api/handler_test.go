@@ -5,9 +5,14 @@ import (
 
 	"github.com/jungtechou/valomap/api/handler/cache"
 	"github.com/jungtechou/valomap/api/handler/health"
+	"github.com/jungtechou/valomap/api/handler/history"
+	"github.com/jungtechou/valomap/api/handler/room"
 	"github.com/jungtechou/valomap/api/handler/roulette"
+	"github.com/jungtechou/valomap/api/handler/stats"
+	"github.com/jungtechou/valomap/api/handler/veto"
 	domain "github.com/jungtechou/valomap/domain/map"
 	"github.com/jungtechou/valomap/pkg/ctx"
+	cachesvc "github.com/jungtechou/valomap/service/cache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -32,6 +37,32 @@ func (m *MockImageCache) CacheMapImages(ctx ctx.CTX, maps []domain.Map) ([]domai
 	return args.Get(0).([]domain.Map), args.Error(1)
 }
 
+func (m *MockImageCache) Resolve(name string) (*cachesvc.CachedFile, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*cachesvc.CachedFile), args.Error(1)
+}
+
+func (m *MockImageCache) Variant(name string, opts cachesvc.VariantOptions) (*cachesvc.CachedFile, error) {
+	args := m.Called(name, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*cachesvc.CachedFile), args.Error(1)
+}
+
+func (m *MockImageCache) Metrics() cachesvc.Metrics {
+	args := m.Called()
+	return args.Get(0).(cachesvc.Metrics)
+}
+
+func (m *MockImageCache) Images() []cachesvc.ImageInfo {
+	args := m.Called()
+	return args.Get(0).([]cachesvc.ImageInfo)
+}
+
 func (m *MockImageCache) Shutdown() {
 	m.Called()
 }
@@ -57,14 +88,22 @@ func TestNewHandlers(t *testing.T) {
 	// Create mock handlers
 	healthHandler := &health.HealthHandler{}
 	rouletteHandler := &roulette.RouletteHandler{}
+	vetoHandler := &veto.VetoHandler{}
+	roomHandler := &room.RoomHandler{}
+	historyHandler := &history.HistoryHandler{}
+	statsHandler := &stats.StatsHandler{}
 	cacheHandler := &cache.CacheHandler{}
 
 	// Call the function under test
-	handlers := NewHandlers(healthHandler, rouletteHandler, cacheHandler)
+	handlers := NewHandlers(healthHandler, rouletteHandler, vetoHandler, roomHandler, historyHandler, statsHandler, cacheHandler)
 
 	// Verify the handlers are returned correctly
-	assert.Len(t, handlers, 3, "Should return 3 handlers")
+	assert.Len(t, handlers, 7, "Should return 7 handlers")
 	assert.Contains(t, handlers, healthHandler, "Should contain health handler")
 	assert.Contains(t, handlers, rouletteHandler, "Should contain roulette handler")
+	assert.Contains(t, handlers, vetoHandler, "Should contain veto handler")
+	assert.Contains(t, handlers, roomHandler, "Should contain room handler")
+	assert.Contains(t, handlers, historyHandler, "Should contain history handler")
+	assert.Contains(t, handlers, statsHandler, "Should contain stats handler")
 	assert.Contains(t, handlers, cacheHandler, "Should contain cache handler")
 }
@@ -156,6 +156,11 @@ func runServer(ctx context.Context, log *logrus.Logger, injector *di.Injector) {
 		shutdownCtx, shutdownCancel := context.WithTimeout(ctx, shutdownTimeout)
 		defer shutdownCancel()
 
+		// End room streams first, otherwise the server waits on them until the timeout
+		if injector.Rooms != nil {
+			injector.Rooms.Close()
+		}
+
 		if err := injector.HttpEngine.GracefulShutdown(shutdownCtx); err != nil {
 			log.WithError(err).Error("Error during shutdown")
 		}
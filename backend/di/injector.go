@@ -6,6 +6,8 @@ import (
 	"github.com/jungtechou/valomap/api/engine/gin"
 	"github.com/jungtechou/valomap/config"
 	"github.com/jungtechou/valomap/service/cache"
+	"github.com/jungtechou/valomap/service/room"
+	"github.com/jungtechou/valomap/service/roulette"
 
 	"github.com/google/wire"
 )
@@ -18,6 +20,8 @@ type Injector struct {
 	Config     *config.Config
 	ImageCache cache.ImageCache
 	HTTPClient *http.Client
+	Rooms      room.Service
+	Roulette   roulette.Service
 }
 
 // ProvideConfig provides the application configuration
@@ -36,5 +36,7 @@ func TestInjectorStructFields(t *testing.T) {
 		_ = injector.Config
 		_ = injector.ImageCache
 		_ = injector.HTTPClient
+		_ = injector.Rooms
+		_ = injector.Roulette
 	})
 }
@@ -1,40 +1,127 @@
 package service
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/jungtechou/valomap/config"
 	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/database"
+	"github.com/jungtechou/valomap/pkg/httpclient"
 	"github.com/jungtechou/valomap/service/cache"
+	"github.com/jungtechou/valomap/service/history"
+	"github.com/jungtechou/valomap/service/room"
+	"github.com/jungtechou/valomap/service/rotation"
 	"github.com/jungtechou/valomap/service/roulette"
+	"github.com/jungtechou/valomap/service/session"
+	"github.com/jungtechou/valomap/service/source"
+	"github.com/jungtechou/valomap/service/stats"
+	"github.com/jungtechou/valomap/service/veto"
 
 	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
 )
 
-// ProvideMapPool creates and returns a MapPool with predefined maps
+// ProvideMapPool creates and returns a MapPool with predefined maps.
+// It is served as the last-resort map source when neither the API nor a snapshot is available.
 func ProvideMapPool() domain.MapPool {
 	return domain.MapPool{
 		Maps: []domain.Map{
-			{UUID: "7eaecc1b-4337-bbf6-6ab9-04b8f06b3319", DisplayName: "Ascent"},
-			{UUID: "d960549e-485c-e861-8d71-aa9d1aed12a2", DisplayName: "Split"},
-			{UUID: "b529448b-4d60-346e-e89e-00a4c527a405", DisplayName: "Fracture"},
-			{UUID: "2c9d57ec-4431-9c5e-2939-8f9ef6dd5cba", DisplayName: "Bind"},
-			{UUID: "2fb9a4fd-47b8-4e7d-a969-74b4046ebd53", DisplayName: "Breeze"},
-			{UUID: "e2ad5c54-4114-a870-9641-8ea21279579a", DisplayName: "Icebox"},
-			{UUID: "ee613ee9-28b7-4beb-9666-08db13bb2244", DisplayName: "Haven"},
-			{UUID: "fd267378-4d1d-484f-ff52-77821ed10dc2", DisplayName: "Pearl"},
-			{UUID: "92584fbe-486a-b1b2-28f9-d29d88a7c7ca", DisplayName: "Lotus"},
-			{UUID: "690b3152-4c7a-d5a2-0093-9796b54176ce", DisplayName: "Sunset"},
+			{UUID: "7eaecc1b-4337-bbf6-6ab9-04b8f06b3319", DisplayName: "Ascent", TacticalDescription: "A/B Sites"},
+			{UUID: "d960549e-485c-e861-8d71-aa9d1aed12a2", DisplayName: "Split", TacticalDescription: "A/B Sites"},
+			{UUID: "b529448b-4d60-346e-e89e-00a4c527a405", DisplayName: "Fracture", TacticalDescription: "A/B Sites"},
+			{UUID: "2c9d57ec-4431-9c5e-2939-8f9ef6dd5cba", DisplayName: "Bind", TacticalDescription: "A/B Sites"},
+			{UUID: "2fb9a4fd-47b8-4e7d-a969-74b4046ebd53", DisplayName: "Breeze", TacticalDescription: "A/B Sites"},
+			{UUID: "e2ad5c54-4114-a870-9641-8ea21279579a", DisplayName: "Icebox", TacticalDescription: "A/B Sites"},
+			{UUID: "ee613ee9-28b7-4beb-9666-08db13bb2244", DisplayName: "Haven", TacticalDescription: "A/B/C Sites"},
+			{UUID: "fd267378-4d1d-484f-ff52-77821ed10dc2", DisplayName: "Pearl", TacticalDescription: "A/B Sites"},
+			{UUID: "92584fbe-486a-b1b2-28f9-d29d88a7c7ca", DisplayName: "Lotus", TacticalDescription: "A/B/C Sites"},
+			{UUID: "690b3152-4c7a-d5a2-0093-9796b54176ce", DisplayName: "Sunset", TacticalDescription: "A/B Sites"},
 		},
 	}
 }
 
-// ProvideHTTPClient creates and returns an HTTP client with reasonable defaults
-func ProvideHTTPClient() *http.Client {
+// ProvideSeasons returns the built-in map rotations, used when neither the config nor a
+// rotation file defines seasons. Both queues share one seven-map rotation from the static pool.
+func ProvideSeasons() []domain.Season {
+	maps := []string{
+		"7eaecc1b-4337-bbf6-6ab9-04b8f06b3319", // Ascent
+		"2c9d57ec-4431-9c5e-2939-8f9ef6dd5cba", // Bind
+		"ee613ee9-28b7-4beb-9666-08db13bb2244", // Haven
+		"e2ad5c54-4114-a870-9641-8ea21279579a", // Icebox
+		"92584fbe-486a-b1b2-28f9-d29d88a7c7ca", // Lotus
+		"fd267378-4d1d-484f-ff52-77821ed10dc2", // Pearl
+		"d960549e-485c-e861-8d71-aa9d1aed12a2", // Split
+	}
+	return []domain.Season{
+		{Name: "Default", Pool: domain.PoolCompetitive, MapUUIDs: maps},
+		{Name: "Default", Pool: domain.PoolPremier, MapUUIDs: maps},
+	}
+}
+
+// ProvideRotation loads the competitive map rotation from the config, the rotation file or the built-in seasons
+func ProvideRotation(cfg *config.Config, seasons []domain.Season) (*rotation.Rotation, error) {
+	var rotationCfg config.RotationConfig
+	if cfg != nil {
+		rotationCfg = cfg.Rotation
+	}
+	return rotation.Load(rotationCfg, seasons)
+}
+
+// ProvideTransport creates the resilient transport shared by all upstream calls: it
+// retries transient failures and keeps a circuit breaker per upstream host
+func ProvideTransport(cfg *config.Config) *httpclient.Transport {
+	upstream := config.UpstreamConfig{MaxRetries: httpclient.DefaultMaxRetries}
+	if cfg != nil {
+		upstream = cfg.Upstream
+	}
+	return httpclient.NewTransport(nil, httpclient.Options{
+		MaxRetries:       upstream.MaxRetries,
+		BaseDelay:        upstream.RetryBaseDelay,
+		MaxDelay:         upstream.RetryMaxDelay,
+		MaxRetryAfter:    upstream.MaxRetryAfter,
+		AttemptTimeout:   upstream.AttemptTimeout,
+		BreakerThreshold: upstream.BreakerThreshold,
+		BreakerCooldown:  upstream.BreakerCooldown,
+	})
+}
+
+// ProvideHTTPClient creates the HTTP client used for the map API and image
+// downloads. The timeout bounds a whole request, retries included.
+func ProvideHTTPClient(transport *httpclient.Transport) *http.Client {
 	return &http.Client{
-		Timeout: 10 * time.Second,
+		Transport: transport,
+		Timeout:   10 * time.Second,
+	}
+}
+
+// ProvideMapSource creates the map source chain: the upstream API (recording a snapshot
+// on every success), then the on-disk snapshot, then the static map pool
+func ProvideMapSource(cfg *config.Config, client *http.Client, pool domain.MapPool) source.MapSource {
+	var (
+		url          string
+		timeout      time.Duration
+		snapshotPath string
+	)
+	if cfg != nil {
+		url = cfg.API.MapAPIURL
+		timeout = cfg.API.RequestTimeout
+		snapshotPath = cfg.Catalog.SnapshotPath
 	}
+
+	upstream := source.MapSource(source.NewHTTPSource(client, url, timeout))
+	sources := []source.MapSource{}
+	if snapshotPath != "" {
+		snapshot := source.NewFileSource(snapshotPath)
+		sources = append(sources, source.WithSnapshot(upstream, snapshot), snapshot)
+	} else {
+		sources = append(sources, upstream)
+	}
+	sources = append(sources, source.NewStaticSource(pool))
+
+	return source.NewChain(sources...)
 }
 
 // ProvideImageCache creates and returns an image cache service
@@ -42,9 +129,83 @@ func ProvideImageCache(cfg *config.Config, client *http.Client) (cache.ImageCach
 	return cache.NewImageCache(cfg, client)
 }
 
+// ProvideSessionStore creates the roulette session history store, backed by Redis when
+// it is enabled and by process memory otherwise
+func ProvideSessionStore(cfg *config.Config) (session.Store, func()) {
+	if cfg == nil {
+		return session.NewMemoryStore(session.DefaultHistorySize, 0), func() {}
+	}
+
+	if !cfg.Redis.Enabled {
+		return session.NewMemoryStore(cfg.Session.HistorySize, cfg.Session.TTL), func() {}
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Address,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	logrus.WithField("address", cfg.Redis.Address).Info("Using Redis for session history")
+
+	return session.NewRedisStore(client, cfg.Session.HistorySize, cfg.Session.TTL), func() {
+		if err := client.Close(); err != nil {
+			logrus.WithError(err).Warn("Failed to close Redis client")
+		}
+	}
+}
+
+// ProvideHistoryStore opens the draw history database and applies its migrations.
+// Draws are written in the background, and the cleanup waits for queued ones.
+// It returns a nil store when the database is disabled, which turns draw history off.
+func ProvideHistoryStore(cfg *config.Config) (history.Store, func(), error) {
+	if cfg == nil || !cfg.Database.Enabled {
+		return nil, func() {}, nil
+	}
+
+	db, err := database.Open(cfg.Database.Driver, cfg.Database.DSN, database.Options{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dialect, err := database.DialectFor(cfg.Database.Driver)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	migrator := database.NewMigrationManager(db, dialect)
+	migrator.SetLockTimeout(cfg.Database.MigrationLockTimeout)
+	if err := migrator.ApplyMigrations(history.Migrations); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to migrate draw history database: %w", err)
+	}
+	logrus.WithField("driver", cfg.Database.Driver).Info("Using database for draw history")
+
+	store := history.NewAsyncStore(history.NewSQLStore(db, cfg.Database.Driver), history.DefaultQueueSize)
+	return store, func() {
+		store.Close()
+		if err := db.Close(); err != nil {
+			logrus.WithError(err).Warn("Failed to close database")
+		}
+	}, nil
+}
+
 var ServiceSet = wire.NewSet(
 	roulette.NewService,
+	veto.NewService,
+	room.NewService,
+	history.NewService,
+	stats.NewService,
 	ProvideMapPool,
+	ProvideMapSource,
+	ProvideTransport,
 	ProvideHTTPClient,
 	ProvideImageCache,
+	ProvideSessionStore,
+	ProvideHistoryStore,
+	ProvideSeasons,
+	ProvideRotation,
 )
@@ -2,10 +2,17 @@ package service
 
 import (
 	"net/http"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/pkg/database"
+	"github.com/jungtechou/valomap/service/history"
+	"github.com/jungtechou/valomap/service/session"
+	"github.com/jungtechou/valomap/service/source"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,12 +35,123 @@ func TestProvideMapPool(t *testing.T) {
 	}
 }
 
+func TestProvideMapSource(t *testing.T) {
+	// Unreachable upstream without a snapshot falls back to the static pool
+	cfg := &config.Config{
+		API: config.APIConfig{
+			MapAPIURL:      "http://127.0.0.1:1/maps",
+			RequestTimeout: time.Second,
+		},
+		Catalog: config.CatalogConfig{
+			SnapshotPath: filepath.Join(t.TempDir(), "maps-snapshot.json"),
+		},
+	}
+
+	src := ProvideMapSource(cfg, &http.Client{}, ProvideMapPool())
+	assert.NotNil(t, src)
+
+	maps, err := src.FetchMaps(ctx.Background())
+	assert.ErrorIs(t, err, source.ErrFallback)
+	assert.Len(t, maps, 10)
+	for _, m := range maps {
+		assert.NotEmpty(t, m.TacticalDescription, "Static maps should count as standard maps")
+	}
+
+	// A nil config still yields a usable chain
+	assert.NotNil(t, ProvideMapSource(nil, &http.Client{}, ProvideMapPool()))
+}
+
+func TestProvideSessionStore(t *testing.T) {
+	// Redis disabled keeps history in memory
+	cfg := &config.Config{
+		Session: config.SessionConfig{HistorySize: 5, TTL: time.Hour},
+	}
+	store, cleanup := ProvideSessionStore(cfg)
+	defer cleanup()
+	assert.IsType(t, &session.MemoryStore{}, store)
+	assert.Equal(t, 5, store.Limit())
+
+	// Redis enabled uses the configured server
+	cfg.Redis = config.RedisConfig{Enabled: true, Address: "127.0.0.1:1"}
+	store, cleanup = ProvideSessionStore(cfg)
+	assert.IsType(t, &session.RedisStore{}, store)
+	cleanup()
+
+	// A nil config still yields a usable store
+	store, cleanup = ProvideSessionStore(nil)
+	defer cleanup()
+	assert.Equal(t, session.DefaultHistorySize, store.Limit())
+}
+
+func TestProvideHistoryStore(t *testing.T) {
+	// Without a database draw history is off
+	store, cleanup, err := ProvideHistoryStore(&config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, store)
+	cleanup()
+
+	store, cleanup, err = ProvideHistoryStore(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, store)
+	cleanup()
+
+	// SQLite is migrated on startup
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{
+			Enabled: true,
+			Driver:  "sqlite",
+			DSN:     filepath.Join(t.TempDir(), "history.db"),
+		},
+	}
+	store, cleanup, err = ProvideHistoryStore(cfg)
+	assert.NoError(t, err)
+	assert.IsType(t, &history.AsyncStore{}, store)
+	cleanup()
+
+	// An unknown driver fails startup
+	cfg.Database.Driver = "oracle"
+	_, _, err = ProvideHistoryStore(cfg)
+	assert.ErrorIs(t, err, database.ErrUnsupportedDriver)
+}
+
+func TestProvideRotation(t *testing.T) {
+	// The built-in seasons only name maps from the static pool
+	poolMaps := make(map[string]bool)
+	for _, m := range ProvideMapPool().Maps {
+		poolMaps[m.UUID] = true
+	}
+	for _, season := range ProvideSeasons() {
+		for _, uuid := range season.MapUUIDs {
+			assert.True(t, poolMaps[uuid], "Season %s lists unknown map %s", season.Name, uuid)
+		}
+	}
+
+	// Without configured seasons the built-in rotation is active
+	r, err := ProvideRotation(&config.Config{}, ProvideSeasons())
+	assert.NoError(t, err)
+	season, err := r.Current(domain.PoolCompetitive, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, season.MapUUIDs, 7)
+
+	r, err = ProvideRotation(nil, ProvideSeasons())
+	assert.NoError(t, err)
+	assert.NotNil(t, r)
+
+	// Invalid configured seasons fail startup
+	_, err = ProvideRotation(&config.Config{
+		Rotation: config.RotationConfig{Seasons: []config.SeasonConfig{{Name: "Bad", Pool: "casual"}}},
+	}, ProvideSeasons())
+	assert.Error(t, err)
+}
+
 func TestProvideHTTPClient(t *testing.T) {
-	client := ProvideHTTPClient()
+	transport := ProvideTransport(nil)
+	client := ProvideHTTPClient(transport)
 
 	// Verify client is not nil and has expected timeout
 	assert.NotNil(t, client)
 	assert.Equal(t, 10, int(client.Timeout.Seconds()), "Expected 10-second timeout")
+	assert.Same(t, transport, client.Transport, "Expected the resilient transport")
 }
 
 func TestProvideImageCache(t *testing.T) {
@@ -7,9 +7,11 @@ type Map struct {
 	TacticalDescription     string    `json:"tacticalDescription"`
 	Coordinates             string    `json:"coordinates"`
 	DisplayIcon             string    `json:"displayIcon"`
+	DisplayIconSrcSet       string    `json:"displayIconSrcSet,omitempty"`
 	ListViewIcon            string    `json:"listViewIcon"`
 	ListViewIconTall        string    `json:"listViewIconTall"`
 	Splash                  string    `json:"splash"`
+	SplashSrcSet            string    `json:"splashSrcSet,omitempty"`
 	StylizedBackgroundImage string    `json:"stylizedBackgroundImage"`
 	PremierBackgroundImage  string    `json:"premierBackgroundImage"`
 	AssetPath               string    `json:"assetPath"`
@@ -1,7 +1,31 @@
 package domain
 
+import "time"
+
+const (
+	// PoolCompetitive is the ranked queue rotation
+	PoolCompetitive = "competitive"
+
+	// PoolPremier is the Premier queue rotation
+	PoolPremier = "premier"
+
+	// PoolAll disables rotation filtering
+	PoolAll = "all"
+)
+
 type MapPool struct {
-	Maps []Map
+	Name          string    `json:"name,omitempty" example:"competitive"`
+	Season        string    `json:"season,omitempty" example:"2025 Act 1"`
+	EffectiveFrom time.Time `json:"effectiveFrom" example:"2025-01-08T00:00:00Z"`
+	Maps          []Map     `json:"maps"`
+}
+
+// Season is a dated map rotation for one pool; it stays active until a later season of the same pool begins
+type Season struct {
+	Name          string    `json:"name"`
+	Pool          string    `json:"pool"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	MapUUIDs      []string  `json:"mapUuids"`
 }
 
 func (p *MapPool) AvailableMaps(banList []string) []Map {
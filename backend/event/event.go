@@ -1 +1,99 @@
 package event
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Event types broadcast to room subscribers
+const (
+	// TypeRoomOpened is the first event of every room
+	TypeRoomOpened = "room.opened"
+
+	// TypeSpin carries the map drawn by a roulette spin
+	TypeSpin = "roulette.spin"
+
+	// TypeRoomClosed tells subscribers the room is gone and they should not reconnect
+	TypeRoomClosed = "room.closed"
+)
+
+// DefaultReplaySize is how many events a room keeps for reconnecting clients
+const DefaultReplaySize = 50
+
+// Event is a message broadcast to everyone in a room. IDs increase by one per room,
+// so a client can resume after the last ID it saw.
+type Event struct {
+	ID   uint64          `json:"id,string" example:"3"`
+	Room string          `json:"room" example:"0b8f3c2e-5d0a-4c55-9a53-2f9a8d1f6b7e"`
+	Type string          `json:"type" example:"roulette.spin"`
+	Data json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+	At   time.Time       `json:"at"`
+}
+
+// New creates an event with its payload encoded as JSON; the ID is assigned by the Log
+func New(room, eventType string, data any) (Event, error) {
+	evt := Event{Room: room, Type: eventType, At: time.Now().UTC()}
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return Event{}, err
+		}
+		evt.Data = payload
+	}
+	return evt, nil
+}
+
+// Log numbers events and keeps the most recent ones for replay
+type Log struct {
+	mutex  sync.Mutex
+	size   int
+	lastID uint64
+	events []Event
+}
+
+// NewLog creates a log that keeps up to size events
+func NewLog(size int) *Log {
+	if size <= 0 {
+		size = DefaultReplaySize
+	}
+	return &Log{size: size}
+}
+
+// Append assigns the next ID to the event and stores it, dropping the oldest event when full
+func (l *Log) Append(evt Event) Event {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.lastID++
+	evt.ID = l.lastID
+
+	if len(l.events) == l.size {
+		copy(l.events, l.events[1:])
+		l.events = l.events[:l.size-1]
+	}
+	l.events = append(l.events, evt)
+
+	return evt
+}
+
+// Since returns the retained events newer than lastID, oldest first
+func (l *Log) Since(lastID uint64) []Event {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var events []Event
+	for _, evt := range l.events {
+		if evt.ID > lastID {
+			events = append(events, evt)
+		}
+	}
+	return events
+}
+
+// LastID returns the ID of the most recent event, or 0 if there is none
+func (l *Log) LastID() uint64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.lastID
+}
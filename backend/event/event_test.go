@@ -14,3 +14,47 @@ func TestPackageExists(t *testing.T) {
 	// Simple test to confirm the package exists and can be imported
 	assert.True(t, true, "Event package exists")
 }
+
+func TestNew(t *testing.T) {
+	evt, err := New("room1", TypeSpin, map[string]string{"map": "Ascent"})
+	assert.NoError(t, err)
+	assert.Equal(t, "room1", evt.Room)
+	assert.Equal(t, TypeSpin, evt.Type)
+	assert.JSONEq(t, `{"map":"Ascent"}`, string(evt.Data))
+	assert.False(t, evt.At.IsZero())
+
+	// Events without a payload have no data
+	evt, err = New("room1", TypeRoomClosed, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, evt.Data)
+
+	_, err = New("room1", TypeSpin, make(chan int))
+	assert.Error(t, err)
+}
+
+func TestLog(t *testing.T) {
+	log := NewLog(3)
+	assert.Equal(t, uint64(0), log.LastID())
+	assert.Empty(t, log.Since(0))
+
+	for i := 0; i < 5; i++ {
+		evt := log.Append(Event{Type: TypeSpin})
+		assert.Equal(t, uint64(i+1), evt.ID)
+	}
+	assert.Equal(t, uint64(5), log.LastID())
+
+	// Only the last three events are kept
+	ids := func(events []Event) []uint64 {
+		var out []uint64
+		for _, evt := range events {
+			out = append(out, evt.ID)
+		}
+		return out
+	}
+	assert.Equal(t, []uint64{3, 4, 5}, ids(log.Since(0)))
+	assert.Equal(t, []uint64{5}, ids(log.Since(4)))
+	assert.Empty(t, log.Since(5))
+
+	// A non-positive size falls back to the default
+	assert.Equal(t, DefaultReplaySize, NewLog(0).size)
+}
@@ -100,6 +100,15 @@ func WithDeadline(parent CTX, deadline time.Time) (CTX, context.CancelFunc) {
 	}, cFunc
 }
 
+// StdContext returns the embedded standard context, or a background context for a
+// zero CTX, so that it can always be passed to context-aware libraries
+func (c CTX) StdContext() context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+	return c.Context
+}
+
 // RequestID returns the request ID from the context
 func (c CTX) RequestID() string {
 	return c.requestID
@@ -225,3 +225,11 @@ func TestWithDeadline(t *testing.T) {
 		t.Fatal("Context did not respect deadline")
 	}
 }
+
+func TestStdContextMethod(t *testing.T) {
+	// A zero CTX still yields a usable context
+	assert.Equal(t, context.Background(), CTX{}.StdContext())
+
+	baseCtx := Background()
+	assert.Equal(t, baseCtx.Context, baseCtx.StdContext())
+}
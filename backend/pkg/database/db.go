@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	// Database drivers selected by name in Open
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// DriverSQLite stores data in a local SQLite file, for development
+	DriverSQLite = "sqlite"
+
+	// DriverPostgres stores data in PostgreSQL, for production
+	DriverPostgres = "postgres"
+
+	// pingTimeout bounds the connection check in Open
+	pingTimeout = 5 * time.Second
+
+	// sqliteBusyTimeout makes SQLite wait for other writers to the same file instead of failing
+	sqliteBusyTimeout = "_busy_timeout=5000"
+)
+
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
+// Options tunes the connection pool opened by Open
+type Options struct {
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// Open connects to the database and checks that it is reachable
+func Open(driver, dsn string, opts Options) (*sql.DB, error) {
+	var driverName string
+	switch driver {
+	case DriverSQLite:
+		driverName = "sqlite3"
+		if !strings.Contains(dsn, "_busy_timeout") {
+			dsn += dsnSeparator(dsn) + sqliteBusyTimeout
+		}
+	case DriverPostgres:
+		driverName = "postgres"
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedDriver, driver)
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", driver, err)
+	}
+
+	// SQLite allows a single writer, so more connections only add lock contention
+	if driver == DriverSQLite {
+		db.SetMaxOpenConns(1)
+	} else if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %w", driver, err)
+	}
+
+	return db, nil
+}
+
+// dsnSeparator returns the character that appends a parameter to a SQLite DSN
+func dsnSeparator(dsn string) string {
+	if strings.Contains(dsn, "?") {
+		return "&"
+	}
+	return "?"
+}
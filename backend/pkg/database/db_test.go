@@ -0,0 +1,52 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	db, err := Open(DriverSQLite, filepath.Join(t.TempDir(), "test.db"), Options{MaxOpenConns: 5})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// SQLite is limited to a single connection
+	assert.Equal(t, 1, db.Stats().MaxOpenConnections)
+
+	// The migration manager works against SQLite
+	manager := NewMigrationManager(db, SQLite)
+	assert.NoError(t, manager.ApplyMigrations([]Migration{{ID: 1, Name: "create_test", SQL: "CREATE TABLE test (id INTEGER)"}}))
+	applied, err := manager.GetAppliedMigrations()
+	assert.NoError(t, err)
+	assert.Equal(t, "create_test", applied[1].Name)
+
+	_, err = Open("oracle", "", Options{})
+	assert.ErrorIs(t, err, ErrUnsupportedDriver)
+
+	// Unreachable databases fail on open
+	_, err = Open(DriverSQLite, filepath.Join(t.TempDir(), "missing", "test.db"), Options{})
+	assert.Error(t, err)
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM t WHERE a = ? AND b IN (?, ?)"
+	assert.Equal(t, query, Rebind(DriverSQLite, query))
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1 AND b IN ($2, $3)", Rebind(DriverPostgres, query))
+}
+
+func TestDialectFor(t *testing.T) {
+	dialect, err := DialectFor(DriverSQLite)
+	assert.NoError(t, err)
+	assert.Equal(t, SQLite, dialect)
+
+	dialect, err = DialectFor(DriverPostgres)
+	assert.NoError(t, err)
+	assert.Equal(t, DriverPostgres, dialect.Name())
+
+	_, err = DialectFor("oracle")
+	assert.ErrorIs(t, err, ErrUnsupportedDriver)
+	assert.Equal(t, "SELECT ?", Rebind("oracle", "SELECT ?"))
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ Dialect = sqliteDialect{}
+	_ Dialect = postgresDialect{}
+)
+
+// Dialect hides the SQL differences between the supported databases
+type Dialect interface {
+	// Name returns the driver name accepted by Open
+	Name() string
+
+	// Rebind rewrites the ? placeholders of a query into the dialect's bind syntax
+	Rebind(query string) string
+
+	// CreateMigrationsTable returns the statement creating the schema_migrations table
+	CreateMigrationsTable() string
+
+	// TableExists returns a query counting the tables named by its single placeholder
+	TableExists() string
+
+	// Lock blocks until this process holds the database-wide migration lock or ctx
+	// ends, and returns the function releasing it
+	Lock(ctx context.Context, db *sql.DB) (unlock func() error, err error)
+}
+
+var (
+	// SQLite is the dialect of DriverSQLite
+	SQLite Dialect = sqliteDialect{}
+
+	// Postgres is the dialect of DriverPostgres
+	Postgres Dialect = postgresDialect{}
+)
+
+// DialectFor returns the dialect of a driver name accepted by Open
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case DriverSQLite:
+		return SQLite, nil
+	case DriverPostgres:
+		return Postgres, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedDriver, driver)
+	}
+}
+
+// Rebind rewrites the ? placeholders of a query into the driver's bind syntax
+func Rebind(driver, query string) string {
+	dialect, err := DialectFor(driver)
+	if err != nil {
+		return query
+	}
+	return dialect.Rebind(query)
+}
+
+// migrationsTable is valid in both dialects; only the catalog queries differ
+const migrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)
+`
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return DriverSQLite
+}
+
+func (sqliteDialect) Rebind(query string) string {
+	return query
+}
+
+func (sqliteDialect) CreateMigrationsTable() string {
+	return migrationsTable
+}
+
+func (sqliteDialect) TableExists() string {
+	return "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return DriverPostgres
+}
+
+func (postgresDialect) Rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (postgresDialect) CreateMigrationsTable() string {
+	return migrationsTable
+}
+
+func (postgresDialect) TableExists() string {
+	return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1"
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// lockPollInterval is how often a waiting instance retries the migration lock
+	lockPollInterval = 250 * time.Millisecond
+
+	// staleLockAge is when a lock table entry is assumed to belong to a crashed instance
+	staleLockAge = 10 * time.Minute
+
+	// lockHeartbeatInterval is how often a holder refreshes its lock table entry, well
+	// within staleLockAge so that a long migration is never taken for a crashed one
+	lockHeartbeatInterval = time.Minute
+
+	// advisoryLockKey identifies the migration lock among PostgreSQL advisory locks ("valomap")
+	advisoryLockKey int64 = 0x76616c6f6d6170
+)
+
+var ErrLockTimeout = errors.New("timed out waiting for migration lock")
+
+// lockTable holds at most one row, owned by the instance running migrations
+const lockTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		owner VARCHAR(255) NOT NULL,
+		locked_at TIMESTAMP NOT NULL
+	)
+`
+
+// Lock takes a session advisory lock on a dedicated connection, which PostgreSQL
+// releases by itself if the instance dies. The pool therefore needs two connections.
+func (postgresDialect) Lock(ctx context.Context, db *sql.DB) (func() error, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve lock connection: %w", err)
+	}
+
+	err = pollLock(ctx, func() (bool, string, error) {
+		var locked bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", advisoryLockKey).Scan(&locked)
+		return locked, "", err
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() error {
+		defer conn.Close()
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey); err != nil {
+			return fmt.Errorf("failed to release migration lock: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// Lock inserts the single row of the lock table. SQLite has no advisory locks, so the
+// holder refreshes locked_at while it runs, and a row left by a crashed instance is
+// taken over once it is older than staleLockAge.
+func (sqliteDialect) Lock(ctx context.Context, db *sql.DB) (func() error, error) {
+	if _, err := db.ExecContext(ctx, lockTable); err != nil {
+		return nil, fmt.Errorf("failed to create migration lock table: %w", err)
+	}
+
+	owner := newLockOwner()
+	err := pollLock(ctx, func() (bool, string, error) {
+		stale, err := db.ExecContext(ctx, "DELETE FROM schema_migrations_lock WHERE locked_at < ?", time.Now().UTC().Add(-staleLockAge))
+		if err != nil {
+			return false, "", err
+		}
+		if n, _ := stale.RowsAffected(); n > 0 {
+			logrus.WithField("component", "migration").Warn("Removed stale migration lock")
+		}
+
+		result, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, ?, ?)", owner, time.Now().UTC())
+		if err != nil {
+			return false, "", err
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			return true, "", nil
+		}
+
+		var holder string
+		if err := db.QueryRowContext(ctx, "SELECT owner FROM schema_migrations_lock").Scan(&holder); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return false, "", err
+		}
+		return false, holder, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stop := heartbeat(db, owner, lockHeartbeatInterval)
+	return func() error {
+		stop()
+		if _, err := db.Exec("DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = ?", owner); err != nil {
+			return fmt.Errorf("failed to release migration lock: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// heartbeat refreshes the lock table entry of owner every interval until the
+// returned function is called, which waits for a refresh in progress to finish
+func heartbeat(db *sql.DB, owner string, interval time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if _, err := db.Exec("UPDATE schema_migrations_lock SET locked_at = ? WHERE id = 1 AND owner = ?", time.Now().UTC(), owner); err != nil {
+				logrus.WithError(err).WithField("component", "migration").Warn("Failed to refresh migration lock")
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// pollLock calls try until it takes the lock, fails or ctx ends. try reports the
+// current holder, when known, for logging.
+func pollLock(ctx context.Context, try func() (bool, string, error)) error {
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	waiting := false
+	for {
+		locked, holder, err := try()
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %w", ErrLockTimeout, ctx.Err())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if locked {
+			return nil
+		}
+
+		if !waiting {
+			logrus.WithFields(logrus.Fields{"component": "migration", "holder": holder}).
+				Info("Waiting for another instance to finish migrating")
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrLockTimeout, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// newLockOwner identifies a lock holder by host, process and a random suffix
+func newLockOwner() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock.db")
+	open := func() *sql.DB {
+		db, err := Open(DriverSQLite, path, Options{})
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	first, second := open(), open()
+
+	unlock, err := SQLite.Lock(context.Background(), first)
+	require.NoError(t, err)
+
+	// Another instance waits until its timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockPollInterval)
+	defer cancel()
+	_, err = SQLite.Lock(ctx, second)
+	assert.ErrorIs(t, err, ErrLockTimeout)
+
+	// and gets the lock once it is released
+	require.NoError(t, unlock())
+	unlock, err = SQLite.Lock(context.Background(), second)
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+
+	// A lock left by a crashed instance is taken over once stale
+	_, err = first.Exec("INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, 'crashed', ?)", time.Now().UTC().Add(-2*staleLockAge))
+	require.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	unlock, err = SQLite.Lock(ctx, second)
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+}
+
+func TestSQLiteLockHeartbeat(t *testing.T) {
+	db, err := Open(DriverSQLite, filepath.Join(t.TempDir(), "heartbeat.db"), Options{})
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(lockTable)
+	require.NoError(t, err)
+
+	// A holder that has run for longer than staleLockAge keeps its entry fresh
+	old := time.Now().UTC().Add(-2 * staleLockAge)
+	_, err = db.Exec("INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, 'holder', ?)", old)
+	require.NoError(t, err)
+
+	stop := heartbeat(db, "holder", 10*time.Millisecond)
+	lockedAt := func() time.Time {
+		var at time.Time
+		require.NoError(t, db.QueryRow("SELECT locked_at FROM schema_migrations_lock").Scan(&at))
+		return at
+	}
+	assert.Eventually(t, func() bool {
+		return lockedAt().After(time.Now().UTC().Add(-staleLockAge))
+	}, time.Second, 10*time.Millisecond)
+	stop()
+
+	// and stops refreshing once released
+	_, err = db.Exec("UPDATE schema_migrations_lock SET locked_at = ?", old)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, lockedAt().Equal(old))
+}
+
+func TestConcurrentMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "concurrent.db")
+	migrations := []Migration{
+		// Both fail if run twice
+		{ID: 1, Name: "001_create_runs", SQL: "CREATE TABLE runs (id INTEGER)"},
+		{ID: 2, Name: "002_record_run", SQL: "INSERT INTO runs (id) VALUES (1)"},
+	}
+
+	// Every replica opens its own pool, as separate containers would
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		db, err := Open(DriverSQLite, path, Options{})
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- NewMigrationManager(db, SQLite).ApplyMigrations(migrations)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	db, err := Open(DriverSQLite, path, Options{})
+	require.NoError(t, err)
+	defer db.Close()
+	var runs int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM runs").Scan(&runs))
+	assert.Equal(t, 1, runs)
+}
+
+func TestPostgresLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The lock is retried while another session holds it
+	locked := func(ok bool) *sqlmock.Rows { return sqlmock.NewRows([]string{"locked"}).AddRow(ok) }
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(advisoryLockKey).WillReturnRows(locked(false))
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(advisoryLockKey).WillReturnRows(locked(true))
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(advisoryLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	unlock, err := Postgres.Lock(context.Background(), db)
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// The manager gives up after its lock timeout
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WillReturnRows(locked(false))
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WillReturnRows(locked(false))
+
+	manager := NewMigrationManager(db, Postgres)
+	manager.SetLockTimeout(lockPollInterval + lockPollInterval/2)
+	err = manager.ApplyMigrations([]Migration{{ID: 1, Name: "never_applied", SQL: "SELECT 1"}})
+	assert.ErrorIs(t, err, ErrLockTimeout)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
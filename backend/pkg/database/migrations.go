@@ -1,47 +1,101 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-// Migration represents a database migration
+var (
+	ErrInvalidMigration = errors.New("invalid migration")
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	ErrIrreversible     = errors.New("migration cannot be rolled back")
+)
+
+// legacyName splits the version prefix off a legacy migration name
+var legacyName = regexp.MustCompile(`^(\d+)_(.+)$`)
+
+const (
+	// legacyTable is the name-keyed table used before migrations were versioned
+	legacyTable = "migrations"
+
+	// defaultLockTimeout bounds the wait for another instance's migrations
+	defaultLockTimeout = time.Minute
+)
+
+// Migration represents a database migration. ID is its version: migrations are
+// applied in ascending and rolled back in descending ID order.
 type Migration struct {
 	ID        int64
 	Name      string
 	SQL       string
+	Down      string
 	Timestamp time.Time
 }
 
+// Checksum identifies the up SQL of the migration, ignoring changes to whitespace
+// and to the final semicolon
+func (m Migration) Checksum() string {
+	normalized := strings.TrimSuffix(strings.Join(strings.Fields(m.SQL), " "), ";")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// AppliedMigration is a migration recorded in the schema_migrations table
+type AppliedMigration struct {
+	ID        int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
 // MigrationManager manages database migrations
 type MigrationManager struct {
-	db     *sql.DB
-	logger *logrus.Entry
+	db          *sql.DB
+	dialect     Dialect
+	dryRun      io.Writer
+	lockTimeout time.Duration
+	logger      *logrus.Entry
 }
 
 // NewMigrationManager creates a new migration manager
-func NewMigrationManager(db *sql.DB) *MigrationManager {
+func NewMigrationManager(db *sql.DB, dialect Dialect) *MigrationManager {
 	return &MigrationManager{
-		db:     db,
-		logger: logrus.WithField("component", "migration"),
+		db:          db,
+		dialect:     dialect,
+		lockTimeout: defaultLockTimeout,
+		logger:      logrus.WithField("component", "migration"),
 	}
 }
 
+// SetLockTimeout bounds how long ApplyMigrations and RollbackTo wait for another
+// instance holding the migration lock; non-positive values keep the default.
+func (m *MigrationManager) SetLockTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		m.lockTimeout = timeout
+	}
+}
+
+// SetDryRun makes the manager write the SQL it would run to w instead of
+// executing it. The database is only read; nil turns dry-run off.
+func (m *MigrationManager) SetDryRun(w io.Writer) {
+	m.dryRun = w
+}
+
 // Initialize creates the migrations table if it doesn't exist
 func (m *MigrationManager) Initialize() error {
-	// Create migrations table
-	_, err := m.db.Exec(`
-		CREATE TABLE IF NOT EXISTS migrations (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(255) NOT NULL UNIQUE,
-			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
-
-	if err != nil {
+	if _, err := m.db.Exec(m.dialect.CreateMigrationsTable()); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
@@ -49,21 +103,37 @@ func (m *MigrationManager) Initialize() error {
 	return nil
 }
 
-// GetAppliedMigrations returns a list of applied migrations
-func (m *MigrationManager) GetAppliedMigrations() (map[string]bool, error) {
-	rows, err := m.db.Query("SELECT name FROM migrations")
+// GetAppliedMigrations returns the applied migrations by version
+func (m *MigrationManager) GetAppliedMigrations() (map[int64]AppliedMigration, error) {
+	return m.appliedMigrations(m.dryRun != nil)
+}
+
+// appliedMigrations returns the applied migrations by version. Read-only callers
+// never create the table, so it may not exist yet.
+func (m *MigrationManager) appliedMigrations(readOnly bool) (map[int64]AppliedMigration, error) {
+	if readOnly {
+		exists, err := m.tableExists("schema_migrations")
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return map[int64]AppliedMigration{}, nil
+		}
+	}
+
+	rows, err := m.db.Query("SELECT version, name, checksum, applied_at FROM schema_migrations")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
 	}
 	defer rows.Close()
 
-	appliedMigrations := make(map[string]bool)
+	appliedMigrations := make(map[int64]AppliedMigration)
 	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
+		var applied AppliedMigration
+		if err := rows.Scan(&applied.ID, &applied.Name, &applied.Checksum, &applied.AppliedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan migration row: %w", err)
 		}
-		appliedMigrations[name] = true
+		appliedMigrations[applied.ID] = applied
 	}
 
 	if err := rows.Err(); err != nil {
@@ -73,50 +143,61 @@ func (m *MigrationManager) GetAppliedMigrations() (map[string]bool, error) {
 	return appliedMigrations, nil
 }
 
-// ApplyMigration applies a migration to the database
+// ApplyMigration runs the up SQL of a migration and records it
 func (m *MigrationManager) ApplyMigration(migration Migration) error {
-	// Begin transaction
-	tx, err := m.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+	if m.dryRun != nil {
+		return m.printSQL(migration, "up", migration.SQL)
 	}
 
-	// Execute migration SQL
-	_, err = tx.Exec(migration.SQL)
+	record := m.dialect.Rebind("INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)")
+	err := m.transact(migration, migration.SQL, record, migration.ID, migration.Name, migration.Checksum())
 	if err != nil {
-		tx.Rollback()
 		return fmt.Errorf("failed to apply migration '%s': %w", migration.Name, err)
 	}
 
-	// Record migration
-	_, err = tx.Exec("INSERT INTO migrations (name) VALUES ($1)", migration.Name)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to record migration '%s': %w", migration.Name, err)
+	m.logger.WithFields(logrus.Fields{"version": migration.ID, "name": migration.Name}).Info("Applied migration")
+	return nil
+}
+
+// RevertMigration runs the down SQL of a migration and removes its record
+func (m *MigrationManager) RevertMigration(migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %s has no down SQL", ErrIrreversible, migration.Name)
+	}
+	if m.dryRun != nil {
+		return m.printSQL(migration, "down", migration.Down)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit migration '%s': %w", migration.Name, err)
+	forget := m.dialect.Rebind("DELETE FROM schema_migrations WHERE version = ?")
+	if err := m.transact(migration, migration.Down, forget, migration.ID); err != nil {
+		return fmt.Errorf("failed to revert migration '%s': %w", migration.Name, err)
 	}
 
-	m.logger.WithField("name", migration.Name).Info("Applied migration")
+	m.logger.WithFields(logrus.Fields{"version": migration.ID, "name": migration.Name}).Info("Reverted migration")
 	return nil
 }
 
-// ApplyMigrations applies all migrations that haven't been applied yet
+// ApplyMigrations applies all migrations that haven't been applied yet, in version order.
+// It fails without changes when an applied migration's SQL has been edited since.
 func (m *MigrationManager) ApplyMigrations(migrations []Migration) error {
-	if err := m.Initialize(); err != nil {
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
 		return err
 	}
 
-	appliedMigrations, err := m.GetAppliedMigrations()
+	return m.withLock(func() error {
+		return m.applyMigrations(sorted)
+	})
+}
+
+func (m *MigrationManager) applyMigrations(sorted []Migration) error {
+	applied, err := m.load(sorted)
 	if err != nil {
 		return err
 	}
 
-	for _, migration := range migrations {
-		if appliedMigrations[migration.Name] {
+	for _, migration := range sorted {
+		if _, ok := applied[migration.ID]; ok {
 			m.logger.WithField("name", migration.Name).Debug("Migration already applied")
 			continue
 		}
@@ -129,3 +210,283 @@ func (m *MigrationManager) ApplyMigrations(migrations []Migration) error {
 	m.logger.Info("All migrations applied")
 	return nil
 }
+
+// RollbackTo reverts the applied migrations newer than target, newest first.
+// Every migration to revert must have down SQL, or nothing is reverted.
+func (m *MigrationManager) RollbackTo(migrations []Migration, target int64) error {
+	if target < 0 {
+		return fmt.Errorf("%w: negative target version %d", ErrInvalidMigration, target)
+	}
+
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return err
+	}
+
+	return m.withLock(func() error {
+		return m.rollbackTo(sorted, target)
+	})
+}
+
+func (m *MigrationManager) rollbackTo(sorted []Migration, target int64) error {
+	applied, err := m.load(sorted)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int64]Migration, len(sorted))
+	for _, migration := range sorted {
+		byID[migration.ID] = migration
+	}
+
+	var reverts []Migration
+	for id := range applied {
+		if id <= target {
+			continue
+		}
+		migration, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("%w: applied version %d is unknown", ErrIrreversible, id)
+		}
+		if migration.Down == "" {
+			return fmt.Errorf("%w: %s has no down SQL", ErrIrreversible, migration.Name)
+		}
+		reverts = append(reverts, migration)
+	}
+	sort.Slice(reverts, func(i, j int) bool { return reverts[i].ID > reverts[j].ID })
+
+	for _, migration := range reverts {
+		if err := m.RevertMigration(migration); err != nil {
+			return err
+		}
+	}
+
+	m.logger.WithField("version", target).Info("Rolled back migrations")
+	return nil
+}
+
+// MigrationStatus describes a migration known to the code or recorded as applied
+type MigrationStatus struct {
+	ID         int64
+	Name       string
+	Applied    bool
+	AppliedAt  time.Time
+	Reversible bool
+	// Modified is set when the migration was applied with different SQL
+	Modified bool
+	// Unknown is set when the applied migration is missing from the code
+	Unknown bool
+}
+
+// Status reports every migration and whether it has been applied, in version order.
+// It only reads the database and takes no lock.
+func (m *MigrationManager) Status(migrations []Migration) ([]MigrationStatus, error) {
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedMigrations(true)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.adoptLegacy(sorted, applied, true); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(sorted))
+	known := make(map[int64]bool, len(sorted))
+	for _, migration := range sorted {
+		known[migration.ID] = true
+		status := MigrationStatus{ID: migration.ID, Name: migration.Name, Reversible: migration.Down != ""}
+		if record, ok := applied[migration.ID]; ok {
+			status.Applied = true
+			status.AppliedAt = record.AppliedAt
+			status.Modified = record.Checksum != migration.Checksum()
+		}
+		statuses = append(statuses, status)
+	}
+	for id, record := range applied {
+		if !known[id] {
+			statuses = append(statuses, MigrationStatus{ID: id, Name: record.Name, Applied: true, AppliedAt: record.AppliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+
+	return statuses, nil
+}
+
+// withLock runs fn while holding the migration lock, so that instances starting
+// together migrate one at a time. Dry runs only read and skip the lock.
+func (m *MigrationManager) withLock(fn func() error) error {
+	if m.dryRun != nil {
+		return fn()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.lockTimeout)
+	defer cancel()
+
+	start := time.Now()
+	unlock, err := m.dialect.Lock(ctx, m.db)
+	if err != nil {
+		return err
+	}
+	m.logger.WithField("waited", time.Since(start).Round(time.Millisecond)).Info("Acquired migration lock")
+
+	defer func() {
+		if err := unlock(); err != nil {
+			m.logger.WithError(err).Warn("Failed to release migration lock")
+			return
+		}
+		m.logger.Debug("Released migration lock")
+	}()
+
+	return fn()
+}
+
+// load returns the applied migrations after adopting legacy records and
+// verifying the checksums of the sorted migrations
+func (m *MigrationManager) load(sorted []Migration) (map[int64]AppliedMigration, error) {
+	if m.dryRun == nil {
+		if err := m.Initialize(); err != nil {
+			return nil, err
+		}
+	}
+
+	applied, err := m.appliedMigrations(m.dryRun != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.adoptLegacy(sorted, applied, m.dryRun != nil); err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]bool, len(sorted))
+	for _, migration := range sorted {
+		known[migration.ID] = true
+		record, ok := applied[migration.ID]
+		if ok && record.Checksum != migration.Checksum() {
+			return nil, fmt.Errorf("%w: %d %s", ErrChecksumMismatch, migration.ID, migration.Name)
+		}
+	}
+	for id, record := range applied {
+		if !known[id] {
+			m.logger.WithFields(logrus.Fields{"version": id, "name": record.Name}).Warn("Applied migration is unknown to this build")
+		}
+	}
+
+	return applied, nil
+}
+
+// adoptLegacy adds the migrations found in the legacy table to applied and, unless
+// readOnly, records them. Legacy names may carry the version, as in 001_create_maps.
+func (m *MigrationManager) adoptLegacy(migrations []Migration, applied map[int64]AppliedMigration, readOnly bool) error {
+	exists, err := m.tableExists(legacyTable)
+	if err != nil || !exists {
+		return err
+	}
+
+	rows, err := m.db.Query("SELECT name FROM " + legacyTable)
+	if err != nil {
+		return fmt.Errorf("failed to get legacy migrations: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[string]bool)
+	versioned := make(map[int64]string)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("failed to scan legacy migration row: %w", err)
+		}
+		names[name] = true
+		if match := legacyName.FindStringSubmatch(name); match != nil {
+			version, _ := strconv.ParseInt(match[1], 10, 64)
+			versioned[version] = match[2]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over legacy migrations: %w", err)
+	}
+	rows.Close()
+
+	record := m.dialect.Rebind("INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)")
+	for _, migration := range migrations {
+		if _, ok := applied[migration.ID]; ok {
+			continue
+		}
+		if !names[migration.Name] && versioned[migration.ID] != migration.Name {
+			continue
+		}
+
+		if !readOnly {
+			if _, err := m.db.Exec(record, migration.ID, migration.Name, migration.Checksum()); err != nil {
+				return fmt.Errorf("failed to adopt legacy migration '%s': %w", migration.Name, err)
+			}
+		}
+		applied[migration.ID] = AppliedMigration{ID: migration.ID, Name: migration.Name, Checksum: migration.Checksum(), AppliedAt: time.Now()}
+		m.logger.WithFields(logrus.Fields{"version": migration.ID, "name": migration.Name}).Info("Adopted legacy migration")
+	}
+
+	return nil
+}
+
+// transact runs a migration statement and its bookkeeping statement in one transaction
+func (m *MigrationManager) transact(migration Migration, statement, bookkeeping string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	if _, err := tx.Exec(statement); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec(bookkeeping, args...); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record version %d: %w", migration.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// printSQL writes the SQL a dry run would execute
+func (m *MigrationManager) printSQL(migration Migration, direction, statement string) error {
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+	_, err := fmt.Fprintf(m.dryRun, "-- %d %s (%s)\n%s;\n\n", migration.ID, migration.Name, direction, statement)
+	return err
+}
+
+// tableExists reports whether the named table exists
+func (m *MigrationManager) tableExists(name string) (bool, error) {
+	var count int
+	if err := m.db.QueryRow(m.dialect.TableExists(), name).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", name, err)
+	}
+	return count > 0, nil
+}
+
+// sortMigrations validates migrations and returns a copy in version order
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	for i, migration := range sorted {
+		if migration.ID <= 0 {
+			return nil, fmt.Errorf("%w: %q needs a positive version", ErrInvalidMigration, migration.Name)
+		}
+		if migration.Name == "" {
+			return nil, fmt.Errorf("%w: version %d has no name", ErrInvalidMigration, migration.ID)
+		}
+		if i > 0 && sorted[i-1].ID == migration.ID {
+			return nil, fmt.Errorf("%w: version %d is used by %q and %q", ErrInvalidMigration, migration.ID, sorted[i-1].Name, migration.Name)
+		}
+	}
+	return sorted, nil
+}
@@ -1,15 +1,18 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockDB is a mock database for testing
@@ -71,11 +74,12 @@ func TestNewMigrationManager(t *testing.T) {
 	defer db.Close()
 
 	// Create a migration manager
-	manager := NewMigrationManager(db)
+	manager := NewMigrationManager(db, Postgres)
 
 	// Assertions
 	assert.NotNil(t, manager)
 	assert.Equal(t, db, manager.db)
+	assert.Equal(t, Postgres, manager.dialect)
 }
 
 func TestInitialize(t *testing.T) {
@@ -85,10 +89,10 @@ func TestInitialize(t *testing.T) {
 	defer db.Close()
 
 	// Create a migration manager
-	manager := NewMigrationManager(db)
+	manager := NewMigrationManager(db, Postgres)
 
 	// Set expectations for the migrations table creation
-	mock.ExpectExec("CREATE TABLE IF NOT EXISTS migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
 
 	// Call Initialize
 	err = manager.Initialize()
@@ -98,7 +102,7 @@ func TestInitialize(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 
 	// Test with error
-	mock.ExpectExec("CREATE TABLE IF NOT EXISTS migrations").WillReturnError(errors.New("db error"))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnError(errors.New("db error"))
 
 	// Call Initialize
 	err = manager.Initialize()
@@ -115,14 +119,15 @@ func TestGetAppliedMigrations(t *testing.T) {
 	defer db.Close()
 
 	// Create a migration manager
-	manager := NewMigrationManager(db)
+	manager := NewMigrationManager(db, Postgres)
 
 	// Set expectations for the query
-	rows := sqlmock.NewRows([]string{"name"}).
-		AddRow("create_users_table").
-		AddRow("add_email_to_users")
+	appliedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).
+		AddRow(1, "create_users_table", "abc", appliedAt).
+		AddRow(2, "add_email_to_users", "def", appliedAt)
 
-	mock.ExpectQuery("SELECT name FROM migrations").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(rows)
 
 	// Call GetAppliedMigrations
 	migrations, err := manager.GetAppliedMigrations()
@@ -130,12 +135,12 @@ func TestGetAppliedMigrations(t *testing.T) {
 	// Assertions
 	assert.NoError(t, err)
 	assert.Len(t, migrations, 2)
-	assert.True(t, migrations["create_users_table"])
-	assert.True(t, migrations["add_email_to_users"])
+	assert.Equal(t, AppliedMigration{ID: 1, Name: "create_users_table", Checksum: "abc", AppliedAt: appliedAt}, migrations[1])
+	assert.Equal(t, "add_email_to_users", migrations[2].Name)
 	assert.NoError(t, mock.ExpectationsWereMet())
 
 	// Test with error
-	mock.ExpectQuery("SELECT name FROM migrations").WillReturnError(errors.New("db error"))
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnError(errors.New("db error"))
 
 	// Call GetAppliedMigrations
 	migrations, err = manager.GetAppliedMigrations()
@@ -153,14 +158,14 @@ func TestGetAppliedMigrations_Errors(t *testing.T) {
 	defer db.Close()
 
 	// Create a migration manager
-	manager := NewMigrationManager(db)
+	manager := NewMigrationManager(db, Postgres)
 
 	// Set up mock for rows.Scan error
-	rows := sqlmock.NewRows([]string{"name"}).
-		AddRow("create_users_table").
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).
+		AddRow(1, "create_users_table", "abc", time.Now()).
 		RowError(0, errors.New("scan error"))
 
-	mock.ExpectQuery("SELECT name FROM migrations").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(rows)
 
 	// Call GetAppliedMigrations
 	migrations, err := manager.GetAppliedMigrations()
@@ -171,11 +176,11 @@ func TestGetAppliedMigrations_Errors(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 
 	// Set up mock for rows.Err error
-	rows = sqlmock.NewRows([]string{"name"}).
-		AddRow("create_users_table").
+	rows = sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).
+		AddRow(1, "create_users_table", "abc", time.Now()).
 		CloseError(errors.New("rows error"))
 
-	mock.ExpectQuery("SELECT name FROM migrations").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(rows)
 
 	// Call GetAppliedMigrations
 	migrations, err = manager.GetAppliedMigrations()
@@ -193,10 +198,11 @@ func TestApplyMigration(t *testing.T) {
 	defer db.Close()
 
 	// Create a migration manager
-	manager := NewMigrationManager(db)
+	manager := NewMigrationManager(db, Postgres)
 
 	// Create a test migration
 	migration := Migration{
+		ID:   1,
 		Name: "test_migration",
 		SQL:  "CREATE TABLE test (id INT)",
 	}
@@ -204,7 +210,8 @@ func TestApplyMigration(t *testing.T) {
 	// Set expectations for transaction
 	mock.ExpectBegin()
 	mock.ExpectExec("CREATE TABLE test").WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec("INSERT INTO migrations").WithArgs("test_migration").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO schema_migrations \\(version, name, checksum\\) VALUES \\(\\$1, \\$2, \\$3\\)").
+		WithArgs(1, "test_migration", migration.Checksum()).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
 	// Call ApplyMigration
@@ -229,7 +236,7 @@ func TestApplyMigration(t *testing.T) {
 	// Test with error in insert
 	mock.ExpectBegin()
 	mock.ExpectExec("CREATE TABLE test").WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec("INSERT INTO migrations").WithArgs("test_migration").WillReturnError(errors.New("db error"))
+	mock.ExpectExec("INSERT INTO schema_migrations").WillReturnError(errors.New("db error"))
 	mock.ExpectRollback()
 
 	// Call ApplyMigration
@@ -240,6 +247,45 @@ func TestApplyMigration(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestRevertMigration(t *testing.T) {
+	// Create a mock DB
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Create a migration manager
+	manager := NewMigrationManager(db, Postgres)
+
+	// Set expectations for transaction
+	mock.ExpectBegin()
+	mock.ExpectExec("DROP TABLE test").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations WHERE version = \\$1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Call RevertMigration
+	err = manager.RevertMigration(Migration{ID: 1, Name: "test_migration", SQL: "CREATE TABLE test (id INT)", Down: "DROP TABLE test"})
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Migrations without down SQL cannot be reverted
+	err = manager.RevertMigration(Migration{ID: 1, Name: "test_migration", SQL: "CREATE TABLE test (id INT)"})
+	assert.ErrorIs(t, err, ErrIrreversible)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectLock expects the PostgreSQL advisory lock to be taken at once
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT pg_try_advisory_lock\\(\\$1\\)").WithArgs(advisoryLockKey).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+}
+
+// expectUnlock expects the PostgreSQL advisory lock to be released
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_unlock\\(\\$1\\)").WithArgs(advisoryLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
 func TestApplyMigrations(t *testing.T) {
 	// Create a mock DB
 	db, mock, err := sqlmock.New()
@@ -247,35 +293,52 @@ func TestApplyMigrations(t *testing.T) {
 	defer db.Close()
 
 	// Create a migration manager
-	manager := NewMigrationManager(db)
+	manager := NewMigrationManager(db, Postgres)
 
-	// Create test migrations
+	// Create test migrations, out of order
 	migrations := []Migration{
 		{
+			ID:   2,
+			Name: "migration_2",
+			SQL:  "CREATE TABLE test2 (id INT)",
+		},
+		{
+			ID:   1,
 			Name: "migration_1",
 			SQL:  "CREATE TABLE test1 (id INT)",
 		},
 		{
-			Name: "migration_2",
-			SQL:  "CREATE TABLE test2 (id INT)",
+			ID:   3,
+			Name: "migration_3",
+			SQL:  "CREATE TABLE test3 (id INT)",
 		},
 	}
 
 	// Set expectations for initialize
-	mock.ExpectExec("CREATE TABLE IF NOT EXISTS migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
 
 	// Set expectations for applied migrations query
-	rows := sqlmock.NewRows([]string{"name"}).
-		AddRow("migration_1")
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).
+		AddRow(1, "migration_1", migrations[1].Checksum(), time.Now())
+
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(rows)
 
-	mock.ExpectQuery("SELECT name FROM migrations").WillReturnRows(rows)
+	// Set expectations for the legacy table lookup
+	mock.ExpectQuery("information_schema.tables").WithArgs("migrations").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 
-	// Set expectations for the second migration only (since first is already applied)
+	// Set expectations for the pending migrations in version order (the first is already applied)
 	mock.ExpectBegin()
 	mock.ExpectExec("CREATE TABLE test2").WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec("INSERT INTO migrations").WithArgs("migration_2").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(2, "migration_2", migrations[0].Checksum()).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE test3").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(3, "migration_3", migrations[2].Checksum()).WillReturnResult(sqlmock.NewResult(3, 1))
 	mock.ExpectCommit()
 
+	expectUnlock(mock)
+
 	// Call ApplyMigrations
 	err = manager.ApplyMigrations(migrations)
 
@@ -284,7 +347,10 @@ func TestApplyMigrations(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 
 	// Test with error in initialize
-	mock.ExpectExec("CREATE TABLE IF NOT EXISTS migrations").WillReturnError(errors.New("db error"))
+	expectLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnError(errors.New("db error"))
+
+	expectUnlock(mock)
 
 	// Call ApplyMigrations
 	err = manager.ApplyMigrations(migrations)
@@ -294,8 +360,11 @@ func TestApplyMigrations(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 
 	// Test with error in getting applied migrations
-	mock.ExpectExec("CREATE TABLE IF NOT EXISTS migrations").WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectQuery("SELECT name FROM migrations").WillReturnError(errors.New("db error"))
+	expectLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnError(errors.New("db error"))
+
+	expectUnlock(mock)
 
 	// Call ApplyMigrations
 	err = manager.ApplyMigrations(migrations)
@@ -313,19 +382,35 @@ func TestApplyMigrations_Errors(t *testing.T) {
 	defer db.Close()
 
 	// Create a migration manager
-	manager := NewMigrationManager(db)
+	manager := NewMigrationManager(db, Postgres)
+
+	// Invalid migration lists fail before touching the database
+	for _, migrations := range [][]Migration{
+		{{Name: "no_version", SQL: "SELECT 1"}},
+		{{ID: 1, SQL: "SELECT 1"}},
+		{{ID: 1, Name: "first", SQL: "SELECT 1"}, {ID: 1, Name: "second", SQL: "SELECT 2"}},
+	} {
+		assert.ErrorIs(t, manager.ApplyMigrations(migrations), ErrInvalidMigration)
+	}
+	assert.ErrorIs(t, manager.RollbackTo(nil, -1), ErrInvalidMigration)
 
 	// Create test migrations
 	migrations := []Migration{
 		{
+			ID:   1,
 			Name: "migration_1",
 			SQL:  "CREATE TABLE test1 (id INT)",
 		},
 	}
 
-	// Test GetAppliedMigrations error
-	mock.ExpectExec("CREATE TABLE IF NOT EXISTS migrations").WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectQuery("SELECT name FROM migrations").WillReturnError(errors.New("query error"))
+	// Test legacy table lookup error
+	expectLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}))
+	mock.ExpectQuery("information_schema.tables").WillReturnError(errors.New("query error"))
+
+	expectUnlock(mock)
 
 	// Call ApplyMigrations
 	err = manager.ApplyMigrations(migrations)
@@ -333,6 +418,173 @@ func TestApplyMigrations_Errors(t *testing.T) {
 	// Assertions
 	assert.Error(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Test checksum mismatch
+	expectLock(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).AddRow(1, "migration_1", "edited", time.Now()))
+	mock.ExpectQuery("information_schema.tables").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	expectUnlock(mock)
+
+	// Call ApplyMigrations
+	err = manager.ApplyMigrations(migrations)
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// newSQLiteManager returns a migration manager on an empty SQLite database
+func newSQLiteManager(t *testing.T) (*MigrationManager, *sql.DB) {
+	db, err := Open(DriverSQLite, filepath.Join(t.TempDir(), "migrations.db"), Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewMigrationManager(db, SQLite), db
+}
+
+// sqliteMigrations create a table and add a column to it
+var sqliteMigrations = []Migration{
+	{ID: 1, Name: "001_create_maps", SQL: "CREATE TABLE maps (uuid TEXT PRIMARY KEY)", Down: "DROP TABLE maps"},
+	{ID: 2, Name: "002_add_name", SQL: "ALTER TABLE maps ADD COLUMN name TEXT", Down: "ALTER TABLE maps DROP COLUMN name"},
+}
+
+func TestMigrationsSQLite(t *testing.T) {
+	manager, db := newSQLiteManager(t)
+	columns := func() int {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('maps')").Scan(&n))
+		return n
+	}
+
+	// Applying is idempotent
+	require.NoError(t, manager.ApplyMigrations(sqliteMigrations))
+	require.NoError(t, manager.ApplyMigrations(sqliteMigrations))
+	assert.Equal(t, 2, columns())
+
+	applied, err := manager.GetAppliedMigrations()
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.Equal(t, sqliteMigrations[1].Checksum(), applied[2].Checksum)
+	assert.False(t, applied[2].AppliedAt.IsZero())
+
+	// Reformatting a migration keeps its checksum, editing it does not
+	reformatted := append([]Migration{}, sqliteMigrations...)
+	reformatted[0].SQL = "CREATE TABLE maps\n\t(uuid TEXT PRIMARY KEY)\n"
+	assert.NoError(t, manager.ApplyMigrations(reformatted))
+	reformatted[0].SQL = "CREATE TABLE maps (uuid TEXT PRIMARY KEY, name TEXT)"
+	assert.ErrorIs(t, manager.ApplyMigrations(reformatted), ErrChecksumMismatch)
+
+	// Rolling back reverts newer versions only
+	require.NoError(t, manager.RollbackTo(sqliteMigrations, 1))
+	assert.Equal(t, 1, columns())
+	applied, err = manager.GetAppliedMigrations()
+	require.NoError(t, err)
+	assert.Len(t, applied, 1)
+
+	require.NoError(t, manager.RollbackTo(sqliteMigrations, 0))
+	var tables int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'maps'").Scan(&tables))
+	assert.Equal(t, 0, tables)
+
+	// Nothing is reverted when any migration lacks down SQL
+	require.NoError(t, manager.ApplyMigrations(sqliteMigrations))
+	irreversible := append([]Migration{}, sqliteMigrations...)
+	irreversible[0].Down = ""
+	assert.ErrorIs(t, manager.RollbackTo(irreversible, 0), ErrIrreversible)
+	assert.Equal(t, 2, columns())
+
+	// Applied versions missing from the code block rollbacks past them
+	assert.ErrorIs(t, manager.RollbackTo(sqliteMigrations[:1], 0), ErrIrreversible)
+}
+
+func TestMigrationsDryRun(t *testing.T) {
+	manager, db := newSQLiteManager(t)
+	var out bytes.Buffer
+	manager.SetDryRun(&out)
+
+	// Pending migrations are printed, not run
+	require.NoError(t, manager.ApplyMigrations(sqliteMigrations))
+	assert.Equal(t, "-- 1 001_create_maps (up)\nCREATE TABLE maps (uuid TEXT PRIMARY KEY);\n\n"+
+		"-- 2 002_add_name (up)\nALTER TABLE maps ADD COLUMN name TEXT;\n\n", out.String())
+	var tables int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&tables))
+	assert.Equal(t, 0, tables)
+
+	// Only the versions that would be reverted are printed
+	manager.SetDryRun(nil)
+	require.NoError(t, manager.ApplyMigrations(sqliteMigrations))
+	out.Reset()
+	manager.SetDryRun(&out)
+	require.NoError(t, manager.RollbackTo(sqliteMigrations, 1))
+	assert.Equal(t, "-- 2 002_add_name (down)\nALTER TABLE maps DROP COLUMN name;\n\n", out.String())
+
+	applied, err := manager.GetAppliedMigrations()
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+}
+
+func TestMigrationsAdoptLegacy(t *testing.T) {
+	manager, db := newSQLiteManager(t)
+
+	// The first migration was applied by the name-keyed manager
+	_, err := db.Exec(`
+		CREATE TABLE migrations (id INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL UNIQUE, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);
+		INSERT INTO migrations (name) VALUES ('001_create_maps');
+		CREATE TABLE maps (uuid TEXT PRIMARY KEY)
+	`)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.ApplyMigrations(sqliteMigrations))
+	applied, err := manager.GetAppliedMigrations()
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.Equal(t, "001_create_maps", applied[1].Name)
+
+	// Legacy names carrying the version match migrations loaded from files
+	manager, db = newSQLiteManager(t)
+	_, err = db.Exec(`
+		CREATE TABLE migrations (id INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL UNIQUE, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);
+		INSERT INTO migrations (name) VALUES ('001_create_maps');
+		CREATE TABLE maps (uuid TEXT PRIMARY KEY)
+	`)
+	require.NoError(t, err)
+
+	loaded := append([]Migration{}, sqliteMigrations...)
+	loaded[0].Name = "create_maps"
+	require.NoError(t, manager.ApplyMigrations(loaded))
+}
+
+func TestMigrationsStatus(t *testing.T) {
+	manager, db := newSQLiteManager(t)
+
+	// Nothing is created before migrating
+	statuses, err := manager.Status(sqliteMigrations)
+	require.NoError(t, err)
+	assert.Equal(t, []MigrationStatus{
+		{ID: 1, Name: "001_create_maps", Reversible: true},
+		{ID: 2, Name: "002_add_name", Reversible: true},
+	}, statuses)
+	var tables int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&tables))
+	assert.Equal(t, 0, tables)
+
+	require.NoError(t, manager.ApplyMigrations(sqliteMigrations[:1]))
+	edited := append([]Migration{}, sqliteMigrations...)
+	edited[0].SQL = "CREATE TABLE maps (uuid TEXT)"
+	statuses, err = manager.Status(edited[1:])
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.True(t, statuses[0].Unknown)
+	assert.False(t, statuses[0].AppliedAt.IsZero())
+	assert.False(t, statuses[1].Applied)
+
+	statuses, err = manager.Status(edited)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Modified)
+	assert.False(t, statuses[0].Unknown)
 }
 
 // Add NewMigrator function that will fix the undefined references
@@ -340,7 +592,7 @@ func TestApplyMigrations_Errors(t *testing.T) {
 func NewMigrator(db interface{}) *MigrationManager {
 	// Convert to sql.DB if needed
 	if sqlDB, ok := db.(*sql.DB); ok {
-		return NewMigrationManager(sqlDB)
+		return NewMigrationManager(sqlDB, Postgres)
 	}
 	// For tests with mocks, create a mock migration manager
 	return &MigrationManager{}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFile matches NNNN_name.up.sql and NNNN_name.down.sql
+var migrationFile = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads the migrations in dir of fsys, which is an embed.FS or an
+// os.DirFS. Each version needs an up file and may have a down file; versions must
+// start at 1 without gaps. A .sql file whose name does not follow the pattern is
+// rejected, so that a misnamed migration is not silently left out; other files
+// and subdirectories are ignored.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byID := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s is not named NNNN_name.up.sql or NNNN_name.down.sql", ErrInvalidMigration, entry.Name())
+		}
+
+		id, _ := strconv.ParseInt(match[1], 10, 64)
+		migration, ok := byID[id]
+		if !ok {
+			migration = &Migration{ID: id, Name: match[2]}
+			byID[id] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d is used by %q and %q", ErrInvalidMigration, id, migration.Name, match[2])
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		if match[3] == "up" {
+			migration.SQL = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byID))
+	for _, migration := range byID {
+		if migration.SQL == "" {
+			return nil, fmt.Errorf("%w: version %d %s has no up file", ErrInvalidMigration, migration.ID, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].ID < migrations[j].ID })
+
+	for i, migration := range migrations {
+		if migration.ID != int64(i+1) {
+			return nil, fmt.Errorf("%w: expected version %d before %d %s", ErrInvalidMigration, i+1, migration.ID, migration.Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// MustLoadMigrations is like LoadMigrations but panics on error. It simplifies
+// package variables holding embedded migrations.
+func MustLoadMigrations(fsys fs.FS, dir string) []Migration {
+	migrations, err := LoadMigrations(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return migrations
+}
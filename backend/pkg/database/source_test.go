@@ -0,0 +1,61 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_add_name.up.sql":       {Data: []byte("ALTER TABLE maps ADD COLUMN name TEXT;\n")},
+		"sql/0001_create_maps.up.sql":    {Data: []byte("CREATE TABLE maps (uuid TEXT PRIMARY KEY);\n")},
+		"sql/0001_create_maps.down.sql":  {Data: []byte("DROP TABLE maps;\n")},
+		"sql/README.md":                  {Data: []byte("Not a migration")},
+		"sql/0003_ignored/0003_x.up.sql": {Data: []byte("SELECT 1")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "sql")
+	require.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{ID: 1, Name: "create_maps", SQL: "CREATE TABLE maps (uuid TEXT PRIMARY KEY);\n", Down: "DROP TABLE maps;\n"},
+		{ID: 2, Name: "add_name", SQL: "ALTER TABLE maps ADD COLUMN name TEXT;\n"},
+	}, migrations)
+
+	// A trailing semicolon does not change the checksum
+	assert.Equal(t, sqliteMigrations[0].Checksum(), migrations[0].Checksum())
+
+	// Directories on disk load the same way
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_create_maps.up.sql"), []byte("CREATE TABLE maps (uuid TEXT)"), 0o644))
+	migrations, err = LoadMigrations(os.DirFS(dir), ".")
+	require.NoError(t, err)
+	assert.Len(t, migrations, 1)
+
+	_, err = LoadMigrations(fsys, "missing")
+	assert.Error(t, err)
+}
+
+func TestLoadMigrationsInvalid(t *testing.T) {
+	up := &fstest.MapFile{Data: []byte("SELECT 1")}
+	for name, fsys := range map[string]fstest.MapFS{
+		"Gap":           {"0001_a.up.sql": up, "0003_c.up.sql": up},
+		"NotFromOne":    {"0002_b.up.sql": up},
+		"DownOnly":      {"0001_a.up.sql": up, "0002_b.down.sql": up},
+		"DuplicateName": {"0001_a.up.sql": up, "0001_b.up.sql": up},
+		"Dash":          {"0001_a.up.sql": up, "0002_add-col.up.sql": up},
+		"ShortVersion":  {"0001_a.up.sql": up, "002_b.up.sql": up},
+		"NoDirection":   {"0001_a.up.sql": up, "0002_b.sql": up},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadMigrations(fsys, ".")
+			assert.ErrorIs(t, err, ErrInvalidMigration)
+		})
+	}
+
+	assert.Panics(t, func() { MustLoadMigrations(fstest.MapFS{"0002_b.up.sql": up}, ".") })
+}
@@ -0,0 +1,127 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// State is the position of a circuit breaker
+type State string
+
+const (
+	// StateClosed lets every request through
+	StateClosed State = "closed"
+
+	// StateOpen rejects requests until the cooldown has passed
+	StateOpen State = "open"
+
+	// StateHalfOpen lets a single probe through to test whether the upstream recovered
+	StateHalfOpen State = "half-open"
+)
+
+// BreakerStatus is a snapshot of a circuit breaker, as reported by the health check
+type BreakerStatus struct {
+	State    State      `json:"state" example:"closed"`
+	Failures int        `json:"failures" example:"0"`
+	OpenedAt *time.Time `json:"opened_at,omitempty" example:"2023-07-01T12:34:56Z"`
+}
+
+// Breaker is a circuit breaker for one upstream host. It opens after threshold
+// failed requests in a row, rejects requests for the cooldown, then half-opens
+// to let a probe through: a successful probe closes it, a failed one reopens it.
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probeAt  time.Time
+}
+
+// NewBreaker creates a closed circuit breaker
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     StateClosed,
+	}
+}
+
+// Allow reports whether a request may be sent. Once the cooldown has passed an
+// open breaker half-opens and allows one probe; a probe that never reports back
+// is replaced by another after a further cooldown.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case StateOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.probeAt = now
+		logrus.WithField("upstream", b.name).Info("Circuit breaker half-open, probing upstream")
+		return true
+	case StateHalfOpen:
+		if now.Sub(b.probeAt) < b.cooldown {
+			return false
+		}
+		b.probeAt = now
+		return true
+	default:
+		return true
+	}
+}
+
+// Success records a request the upstream answered, closing the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateClosed {
+		logrus.WithField("upstream", b.name).Info("Circuit breaker closed, upstream recovered")
+	}
+	b.state = StateClosed
+	b.failures = 0
+}
+
+// Failure records a failed request, opening the breaker at the threshold or
+// when a half-open probe fails
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == StateHalfOpen || (b.state == StateClosed && b.failures >= b.threshold) {
+		b.state = StateOpen
+		b.openedAt = b.now()
+		logrus.WithFields(logrus.Fields{
+			"upstream": b.name,
+			"failures": b.failures,
+			"cooldown": b.cooldown,
+		}).Warn("Circuit breaker opened, rejecting upstream requests")
+	}
+}
+
+// Status returns a snapshot of the breaker
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{State: b.state, Failures: b.failures}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := NewBreaker("media.example.com", 3, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	// Failures below the threshold, or interrupted by a success, keep it closed
+	breaker.Failure()
+	breaker.Failure()
+	breaker.Success()
+	breaker.Failure()
+	breaker.Failure()
+	assert.True(t, breaker.Allow())
+	assert.Equal(t, BreakerStatus{State: StateClosed, Failures: 2}, breaker.Status())
+
+	// The third failure in a row opens it until the cooldown has passed
+	breaker.Failure()
+	assert.False(t, breaker.Allow())
+	status := breaker.Status()
+	assert.Equal(t, StateOpen, status.State)
+	require.NotNil(t, status.OpenedAt)
+	assert.Equal(t, now, *status.OpenedAt)
+
+	// After the cooldown a single probe is let through
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow())
+	assert.False(t, breaker.Allow())
+	assert.Equal(t, StateHalfOpen, breaker.Status().State)
+
+	// A failed probe reopens it for another cooldown
+	breaker.Failure()
+	assert.False(t, breaker.Allow())
+	assert.Equal(t, StateOpen, breaker.Status().State)
+
+	// A probe that never reports back is replaced after a cooldown
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow())
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow())
+
+	// A successful probe closes it
+	breaker.Success()
+	assert.True(t, breaker.Allow())
+	assert.Equal(t, BreakerStatus{State: StateClosed}, breaker.Status())
+}
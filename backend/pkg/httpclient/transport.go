@@ -0,0 +1,275 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Defaults for options left at zero
+const (
+	DefaultMaxRetries       = 3
+	DefaultBaseDelay        = 200 * time.Millisecond
+	DefaultMaxDelay         = 5 * time.Second
+	DefaultMaxRetryAfter    = 30 * time.Second
+	DefaultAttemptTimeout   = 4 * time.Second
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+var (
+	// ErrCircuitOpen is returned without contacting the upstream while its breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker open")
+
+	// ErrAttemptTimeout is returned when an upstream sends no response headers in time
+	ErrAttemptTimeout = errors.New("upstream attempt timed out")
+)
+
+// Options tunes the retries and circuit breakers of a Transport. Zero values fall
+// back to the defaults, except for MaxRetries.
+type Options struct {
+	// MaxRetries is how many times a failed request is retried; zero disables
+	// retries and a negative value uses the default
+	MaxRetries     int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	MaxRetryAfter  time.Duration
+	AttemptTimeout time.Duration
+
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// Transport is an http.RoundTripper that retries transient upstream failures
+// with jittered exponential backoff and guards each host with a circuit breaker.
+//
+// Network errors, attempts without response headers within the attempt timeout
+// and 5xx responses are retried. A 429 waits for its Retry-After when that fits
+// within MaxRetryAfter and the request's deadline. Requests with a body are only
+// retried when it can be replayed through GetBody.
+type Transport struct {
+	base http.RoundTripper
+	opts Options
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewTransport wraps base, or http.DefaultTransport when it is nil
+func NewTransport(base http.RoundTripper, opts Options) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = DefaultBaseDelay
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = DefaultMaxDelay
+	}
+	if opts.MaxRetryAfter <= 0 {
+		opts.MaxRetryAfter = DefaultMaxRetryAfter
+	}
+	if opts.AttemptTimeout <= 0 {
+		opts.AttemptTimeout = DefaultAttemptTimeout
+	}
+	if opts.BreakerThreshold <= 0 {
+		opts.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = DefaultBreakerCooldown
+	}
+
+	return &Transport{
+		base:     base,
+		opts:     opts,
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// RoundTrip sends the request through the host's circuit breaker, retrying
+// transient failures. The final outcome is recorded on the breaker. Requests
+// cancelled by their caller are not held against the upstream, but those that
+// ran out of time, including through the client's timeout, are.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	breaker := t.breaker(req.URL.Host)
+	if !breaker.Allow() {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, req.URL.Host)
+	}
+
+	resp, err := t.roundTripWithRetries(req)
+	switch {
+	case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		breaker.Failure()
+	default:
+		breaker.Success()
+	}
+	return resp, err
+}
+
+// Breakers returns the state of the breaker of every host contacted so far
+func (t *Transport) Breakers() map[string]BreakerStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make(map[string]BreakerStatus, len(t.breakers))
+	for host, breaker := range t.breakers {
+		statuses[host] = breaker.Status()
+	}
+	return statuses
+}
+
+// breaker returns the breaker of host, creating it on first use
+func (t *Transport) breaker(host string) *Breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	breaker, ok := t.breakers[host]
+	if !ok {
+		breaker = NewBreaker(host, t.opts.BreakerThreshold, t.opts.BreakerCooldown)
+		t.breakers[host] = breaker
+	}
+	return breaker
+}
+
+// roundTripWithRetries sends req until it succeeds, fails permanently or runs
+// out of retries, returning the last response or error
+func (t *Transport) roundTripWithRetries(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retries := t.opts.MaxRetries
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := t.attempt(attemptReq)
+		if attempt >= retries || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if wait > t.opts.MaxRetryAfter {
+					return resp, nil
+				}
+				delay = wait
+			}
+		}
+		// Give the caller the failure rather than wait past its deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		fields := logrus.Fields{"url": req.URL.String(), "attempt": attempt + 1, "delay": delay}
+		if err != nil {
+			fields["error"] = err.Error()
+		} else {
+			fields["status"] = resp.StatusCode
+		}
+		logrus.WithFields(fields).Debug("Retrying upstream request")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt sends a single request, failing it with ErrAttemptTimeout when no
+// response headers arrive within the attempt timeout. The body of a response is
+// not subject to the timeout.
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(t.opts.AttemptTimeout, cancel)
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if !timer.Stop() && req.Context().Err() == nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("%w after %s", ErrAttemptTimeout, t.opts.AttemptTimeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns the jittered delay before retry number attempt+1: between half
+// and all of the exponentially growing delay, capped at MaxDelay
+func (t *Transport) backoff(attempt int) time.Duration {
+	delay := t.opts.BaseDelay << attempt
+	if delay <= 0 || delay > t.opts.MaxDelay {
+		delay = t.opts.MaxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryable reports whether the outcome of an attempt is worth retrying
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return ctx.Err() == nil && (errors.Is(err, ErrAttemptTimeout) || errors.As(err, &netErr) ||
+			errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF))
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// cancelOnClose releases the context of an attempt once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,243 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedUpstream answers each request with the next status of its script,
+// repeating the last one, and counts the requests
+type scriptedUpstream struct {
+	requests atomic.Int32
+	statuses []int
+	header   http.Header
+	// stalls is how many of the first requests are held until the client gives up on them
+	stalls int
+}
+
+func (u *scriptedUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := int(u.requests.Add(1))
+	if n <= u.stalls {
+		<-r.Context().Done()
+		return
+	}
+	for key, values := range u.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(u.statuses[min(n, len(u.statuses))-1])
+	w.Write([]byte("body"))
+}
+
+// fastOptions retries quickly so that tests do not wait on backoff
+var fastOptions = Options{MaxRetries: DefaultMaxRetries, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func get(t *testing.T, client *http.Client, target string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	if err == nil {
+		t.Cleanup(func() { resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func TestTransportRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		opts     Options
+		want     int
+		requests int32
+	}{
+		{"server errors are retried", []int{503, 502, 200}, fastOptions, 200, 3},
+		{"retries are limited", []int{500}, Options{MaxRetries: 2, BaseDelay: time.Millisecond}, 500, 3},
+		{"retries can be disabled", []int{503, 200}, Options{}, 503, 1},
+		{"negative retries use the default", []int{503, 503, 503, 200}, Options{MaxRetries: -1, BaseDelay: time.Millisecond}, 200, 4},
+		{"client errors are not retried", []int{404, 200}, fastOptions, 404, 1},
+		{"rate limits without Retry-After back off", []int{429, 200}, fastOptions, 200, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &scriptedUpstream{statuses: tt.statuses}
+			server := httptest.NewServer(upstream)
+			defer server.Close()
+			client := &http.Client{Transport: NewTransport(nil, tt.opts)}
+
+			resp, err := get(t, client, server.URL)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resp.StatusCode)
+			assert.Equal(t, tt.requests, upstream.requests.Load())
+		})
+	}
+}
+
+func TestTransportRetryAfter(t *testing.T) {
+	upstream := &scriptedUpstream{statuses: []int{429, 200}, header: http.Header{"Retry-After": {"1"}}}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+	client := &http.Client{Transport: NewTransport(nil, fastOptions)}
+
+	start := time.Now()
+	resp, err := get(t, client, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	// A wait beyond the cap, or past the caller's deadline, returns the 429 at once
+	upstream = &scriptedUpstream{statuses: []int{429, 200}, header: http.Header{"Retry-After": {"120"}}}
+	server = httptest.NewServer(upstream)
+	defer server.Close()
+	resp, err = get(t, client, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), upstream.requests.Load())
+
+	upstream.requests.Store(0)
+	client = &http.Client{Transport: NewTransport(nil, Options{MaxRetries: DefaultMaxRetries, MaxRetryAfter: time.Hour}), Timeout: 500 * time.Millisecond}
+	resp, err = get(t, client, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), upstream.requests.Load())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"Wed, 01 Jan 2025 00:00:10 GMT", 10 * time.Second, true},
+		{"Tue, 31 Dec 2024 23:59:00 GMT", 0, true},
+		{"soon", 0, false},
+		{"-1", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value, now)
+		assert.Equal(t, tt.ok, ok, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+}
+
+func TestTransportAttemptTimeout(t *testing.T) {
+	upstream := &scriptedUpstream{statuses: []int{200}, stalls: 1}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	opts := fastOptions
+	opts.AttemptTimeout = 50 * time.Millisecond
+	client := &http.Client{Transport: NewTransport(nil, opts)}
+
+	resp, err := get(t, client, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), upstream.requests.Load())
+}
+
+func TestTransportReplaysBody(t *testing.T) {
+	var bodies []string
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, 16)
+		n, _ := r.Body.Read(body)
+		bodies = append(bodies, string(body[:n]))
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewTransport(nil, fastOptions)}
+
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"payload", "payload"}, bodies)
+}
+
+func TestTransportCircuitBreaker(t *testing.T) {
+	upstream := &scriptedUpstream{statuses: []int{500}}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	opts := Options{MaxRetries: 1, BaseDelay: time.Millisecond, BreakerThreshold: 2, BreakerCooldown: 100 * time.Millisecond}
+	transport := NewTransport(nil, opts)
+	client := &http.Client{Transport: transport}
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// Two failed requests in a row open the circuit
+	for range 2 {
+		resp, err := get(t, client, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	}
+	assert.Equal(t, int32(4), upstream.requests.Load())
+	assert.Equal(t, StateOpen, transport.Breakers()[host].State)
+
+	// Requests are rejected without reaching the upstream
+	_, err := get(t, client, server.URL)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	var urlErr *url.Error
+	assert.True(t, errors.As(err, &urlErr))
+	assert.Equal(t, int32(4), upstream.requests.Load())
+
+	// Once the cooldown has passed, a successful probe closes it
+	upstream.statuses = []int{200}
+	time.Sleep(opts.BreakerCooldown)
+	resp, err := get(t, client, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, BreakerStatus{State: StateClosed}, transport.Breakers()[host])
+}
+
+func TestTransportHangingUpstreamOpensBreaker(t *testing.T) {
+	upstream := &scriptedUpstream{statuses: []int{200}, stalls: 100}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	opts := fastOptions
+	opts.AttemptTimeout = 30 * time.Millisecond
+	opts.BreakerThreshold = 2
+	transport := NewTransport(nil, opts)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// Requests ending on the attempt timeout or on the client's timeout both count
+	for _, timeout := range []time.Duration{time.Second, 50 * time.Millisecond} {
+		client := &http.Client{Transport: transport, Timeout: timeout}
+		_, err := get(t, client, server.URL)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, StateOpen, transport.Breakers()[host].State)
+	assert.Equal(t, 2, transport.Breakers()[host].Failures)
+}
+
+func TestTransportIgnoresCallerCancellation(t *testing.T) {
+	upstream := &scriptedUpstream{statuses: []int{200}, stalls: 1}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	transport := NewTransport(nil, Options{BreakerThreshold: 1})
+	client := &http.Client{Transport: transport}
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), upstream.requests.Load())
+	assert.Equal(t, StateClosed, transport.Breakers()[strings.TrimPrefix(server.URL, "http://")].State)
+}
@@ -1,11 +1,13 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,6 +17,22 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// Defaults for settings missing from the cache configuration
+const (
+	// DefaultPath is the directory map images are cached in
+	DefaultPath           = "/home/appuser/images-cache"
+	defaultWorkers        = 3
+	defaultQueueSize      = 10
+	defaultPrewarmTimeout = 60 * time.Second
+)
+
+var (
+	// ErrInvalidName is returned when a requested file name is not a plain file name
+	ErrInvalidName = errors.New("invalid cache file name")
+	// ErrNotCached is returned when a requested file is not in the cache
+	ErrNotCached = errors.New("file not cached")
+)
+
 // Variable to allow mocking of os.MkdirAll
 var osMkdirAll = os.MkdirAll
 
@@ -34,58 +52,148 @@ type ImageCache interface {
 	// CacheMapImages processes maps to replace image URLs with cached versions
 	CacheMapImages(ctx ctx.CTX, maps []domain.Map) ([]domain.Map, error)
 
+	// Resolve returns the cached file served under the given name
+	Resolve(name string) (*CachedFile, error)
+
+	// Variant returns a resized or converted copy of the cached file served under the given name
+	Variant(name string, opts VariantOptions) (*CachedFile, error)
+
+	// Metrics returns the size, limits and hit and eviction counters of the cache
+	Metrics() Metrics
+
+	// Images lists the cached images and when they were last revalidated
+	Images() []ImageInfo
+
 	// Shutdown gracefully shuts down the cache service
 	Shutdown()
 }
 
+// CachedFile is an image stored in the cache directory
+type CachedFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	// RevalidatedAt is when the image was last checked against upstream, if known
+	RevalidatedAt time.Time
+}
+
+// Metrics describes the contents of the image cache and its activity since startup
+type Metrics struct {
+	Entries      int    `json:"entries" example:"24"`
+	Bytes        int64  `json:"bytes" example:"31457280"`
+	MaxEntries   int    `json:"maxEntries" example:"0"`
+	MaxBytes     int64  `json:"maxBytes" example:"536870912"`
+	Hits         uint64 `json:"hits" example:"1200"`
+	Misses       uint64 `json:"misses" example:"24"`
+	Evictions    uint64 `json:"evictions" example:"0"`
+	EvictedBytes int64  `json:"evictedBytes" example:"0"`
+	Quarantined  uint64 `json:"quarantined" example:"0"`
+}
+
 type imageCache struct {
-	cachePath     string
-	client        HTTPClient
-	mutex         sync.RWMutex
-	cachedImages  map[string]string
+	cachePath      string
+	prewarmTimeout time.Duration
+	variantWidths  []int
+	variantHeights []int
+	client         HTTPClient
+	// mutex guards index, aliases and the counters below
+	mutex sync.RWMutex
+	index *lruIndex
+	// aliases maps the file names of replaced images to their cache key, so that
+	// URLs handed out before a replacement keep resolving
+	aliases      map[string]string
+	hits         uint64
+	misses       uint64
+	evictions    uint64
+	evictedBytes int64
+	quarantined  uint64
+	// downloads shares one download between concurrent misses for a key
+	downloads flightGroup
+	// variants shares one rendering between concurrent requests for a variant
+	variants      flightGroup
 	downloadQueue chan downloadTask
-	wg            sync.WaitGroup
-	quit          chan struct{}
+	// queueMutex keeps Shutdown from closing downloadQueue during a send
+	queueMutex sync.RWMutex
+	wg         sync.WaitGroup
+	// quit is closed by Shutdown to stop workers and cancel in-flight downloads
+	quit     chan struct{}
+	shutdown sync.Once
 }
 
 type downloadTask struct {
 	URL      string
 	CacheKey string
 	Ctx      ctx.CTX
+	// group tracks the task for the prewarm that queued it, if any
+	group *sync.WaitGroup
+}
+
+// finish marks a task taken off the queue as done
+func (c *imageCache) finish(task downloadTask) {
+	if task.group != nil {
+		task.group.Done()
+	}
+	c.wg.Done()
 }
 
-// NewImageCache creates a new image cache service
+// NewImageCache creates a new image cache service. Settings missing from
+// cfg.Cache, or a nil cfg, fall back to the defaults.
 func NewImageCache(cfg *config.Config, client *http.Client) (ImageCache, error) {
-	// Default cache path is ./images-cache relative to the server
-	cachePath := "/home/appuser/images-cache"
-
-	// If testing, use a temporary directory
-	if cfg != nil && cfg.Server.Port == "test" {
-		var err error
-		cachePath, err = os.MkdirTemp("", "image-cache-test")
-		if err != nil {
-			return nil, fmt.Errorf("failed to create temp directory: %w", err)
-		}
+	var settings config.CacheConfig
+	if cfg != nil {
+		settings = cfg.Cache
+	}
+	if settings.Directory == "" {
+		settings.Directory = DefaultPath
+	}
+	if settings.Workers <= 0 {
+		settings.Workers = defaultWorkers
+	}
+	if settings.QueueSize < 0 {
+		settings.QueueSize = defaultQueueSize
+	}
+	if settings.PrewarmTimeout <= 0 {
+		settings.PrewarmTimeout = defaultPrewarmTimeout
+	}
+	if settings.VariantWidths == nil {
+		settings.VariantWidths = defaultVariantWidths
+	}
+	if settings.VariantHeights == nil {
+		settings.VariantHeights = defaultVariantHeights
 	}
 
 	// Create cache directory if it doesn't exist
-	if err := osMkdirAll(cachePath, 0755); err != nil {
+	if err := osMkdirAll(settings.Directory, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
 	cache := &imageCache{
-		cachePath:     cachePath,
-		client:        client,
-		cachedImages:  make(map[string]string),
-		downloadQueue: make(chan downloadTask, 10),
-		quit:          make(chan struct{}),
+		cachePath:      settings.Directory,
+		prewarmTimeout: settings.PrewarmTimeout,
+		variantWidths:  settings.VariantWidths,
+		variantHeights: settings.VariantHeights,
+		client:         client,
+		index:          newLRUIndex(settings.MaxSize, settings.MaxEntries),
+		aliases:        make(map[string]string),
+		downloadQueue:  make(chan downloadTask, settings.QueueSize),
+		quit:           make(chan struct{}),
+	}
+
+	// Rebuild the index from the images already on disk
+	if err := cache.scan(); err != nil {
+		return nil, err
 	}
 
 	// Start worker goroutines to handle downloads in the background
-	for i := 0; i < 3; i++ {
+	for i := 0; i < settings.Workers; i++ {
 		go cache.downloadWorker()
 	}
 
+	// Periodically check cached images against upstream
+	if settings.RevalidateInterval > 0 {
+		go cache.revalidateLoop(settings.RevalidateInterval)
+	}
+
 	return cache, nil
 }
 
@@ -97,46 +205,124 @@ func (c *imageCache) downloadWorker() {
 			if !ok {
 				return
 			}
-			c.downloadImage(task.Ctx, task.URL, task.CacheKey)
-			c.wg.Done()
+			c.runTask(task)
+			c.finish(task)
 		case <-c.quit:
 			return
 		}
 	}
 }
 
-// GetOrDownloadImage gets an image from the cache or downloads it if not found
+// runTask downloads a queued image unless its context is done or the cache is
+// shutting down, in which case the task is dropped
+func (c *imageCache) runTask(task downloadTask) {
+	if err := task.Ctx.StdContext().Err(); err != nil {
+		task.Ctx.FieldLogger.WithFields(logrus.Fields{
+			"cache_key": task.CacheKey,
+			"reason":    err.Error(),
+		}).Debug("Dropped queued image download")
+		return
+	}
+	select {
+	case <-c.quit:
+		return
+	default:
+	}
+	c.fetch(task.Ctx, task.URL, task.CacheKey)
+}
+
+// GetOrDownloadImage gets an image from the cache or downloads it if not found.
+// A cached image that fails its integrity check is quarantined and downloaded again.
 func (c *imageCache) GetOrDownloadImage(ctx ctx.CTX, imageURL, cacheKey string) (string, error) {
 	// Check if the image is already in memory cache
-	c.mutex.RLock()
-	cachedPath, exists := c.cachedImages[cacheKey]
-	c.mutex.RUnlock()
-
+	c.mutex.Lock()
+	entry, exists := c.index.touch(cacheKey)
 	if exists {
-		ctx.FieldLogger.WithFields(logrus.Fields{
-			"cache_key": cacheKey,
-			"path":      cachedPath,
-		}).Debug("Image found in memory cache")
-		return cachedPath, nil
+		c.hits++
+	} else {
+		c.misses++
 	}
+	c.mutex.Unlock()
 
-	// Check if the image is in the file system cache
-	filePath := filepath.Join(c.cachePath, cacheKey+filepath.Ext(imageURL))
-	if _, err := os.Stat(filePath); err == nil {
-		// Image exists in filesystem, add to memory cache
-		c.mutex.Lock()
-		c.cachedImages[cacheKey] = filePath
-		c.mutex.Unlock()
+	if !exists {
+		// Check if the image is in the file system cache
+		if entry, exists = c.loadFromDisk(cacheKey, imageURL); exists {
+			c.store(entry.key, entry.path, entry.size, entry.verified)
+		}
+	}
 
-		ctx.FieldLogger.WithFields(logrus.Fields{
-			"cache_key": cacheKey,
-			"path":      filePath,
-		}).Debug("Image found in filesystem cache")
-		return filePath, nil
+	if exists {
+		_, err := c.check(entry)
+		if err == nil {
+			ctx.FieldLogger.WithFields(logrus.Fields{
+				"cache_key": cacheKey,
+				"path":      entry.path,
+			}).Debug("Image found in cache")
+			return entry.path, nil
+		}
+
+		// Other keys with the same content are downloaded again in the background
+		keys := c.quarantine(ctx, entry.path, err)
+		others := keys[:0]
+		for _, key := range keys {
+			if key != cacheKey {
+				others = append(others, key)
+			}
+		}
+		c.redownload(ctx, others)
 	}
 
 	// Image not found in cache, download it synchronously
-	return c.downloadImage(ctx, imageURL, cacheKey)
+	return c.fetch(ctx, imageURL, cacheKey)
+}
+
+// fetch downloads an image into the cache. Concurrent callers for the same key
+// wait on a single download and share its result.
+func (c *imageCache) fetch(ctx ctx.CTX, imageURL, cacheKey string) (string, error) {
+	for {
+		path, err, shared := c.downloads.do(ctx.StdContext(), cacheKey, func() (string, error) {
+			// A download that finished since the caller missed the index is reused
+			c.mutex.RLock()
+			entry, ok := c.index.peek(cacheKey)
+			c.mutex.RUnlock()
+			if ok {
+				return entry.path, nil
+			}
+			return c.downloadImage(ctx, imageURL, cacheKey)
+		})
+		if !shared {
+			return path, err
+		}
+
+		// A shared download cancelled by the caller that started it is retried
+		// while this caller's context is still live
+		if isCancellation(err) && ctx.StdContext().Err() == nil {
+			continue
+		}
+		ctx.FieldLogger.WithField("cache_key", cacheKey).Debug("Joined in-flight image download")
+		return path, err
+	}
+}
+
+// downloadContext derives the context of a download from the caller's, cancelling
+// it when the cache shuts down
+func (c *imageCache) downloadContext(ctx ctx.CTX) (context.Context, context.CancelFunc) {
+	downloadCtx, cancel := context.WithCancel(ctx.StdContext())
+	if c.quit != nil {
+		go func() {
+			select {
+			case <-c.quit:
+				cancel()
+			case <-downloadCtx.Done():
+			}
+		}()
+	}
+	return downloadCtx, cancel
+}
+
+// isCancellation reports whether err comes from a cancelled or expired context
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
 
 // downloadImage downloads an image and stores it in the cache
@@ -146,8 +332,12 @@ func (c *imageCache) downloadImage(ctx ctx.CTX, imageURL, cacheKey string) (stri
 		"cache_key": cacheKey,
 	}).Info("Downloading image")
 
+	// The download ends with the caller's context or when the cache shuts down
+	downloadCtx, cancel := c.downloadContext(ctx)
+	defer cancel()
+
 	// Create HTTP request
-	req, err := http.NewRequest(http.MethodGet, imageURL, nil)
+	req, err := http.NewRequestWithContext(downloadCtx, http.MethodGet, imageURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -187,26 +377,17 @@ func (c *imageCache) downloadImage(ctx ctx.CTX, imageURL, cacheKey string) (stri
 		}
 	}
 
-	// Create output file path
-	filePath := filepath.Join(c.cachePath, cacheKey+extension)
-
-	// Create output file
-	outFile, err := os.Create(filePath)
+	// Write to a temporary file named after the content hash once complete
+	filePath, size, err := writeBlob(c.cachePath, resp.Body, extension)
 	if err != nil {
-		return "", fmt.Errorf("failed to create output file: %w", err)
-	}
-	defer outFile.Close()
-
-	// Copy response body to file
-	if _, err := io.Copy(outFile, resp.Body); err != nil {
-		os.Remove(filePath) // Clean up partial file
 		return "", fmt.Errorf("failed to save image: %w", err)
 	}
 
-	// Store in memory cache
-	c.mutex.Lock()
-	c.cachedImages[cacheKey] = filePath
-	c.mutex.Unlock()
+	// Record the file and the validators needed to revalidate it later, then index it
+	if err := writeMeta(c.cachePath, cacheKey, newMeta(filePath, imageURL, resp)); err != nil {
+		ctx.FieldLogger.WithError(err).WithField("cache_key", cacheKey).Warn("Failed to save image metadata")
+	}
+	c.store(cacheKey, filePath, size, true)
 
 	ctx.FieldLogger.WithFields(logrus.Fields{
 		"url":       imageURL,
@@ -217,44 +398,51 @@ func (c *imageCache) downloadImage(ctx ctx.CTX, imageURL, cacheKey string) (stri
 	return filePath, nil
 }
 
-// PrewarmCache downloads and caches all images in the provided URL map
+// PrewarmCache downloads and caches all images in the provided URL map. It gives
+// up at the prewarm deadline or when ctx is done, cancelling the downloads still
+// in flight and dropping those still queued.
 func (c *imageCache) PrewarmCache(ctx ctx.CTX, urlMap map[string]string) error {
 	ctx.FieldLogger.WithField("image_count", len(urlMap)).Info("Prewarming image cache")
 
 	startTime := time.Now()
+	timeout := c.prewarmTimeout
+	if timeout <= 0 {
+		timeout = defaultPrewarmTimeout
+	}
 
-	// Queue all downloads
+	// The prewarm deadline applies on top of any deadline of the caller
+	deadlineCtx, cancel := context.WithTimeout(ctx.StdContext(), timeout)
+	defer cancel()
+	prewarmCtx := ctx
+	prewarmCtx.Context = deadlineCtx
+
+	// Queue all downloads, tracking them apart from other queued work
+	var group sync.WaitGroup
 	for cacheKey, url := range urlMap {
 		// Check if already cached to avoid unnecessary downloads
-		c.mutex.RLock()
-		_, exists := c.cachedImages[cacheKey]
-		c.mutex.RUnlock()
+		c.mutex.Lock()
+		exists := c.index.contains(cacheKey)
+		c.mutex.Unlock()
 
 		if !exists {
 			// Check if image exists in filesystem
-			filePath := filepath.Join(c.cachePath, cacheKey+filepath.Ext(url))
-			if _, err := os.Stat(filePath); err == nil {
-				// Image exists in filesystem, add to memory cache
-				c.mutex.Lock()
-				c.cachedImages[cacheKey] = filePath
-				c.mutex.Unlock()
+			if entry, ok := c.loadFromDisk(cacheKey, url); ok {
+				c.store(entry.key, entry.path, entry.size, entry.verified)
 				continue
 			}
 
-			// Queue the download task
-			c.wg.Add(1)
-			c.downloadQueue <- downloadTask{
-				URL:      url,
-				CacheKey: cacheKey,
-				Ctx:      ctx,
+			// Queue the download task, waiting for room in the queue
+			task := downloadTask{URL: url, CacheKey: cacheKey, Ctx: prewarmCtx, group: &group}
+			if !c.enqueue(task, true) {
+				break
 			}
 		}
 	}
 
-	// Wait for all downloads to complete with a timeout
+	// Wait for all downloads to complete within the deadline
 	done := make(chan struct{})
 	go func() {
-		c.wg.Wait()
+		group.Wait()
 		close(done)
 	}()
 
@@ -266,9 +454,80 @@ func (c *imageCache) PrewarmCache(ctx ctx.CTX, urlMap map[string]string) error {
 			"image_count": len(urlMap),
 		}).Info("Cache prewarm completed")
 		return nil
-	case <-time.After(60 * time.Second):
-		ctx.FieldLogger.Warn("Cache prewarm timed out after 60 seconds")
-		return fmt.Errorf("cache prewarm timed out after 60 seconds")
+	case <-deadlineCtx.Done():
+		if ctx.StdContext().Err() == nil && errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+			ctx.FieldLogger.WithField("timeout", timeout).Warn("Cache prewarm timed out")
+			return fmt.Errorf("cache prewarm timed out after %s", timeout)
+		}
+		ctx.FieldLogger.WithError(deadlineCtx.Err()).Warn("Cache prewarm cancelled")
+		return fmt.Errorf("cache prewarm cancelled: %w", deadlineCtx.Err())
+	}
+}
+
+// Resolve returns the cached file served under name, which must be a plain
+// file name. Names are content-addressed files or, from earlier versions, a
+// cache key with an extension. The name of a replaced file resolves to the
+// image now cached under its key. An evicted image, and a file that fails its
+// integrity check, which is quarantined, are queued for download and reported
+// as not cached.
+func (c *imageCache) Resolve(name string) (*CachedFile, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	// Serving an image counts as a use for eviction
+	c.mutex.Lock()
+	var entry cacheEntry
+	var ok bool
+	key := strings.TrimSuffix(name, filepath.Ext(name))
+	if blobName.MatchString(name) {
+		for _, key := range c.index.keysAt(filepath.Join(c.cachePath, name)) {
+			entry, ok = c.index.touch(key)
+		}
+		key = c.aliases[name]
+		if !ok && key != "" {
+			entry, ok = c.index.touch(key)
+		}
+	} else {
+		entry, ok = c.index.touch(key)
+	}
+	c.mutex.Unlock()
+	if !ok {
+		if key != "" {
+			c.redownload(ctx.Background(), []string{key})
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNotCached, name)
+	}
+
+	info, err := c.check(entry)
+	if err != nil {
+		background := ctx.Background()
+		c.redownload(background, c.quarantine(background, entry.path, err))
+		return nil, fmt.Errorf("%w: %s", ErrNotCached, name)
+	}
+
+	file := &CachedFile{Path: entry.path, Size: info.Size(), ModTime: info.ModTime()}
+	if meta, err := readMeta(c.cachePath, entry.key); err == nil && meta != nil {
+		file.RevalidatedAt = meta.RevalidatedAt
+	}
+	return file, nil
+}
+
+// Metrics returns the size, limits and hit and eviction counters of the cache
+func (c *imageCache) Metrics() Metrics {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return Metrics{
+		Entries:      c.index.len(),
+		Bytes:        c.index.bytes,
+		MaxEntries:   c.index.maxEntries,
+		MaxBytes:     c.index.maxBytes,
+		Hits:         c.hits,
+		Misses:       c.misses,
+		Evictions:    c.evictions,
+		EvictedBytes: c.evictedBytes,
+		Quarantined:  c.quarantined,
 	}
 }
 
@@ -296,6 +555,7 @@ func (c *imageCache) CacheMapImages(ctx ctx.CTX, maps []domain.Map) ([]domain.Ma
 			cachedPath, err := c.GetOrDownloadImage(ctx, mapPtr.Splash, splashKey)
 			if err == nil {
 				mapPtr.Splash = "/api/cache/" + filepath.Base(cachedPath)
+				mapPtr.SplashSrcSet = c.srcSet(cachedPath)
 			} else {
 				ctx.FieldLogger.WithFields(logrus.Fields{
 					"url":   mapPtr.Splash,
@@ -310,6 +570,7 @@ func (c *imageCache) CacheMapImages(ctx ctx.CTX, maps []domain.Map) ([]domain.Ma
 			cachedPath, err := c.GetOrDownloadImage(ctx, mapPtr.DisplayIcon, iconKey)
 			if err == nil {
 				mapPtr.DisplayIcon = "/api/cache/" + filepath.Base(cachedPath)
+				mapPtr.DisplayIconSrcSet = c.srcSet(cachedPath)
 			} else {
 				ctx.FieldLogger.WithFields(logrus.Fields{
 					"url":   mapPtr.DisplayIcon,
@@ -324,39 +585,31 @@ func (c *imageCache) CacheMapImages(ctx ctx.CTX, maps []domain.Map) ([]domain.Ma
 	return maps, nil
 }
 
-// Shutdown gracefully shuts down the cache service
+// Shutdown stops the download workers, cancelling in-flight downloads and
+// dropping queued ones, and waits for them to finish. It is safe to call more
+// than once.
 func (c *imageCache) Shutdown() {
-	// Use mutex to ensure thread safety during shutdown
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	// Check if quit channel is initialized and not already closed
-	if c.quit != nil {
-		select {
-		case <-c.quit:
-			// Already closed, do nothing
-		default:
-			// Signal all workers to stop
+	c.shutdown.Do(func() {
+		// Signal workers, senders and in-flight downloads to stop
+		if c.quit != nil {
 			close(c.quit)
 		}
-	}
 
-	// Check if download queue is initialized and not already closed
-	if c.downloadQueue != nil {
-		select {
-		case <-c.downloadQueue:
-			// Already closed or has messages, try to close in defer with recover
-			defer func() {
-				// Recover in case channel is already closed by another goroutine
-				recover()
-			}()
-			close(c.downloadQueue)
-		default:
-			// Close the download queue to prevent new tasks
+		// Senders waiting for room give up on quit, releasing the lock
+		c.queueMutex.Lock()
+		if c.downloadQueue != nil {
 			close(c.downloadQueue)
 		}
+		c.queueMutex.Unlock()
+	})
+
+	// Drop the tasks no worker will pick up
+	if c.downloadQueue != nil {
+		for task := range c.downloadQueue {
+			c.finish(task)
+		}
 	}
 
-	// Wait for all pending downloads to complete
+	// Wait for the downloads that were running to be cancelled
 	c.wg.Wait()
 }
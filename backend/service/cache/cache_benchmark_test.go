@@ -1,15 +1,22 @@
 package cache
 
 import (
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	domain "github.com/jungtechou/valomap/domain/map"
 	"github.com/jungtechou/valomap/pkg/ctx"
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Setup benchmark-specific context
@@ -36,7 +43,7 @@ func createBenchTestCache(b *testing.B) (ImageCache, string, *MockHTTPClient) {
 	cache := &imageCache{
 		cachePath:     tmpDir,
 		client:        mockClient,
-		cachedImages:  make(map[string]string),
+		index:         newLRUIndex(0, 0),
 		downloadQueue: make(chan downloadTask, 10),
 		mutex:         sync.RWMutex{},
 	}
@@ -99,7 +106,7 @@ func BenchmarkGetOrDownloadImage_CachedOnDisk(b *testing.B) {
 	// Clear memory cache but keep file on disk
 	imageCacheImpl := cache.(*imageCache)
 	imageCacheImpl.mutex.Lock()
-	imageCacheImpl.cachedImages = make(map[string]string)
+	imageCacheImpl.index = newLRUIndex(0, 0)
 	imageCacheImpl.mutex.Unlock()
 
 	// Benchmark disk cache retrieval
@@ -130,6 +137,92 @@ func BenchmarkGetOrDownloadImage_Download(b *testing.B) {
 	}
 }
 
+// countingUpstream serves a fixed image and counts the requests it receives,
+// holding each response until release is closed
+type countingUpstream struct {
+	requests atomic.Int64
+	release  chan struct{}
+}
+
+func (u *countingUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.requests.Add(1)
+	<-u.release
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Write([]byte("test image data"))
+}
+
+func TestGetOrDownloadImage_ConcurrentMisses(t *testing.T) {
+	upstream := &countingUpstream{release: make(chan struct{})}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	cache, tmpDir, _ := createTestCache(t)
+	defer os.RemoveAll(tmpDir)
+	cache.(*imageCache).client = server.Client()
+
+	const callers = 10
+	paths := make([]string, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paths[i], errs[i] = cache.GetOrDownloadImage(setupTestContext(), server.URL+"/splash.jpg", "map_1_splash")
+		}()
+	}
+
+	// Hold the first download until every caller has missed the cache
+	assert.Eventually(t, func() bool {
+		return cache.Metrics().Misses == callers
+	}, time.Second, time.Millisecond)
+	close(upstream.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), upstream.requests.Load(), "Concurrent misses should share one download")
+	for i := range callers {
+		require.NoError(t, errs[i])
+		assert.Equal(t, paths[0], paths[i])
+	}
+	assert.Equal(t, 1, cache.Metrics().Entries)
+}
+
+func BenchmarkGetOrDownloadImage_ConcurrentMisses(b *testing.B) {
+	upstream := &countingUpstream{release: make(chan struct{})}
+	close(upstream.release)
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	cache, tmpDir, _ := createBenchTestCache(b)
+	defer os.RemoveAll(tmpDir)
+	defer cache.Shutdown()
+	cache.(*imageCache).client = server.Client()
+
+	testCtx := setupBenchContext()
+	const callers = 10
+
+	// Each iteration misses a new key from several goroutines at once
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("map_%d_splash", i)
+		var wg sync.WaitGroup
+		for range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cache.GetOrDownloadImage(testCtx, server.URL+"/splash.jpg", key)
+			}()
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(upstream.requests.Load())/float64(b.N), "fetches/op")
+	if fetches := upstream.requests.Load(); fetches != int64(b.N) {
+		b.Fatalf("Expected %d upstream fetches, got %d", b.N, fetches)
+	}
+}
+
 func BenchmarkCacheMapImages(b *testing.B) {
 	cache, tmpDir, mockClient := createBenchTestCache(b)
 	defer os.RemoveAll(tmpDir)
@@ -95,7 +95,7 @@ func TestCacheMapImages_Coverage(t *testing.T) {
 	cache := &imageCache{
 		cachePath:     tempDir,
 		client:        mockClient,
-		cachedImages:  make(map[string]string),
+		index:         newLRUIndex(0, 0),
 		downloadQueue: make(chan downloadTask, 10),
 		quit:          make(chan struct{}),
 	}
@@ -229,9 +229,9 @@ func TestDownloadImage_Coverage(t *testing.T) {
 
 	// Create image cache
 	cache := &imageCache{
-		cachePath:    tempDir,
-		client:       mockClient,
-		cachedImages: make(map[string]string),
+		cachePath: tempDir,
+		client:    mockClient,
+		index:     newLRUIndex(0, 0),
 	}
 
 	// Test with different image types
@@ -278,10 +278,10 @@ func TestNewImageCache_Coverage(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, cache)
 
-	// Test with test port
+	// Test with a configured directory
 	cfg := &config.Config{
-		Server: config.ServerConfig{
-			Port: "test",
+		Cache: config.CacheConfig{
+			Directory: t.TempDir(),
 		},
 	}
 	cache, err = NewImageCache(cfg, &http.Client{})
@@ -332,7 +332,7 @@ func TestPrewarmCache_Coverage(t *testing.T) {
 	cache := &imageCache{
 		cachePath:     tempDir,
 		client:        mockClient,
-		cachedImages:  make(map[string]string),
+		index:         newLRUIndex(0, 0),
 		downloadQueue: make(chan downloadTask, 10),
 		quit:          make(chan struct{}),
 	}
@@ -364,9 +364,9 @@ func TestPrewarmCache_Coverage(t *testing.T) {
 
 	// Verify images were cached
 	cache.mutex.RLock()
-	assert.Contains(t, cache.cachedImages, "image1")
-	assert.Contains(t, cache.cachedImages, "image2")
-	assert.Contains(t, cache.cachedImages, "existing")
+	assert.True(t, cache.index.contains("image1"))
+	assert.True(t, cache.index.contains("image2"))
+	assert.True(t, cache.index.contains("existing"))
 	cache.mutex.RUnlock()
 
 	// Shutdown cache
@@ -8,9 +8,12 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -58,7 +61,7 @@ func createTestCache(t *testing.T) (ImageCache, string, *MockHTTPClient) {
 	cache := &imageCache{
 		cachePath:     tmpDir,
 		client:        mockClient, // This works because MockHTTPClient implements the Do method as in http.Client
-		cachedImages:  make(map[string]string),
+		index:         newLRUIndex(0, 0),
 		downloadQueue: make(chan downloadTask, 10),
 		quit:          make(chan struct{}), // Add quit channel for clean shutdown
 	}
@@ -171,7 +174,7 @@ func TestCacheMapImages(t *testing.T) {
 	assert.Contains(t, processedMaps[1].Splash, "/api/cache/", "Splash URL should be replaced with cached version")
 
 	// Verify that files were actually downloaded
-	assert.True(t, len(cache.(*imageCache).cachedImages) > 0, "Cache should contain images")
+	assert.True(t, cache.(*imageCache).index.len() > 0, "Cache should contain images")
 }
 
 func TestDownloadImageErrors(t *testing.T) {
@@ -231,7 +234,7 @@ func TestPrewarmCache(t *testing.T) {
 	cache := &imageCache{
 		cachePath:     tempDir,
 		client:        mockClient,
-		cachedImages:  make(map[string]string),
+		index:         newLRUIndex(0, 0),
 		downloadQueue: make(chan downloadTask, 10),
 		quit:          make(chan struct{}),
 	}
@@ -258,9 +261,9 @@ func TestPrewarmCache(t *testing.T) {
 
 	// Verify images were cached
 	cache.mutex.RLock()
-	assert.Equal(t, 2, len(cache.cachedImages))
-	assert.Contains(t, cache.cachedImages, "image1")
-	assert.Contains(t, cache.cachedImages, "image2")
+	assert.Equal(t, 2, cache.index.len())
+	assert.True(t, cache.index.contains("image1"))
+	assert.True(t, cache.index.contains("image2"))
 	cache.mutex.RUnlock()
 
 	// Test GetOrDownloadImage for already cached images
@@ -279,10 +282,12 @@ func TestNewImageCache(t *testing.T) {
 	// Create a mock HTTP client
 	mockClient := &http.Client{}
 
-	// Create a mock config with test port to use temp directory
+	// Create a config that caches into a temp directory
 	cfg := &config.Config{
-		Server: config.ServerConfig{
-			Port: "test",
+		Cache: config.CacheConfig{
+			Directory:      t.TempDir(),
+			Workers:        2,
+			PrewarmTimeout: time.Second,
 		},
 	}
 
@@ -292,6 +297,9 @@ func TestNewImageCache(t *testing.T) {
 	// Assertions
 	assert.NoError(t, err, "NewImageCache should not return an error")
 	assert.NotNil(t, cache, "Cache should not be nil")
+	assert.Equal(t, cfg.Cache.Directory, cache.(*imageCache).cachePath)
+	assert.Equal(t, time.Second, cache.(*imageCache).prewarmTimeout)
+	cache.Shutdown()
 
 	// Test with a non-existent directory by patching os.MkdirAll
 	// We need to use a test double approach here
@@ -335,7 +343,7 @@ func TestGetOrDownloadImage_FileSystemCacheHit(t *testing.T) {
 	cache := &imageCache{
 		cachePath:     tmpDir,
 		client:        mockClient,
-		cachedImages:  make(map[string]string),
+		index:         newLRUIndex(0, 0),
 		downloadQueue: make(chan downloadTask, 10),
 	}
 	defer cache.Shutdown()
@@ -348,15 +356,19 @@ func TestGetOrDownloadImage_FileSystemCacheHit(t *testing.T) {
 
 	// Assertions
 	assert.NoError(t, err, "GetOrDownloadImage should not error")
-	assert.Equal(t, filePath, resultPath, "Should return the cached file path")
+	assert.True(t, blobName.MatchString(filepath.Base(resultPath)), "File should be stored under its content hash")
+	assert.NoFileExists(t, filePath, "File cached under its key should be adopted")
+	fileData, err := os.ReadFile(resultPath)
+	assert.NoError(t, err, "Reading file should not error")
+	assert.Equal(t, testImageData, fileData, "File content should be preserved")
 
 	// Check that the image was added to memory cache
-	cache.mutex.RLock()
-	memCachedPath, exists := cache.cachedImages[testKey]
-	cache.mutex.RUnlock()
+	cache.mutex.Lock()
+	memCached, exists := cache.index.touch(testKey)
+	cache.mutex.Unlock()
 
 	assert.True(t, exists, "Image should be added to memory cache")
-	assert.Equal(t, filePath, memCachedPath, "Cached path should match file path")
+	assert.Equal(t, resultPath, memCached.path, "Cached path should match file path")
 
 	// No HTTP calls should have been made
 	mockClient.AssertNotCalled(t, "Do")
@@ -496,7 +508,7 @@ func TestShutdown(t *testing.T) {
 	cache := &imageCache{
 		cachePath:     tempDir,
 		client:        &http.Client{},
-		cachedImages:  make(map[string]string),
+		index:         newLRUIndex(0, 0),
 		downloadQueue: make(chan downloadTask, 10),
 		quit:          make(chan struct{}),
 	}
@@ -520,3 +532,194 @@ func TestShutdown(t *testing.T) {
 	// This should panic since the channel is closed
 	cache.downloadQueue <- downloadTask{}
 }
+
+func TestResolve(t *testing.T) {
+	tmpDir := t.TempDir()
+	path, size, err := writeBlob(tmpDir, strings.NewReader("image"), ".png")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "unindexed.png"), []byte("image"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "nested"), 0755))
+	cache := &imageCache{cachePath: tmpDir, index: newLRUIndex(0, 0)}
+	cache.store("map_1_splash", path, size, false)
+
+	file, err := cache.Resolve(filepath.Base(path))
+	require.NoError(t, err)
+	assert.Equal(t, path, file.Path)
+	assert.Equal(t, int64(5), file.Size)
+
+	// Names from before content addressing are looked up by cache key
+	file, err = cache.Resolve("map_1_splash.png")
+	require.NoError(t, err)
+	assert.Equal(t, path, file.Path)
+
+	for _, name := range []string{"missing.png", "unindexed.png", "nested", strings.Repeat("0", 64) + ".png"} {
+		_, err = cache.Resolve(name)
+		assert.ErrorIs(t, err, ErrNotCached, name)
+	}
+
+	for _, name := range []string{"", ".", "..", "../etc/passwd", "nested/file.png"} {
+		_, err = cache.Resolve(name)
+		assert.ErrorIs(t, err, ErrInvalidName, name)
+	}
+}
+
+func TestImageCacheEviction(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Images left by an earlier run are adopted and indexed oldest first
+	old := time.Now().Add(-time.Hour)
+	for i, name := range []string{"map_a_splash.png", "map_b_splash.png", "map_c_splash.png"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte{byte('a' + i)}, 100), 0644))
+		require.NoError(t, os.Chtimes(path, old, old.Add(time.Duration(i)*time.Minute)))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "maps-snapshot.json"), make([]byte, 1000), 0644))
+
+	cfg := &config.Config{Cache: config.CacheConfig{Directory: tmpDir, MaxSize: 250}}
+	imgCache, err := NewImageCache(cfg, &http.Client{})
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+
+	// The startup scan evicts the oldest image to fit the budget
+	metrics := imgCache.Metrics()
+	assert.Equal(t, 2, metrics.Entries)
+	assert.Equal(t, int64(200), metrics.Bytes)
+	assert.Equal(t, int64(250), metrics.MaxBytes)
+	assert.Equal(t, uint64(1), metrics.Evictions)
+	assert.FileExists(t, filepath.Join(tmpDir, "maps-snapshot.json"))
+	files := make(map[string]string)
+	for _, image := range imgCache.Images() {
+		files[image.Key] = filepath.Join(tmpDir, image.File)
+	}
+	assert.NotContains(t, files, "map_a_splash")
+
+	// Using b makes c the least recently used
+	path, err := imgCache.GetOrDownloadImage(setupTestContext(), "https://example.com/b.png", "map_b_splash")
+	require.NoError(t, err)
+	assert.Equal(t, files["map_b_splash"], path)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte{'d'}, 100))
+	}))
+	defer server.Close()
+	imgCache.(*imageCache).client = server.Client()
+
+	pathD, err := imgCache.GetOrDownloadImage(setupTestContext(), server.URL+"/d.png", "map_d_splash")
+	require.NoError(t, err)
+
+	metrics = imgCache.Metrics()
+	assert.Equal(t, 2, metrics.Entries)
+	assert.Equal(t, uint64(2), metrics.Evictions)
+	assert.Equal(t, int64(200), metrics.EvictedBytes)
+	assert.Equal(t, uint64(1), metrics.Hits)
+	assert.Equal(t, uint64(1), metrics.Misses)
+	assert.NoFileExists(t, files["map_c_splash"])
+	assert.FileExists(t, files["map_b_splash"])
+	assert.FileExists(t, pathD)
+}
+
+// stalledUpstream accepts requests and never answers them, until the client gives up
+type stalledUpstream struct {
+	requests atomic.Int64
+}
+
+func (u *stalledUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.requests.Add(1)
+	<-r.Context().Done()
+}
+
+func TestDownloadImageUsesCallerContext(t *testing.T) {
+	upstream := &stalledUpstream{}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	cache, _, _ := createTestCache(t)
+	cache.(*imageCache).client = server.Client()
+
+	testCtx := setupTestContext()
+	deadlineCtx, cancel := context.WithTimeout(testCtx.Context, 50*time.Millisecond)
+	defer cancel()
+	testCtx.Context = deadlineCtx
+
+	_, err := cache.GetOrDownloadImage(testCtx, server.URL+"/splash.png", "map_1_splash")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, cache.Metrics().Entries)
+}
+
+func TestShutdownCancelsDownloads(t *testing.T) {
+	upstream := &stalledUpstream{}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	cfg := &config.Config{Cache: config.CacheConfig{Directory: t.TempDir(), Workers: 1, QueueSize: 10}}
+	imgCache, err := NewImageCache(cfg, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := imgCache.GetOrDownloadImage(setupTestContext(), server.URL+"/splash.png", "map_1_splash")
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return upstream.requests.Load() == 1 }, time.Second, time.Millisecond)
+
+	// Queued downloads are dropped rather than started
+	cache := imgCache.(*imageCache)
+	for i := range 3 {
+		require.True(t, cache.enqueue(downloadTask{URL: server.URL + "/icon.png", CacheKey: fmt.Sprintf("map_%d_icon", i), Ctx: setupTestContext()}, false))
+	}
+
+	shutdown := make(chan struct{})
+	go func() {
+		imgCache.Shutdown()
+		close(shutdown)
+	}()
+	select {
+	case <-shutdown:
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown waited for in-flight downloads")
+	}
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.LessOrEqual(t, upstream.requests.Load(), int64(2))
+}
+
+func TestPrewarmCacheDeadline(t *testing.T) {
+	upstream := &stalledUpstream{}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	cfg := &config.Config{Cache: config.CacheConfig{Directory: t.TempDir(), Workers: 1, QueueSize: 1, PrewarmTimeout: 50 * time.Millisecond}}
+	imgCache, err := NewImageCache(cfg, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+
+	urlMap := make(map[string]string)
+	for i := range 5 {
+		urlMap[fmt.Sprintf("map_%d_splash", i)] = fmt.Sprintf("%s/%d.png", server.URL, i)
+	}
+
+	start := time.Now()
+	err = imgCache.PrewarmCache(setupTestContext(), urlMap)
+	assert.ErrorContains(t, err, "timed out after 50ms")
+	assert.Less(t, time.Since(start), time.Second)
+
+	// The download in flight is cancelled and the one still queued is dropped
+	done := make(chan struct{})
+	go func() {
+		imgCache.(*imageCache).wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Prewarm downloads outlived the deadline")
+	}
+	assert.Equal(t, int64(1), upstream.requests.Load())
+
+	// A caller cancelling the prewarm is reported as such
+	cancelled := setupTestContext()
+	cancelledCtx, cancel := context.WithCancel(cancelled.Context)
+	cancel()
+	cancelled.Context = cancelledCtx
+	assert.ErrorIs(t, imgCache.PrewarmCache(cancelled, urlMap), context.Canceled)
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// flight is a download in progress that concurrent callers for its key wait on
+type flight struct {
+	done chan struct{}
+	path string
+	err  error
+}
+
+// flightGroup coalesces concurrent downloads of the same cache key into one.
+// The zero value is ready to use.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// do runs fn for key unless a call for key is already in progress, in which case
+// it waits for that call, or until ctx is done, and returns its result. shared
+// reports whether the result came from a call started by another caller.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (string, error)) (path string, err error, shared bool) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.path, f.err, true
+		case <-ctx.Done():
+			return "", ctx.Err(), true
+		}
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.path, f.err = fn()
+	return f.path, f.err, false
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"container/list"
+	"sort"
+)
+
+// cacheEntry is a cached image tracked by the LRU index. Entries with identical
+// content share a path.
+type cacheEntry struct {
+	key  string
+	path string
+	size int64
+	// verified is set once the file's content has been checked against its hash
+	verified bool
+}
+
+// lruIndex tracks cached images in least recently used order and evicts the
+// oldest ones once the byte budget or entry limit is exceeded. A zero limit
+// disables it. lruIndex is not safe for concurrent use.
+type lruIndex struct {
+	maxBytes   int64
+	maxEntries int
+	bytes      int64
+	// order holds *cacheEntry values, most recently used first
+	order   *list.List
+	entries map[string]*list.Element
+	// paths holds the entries stored at each path by key, so that entries sharing
+	// content are found without scanning the index
+	paths map[string]map[string]*cacheEntry
+	// variantBytes holds the size of the variants rendered from each path, which
+	// counts against the byte budget until the path is no longer stored
+	variantBytes map[string]int64
+}
+
+// newLRUIndex creates an empty index with the given limits
+func newLRUIndex(maxBytes int64, maxEntries int) *lruIndex {
+	return &lruIndex{
+		maxBytes:     maxBytes,
+		maxEntries:   maxEntries,
+		order:        list.New(),
+		entries:      make(map[string]*list.Element),
+		paths:        make(map[string]map[string]*cacheEntry),
+		variantBytes: make(map[string]int64),
+	}
+}
+
+// touch returns a copy of the entry for key and marks it as recently used
+func (l *lruIndex) touch(key string) (cacheEntry, bool) {
+	element, ok := l.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	l.order.MoveToFront(element)
+	return *element.Value.(*cacheEntry), true
+}
+
+// peek returns a copy of the entry for key without marking it as used
+func (l *lruIndex) peek(key string) (cacheEntry, bool) {
+	element, ok := l.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	return *element.Value.(*cacheEntry), true
+}
+
+// contains reports whether key is indexed without marking it as used
+func (l *lruIndex) contains(key string) bool {
+	_, ok := l.entries[key]
+	return ok
+}
+
+// referenced reports whether any entry is stored at path
+func (l *lruIndex) referenced(path string) bool {
+	return len(l.paths[path]) > 0
+}
+
+// keysAt returns the keys of the entries stored at path in sorted order
+func (l *lruIndex) keysAt(path string) []string {
+	var keys []string
+	for key := range l.paths[path] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// markVerified records that the content at path matches its hash
+func (l *lruIndex) markVerified(path string) {
+	for _, entry := range l.paths[path] {
+		entry.verified = true
+	}
+}
+
+// add indexes or updates the entry for key as the most recently used and returns
+// the entries evicted to stay within the limits. The added entry itself is never
+// evicted, so a single image larger than the budget is still cached.
+func (l *lruIndex) add(key, path string, size int64) []cacheEntry {
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		l.bytes += size - entry.size
+		if entry.path != path {
+			l.release(entry)
+			entry.path = path
+			l.hold(entry)
+			entry.verified = false
+		}
+		entry.size = size
+		l.order.MoveToFront(element)
+	} else {
+		entry := &cacheEntry{key: key, path: path, size: size}
+		l.entries[key] = l.order.PushFront(entry)
+		l.bytes += size
+		l.hold(entry)
+	}
+
+	return l.shrink()
+}
+
+// addVariant counts delta bytes of variants rendered from path, which may be
+// negative once variants are removed, and returns the entries evicted to stay
+// within the limits. Variants of a path no entry is stored at are not counted.
+func (l *lruIndex) addVariant(path string, delta int64) []cacheEntry {
+	if !l.referenced(path) {
+		return nil
+	}
+	delta = max(delta, -l.variantBytes[path])
+	l.variantBytes[path] += delta
+	l.bytes += delta
+	return l.shrink()
+}
+
+// shrink evicts the least recently used entries beyond the limits, keeping at
+// least the most recently used one, and returns them
+func (l *lruIndex) shrink() []cacheEntry {
+	var evicted []cacheEntry
+	for l.order.Len() > 1 && l.overLimit() {
+		evicted = append(evicted, l.removeElement(l.order.Back()))
+	}
+	return evicted
+}
+
+// remove drops the entry for key from the index
+func (l *lruIndex) remove(key string) {
+	if element, ok := l.entries[key]; ok {
+		l.removeElement(element)
+	}
+}
+
+// snapshot returns a copy of the indexed entries, most recently used first
+func (l *lruIndex) snapshot() []cacheEntry {
+	entries := make([]cacheEntry, 0, l.order.Len())
+	for element := l.order.Front(); element != nil; element = element.Next() {
+		entries = append(entries, *element.Value.(*cacheEntry))
+	}
+	return entries
+}
+
+// len returns the number of indexed entries
+func (l *lruIndex) len() int {
+	return l.order.Len()
+}
+
+// overLimit reports whether the index exceeds its byte budget or entry limit
+func (l *lruIndex) overLimit() bool {
+	return (l.maxBytes > 0 && l.bytes > l.maxBytes) || (l.maxEntries > 0 && l.order.Len() > l.maxEntries)
+}
+
+// removeElement unlinks an entry and returns it
+func (l *lruIndex) removeElement(element *list.Element) cacheEntry {
+	entry := element.Value.(*cacheEntry)
+	l.order.Remove(element)
+	delete(l.entries, entry.key)
+	l.bytes -= entry.size
+	l.release(entry)
+	return *entry
+}
+
+// hold records entry as stored at its path
+func (l *lruIndex) hold(entry *cacheEntry) {
+	held, ok := l.paths[entry.path]
+	if !ok {
+		held = make(map[string]*cacheEntry)
+		l.paths[entry.path] = held
+	}
+	held[entry.key] = entry
+}
+
+// release drops entry from its path, and with the last entry there the variants
+// rendered from it
+func (l *lruIndex) release(entry *cacheEntry) {
+	path := entry.path
+	delete(l.paths[path], entry.key)
+	if len(l.paths[path]) == 0 {
+		delete(l.paths, path)
+		l.bytes -= l.variantBytes[path]
+		delete(l.variantBytes, path)
+	}
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUIndex(t *testing.T) {
+	index := newLRUIndex(300, 0)
+
+	assert.Empty(t, index.add("a", "/a.png", 100))
+	assert.Empty(t, index.add("b", "/b.png", 100))
+	assert.Empty(t, index.add("c", "/c.png", 100))
+	assert.Equal(t, int64(300), index.bytes)
+
+	// Reading a makes b the least recently used
+	entry, ok := index.touch("a")
+	assert.True(t, ok)
+	assert.Equal(t, "/a.png", entry.path)
+
+	evicted := index.add("d", "/d.png", 50)
+	assert.Equal(t, []cacheEntry{{key: "b", path: "/b.png", size: 100}}, evicted)
+	assert.False(t, index.contains("b"))
+	assert.Equal(t, 3, index.len())
+	assert.Equal(t, int64(250), index.bytes)
+
+	// Growing an entry evicts others, but never the entry itself
+	evicted = index.add("d", "/d.png", 400)
+	assert.Len(t, evicted, 2)
+	assert.Equal(t, 1, index.len())
+	assert.Equal(t, int64(400), index.bytes)
+	_, ok = index.touch("a")
+	assert.False(t, ok)
+}
+
+func TestLRUIndexMaxEntries(t *testing.T) {
+	index := newLRUIndex(0, 2)
+
+	index.add("a", "/a.png", 1<<30)
+	index.add("b", "/b.png", 1<<30)
+	assert.True(t, index.contains("a"))
+
+	evicted := index.add("c", "/c.png", 1)
+	assert.Len(t, evicted, 1)
+	assert.Equal(t, "a", evicted[0].key)
+	assert.Equal(t, 2, index.len())
+}
+
+func TestLRUIndexVariantBytes(t *testing.T) {
+	index := newLRUIndex(300, 0)
+	index.add("a", "/a.png", 100)
+	index.add("b", "/b.png", 100)
+
+	// Variants count against the budget until their image is gone
+	assert.Empty(t, index.addVariant("/b.png", 50))
+	assert.Empty(t, index.addVariant("/missing.png", 50))
+	assert.Equal(t, int64(250), index.bytes)
+
+	evicted := index.addVariant("/b.png", 100)
+	assert.Equal(t, []cacheEntry{{key: "a", path: "/a.png", size: 100}}, evicted)
+	assert.Equal(t, int64(250), index.bytes)
+
+	// Removed variants are subtracted, but never below zero
+	index.addVariant("/b.png", -500)
+	assert.Equal(t, int64(100), index.bytes)
+
+	index.addVariant("/b.png", 80)
+	index.add("b", "/c.png", 100)
+	assert.Equal(t, int64(100), index.bytes)
+}
+
+func TestLRUIndexSharedPaths(t *testing.T) {
+	index := newLRUIndex(0, 0)
+	index.add("b", "/blob.png", 100)
+	index.add("a", "/blob.png", 100)
+	index.add("c", "/c.png", 100)
+
+	assert.Equal(t, []string{"a", "b"}, index.keysAt("/blob.png"))
+	assert.Empty(t, index.keysAt("/missing.png"))
+
+	index.markVerified("/blob.png")
+	for _, entry := range index.snapshot() {
+		assert.Equal(t, entry.path == "/blob.png", entry.verified, entry.key)
+	}
+
+	// Moving an entry to other content leaves the rest at the shared path
+	index.add("b", "/c.png", 100)
+	assert.Equal(t, []string{"a"}, index.keysAt("/blob.png"))
+	assert.Equal(t, []string{"b", "c"}, index.keysAt("/c.png"))
+
+	index.remove("a")
+	assert.False(t, index.referenced("/blob.png"))
+	assert.Empty(t, index.keysAt("/blob.png"))
+}
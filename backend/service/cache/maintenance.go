@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// imageExtensions are the file types the cache writes; other files in the
+// directory, such as the catalog snapshot or the SQLite database, are left alone
+var imageExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
+
+// DirStats summarises the images cached in a directory
+type DirStats struct {
+	Dir    string    `json:"dir"`
+	Files  int       `json:"files"`
+	Bytes  int64     `json:"bytes"`
+	Oldest time.Time `json:"oldest,omitempty"`
+	Newest time.Time `json:"newest,omitempty"`
+}
+
+// CorruptImage is a cached file that is not a readable image
+type CorruptImage struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Stats counts the images cached in dir and their total size
+func Stats(dir string) (*DirStats, error) {
+	stats := &DirStats{Dir: dir}
+	err := eachImage(dir, func(path string, info os.FileInfo) error {
+		stats.Files++
+		stats.Bytes += info.Size()
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+		if info.ModTime().After(stats.Newest) {
+			stats.Newest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Purge deletes the images cached in dir, with their metadata and variants, and
+// returns how many were removed. A running server downloads them again on demand.
+func Purge(dir string) (int, error) {
+	removed := 0
+	err := eachImage(dir, func(path string, info os.FileInfo) error {
+		if err := removeImage(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", info.Name(), err)
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	metas, err := filepath.Glob(filepath.Join(dir, "*"+metaSuffix))
+	if err != nil {
+		return removed, err
+	}
+	for _, path := range metas {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove %s: %w", filepath.Base(path), err)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(dir, variantDir)); err != nil {
+		return removed, fmt.Errorf("failed to remove variants: %w", err)
+	}
+	return removed, nil
+}
+
+// Verify checks that every image cached in dir can be decoded and returns those
+// that cannot, deleting them when remove is set
+func Verify(dir string, remove bool) ([]CorruptImage, error) {
+	var corrupt []CorruptImage
+	err := eachImage(dir, func(path string, info os.FileInfo) error {
+		reason, err := checkImage(path)
+		if err != nil {
+			return err
+		}
+		if reason == "" {
+			return nil
+		}
+
+		corrupt = append(corrupt, CorruptImage{Name: info.Name(), Reason: reason})
+		if remove {
+			if err := removeImage(path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", info.Name(), err)
+			}
+		}
+		return nil
+	})
+	return corrupt, err
+}
+
+// checkImage returns why the file at path is not a valid image, or an empty string
+func checkImage(path string) (string, error) {
+	if blobName.MatchString(filepath.Base(path)) {
+		if err := verifyBlob(path); errors.Is(err, ErrCorrupt) {
+			return "checksum mismatch", nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	if len(data) == 0 {
+		return "empty file", nil
+	}
+
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return "not an image (" + contentType + ")", nil
+	}
+	// WebP has no decoder in the standard library, so sniffing has to do
+	if contentType == "image/webp" {
+		return "", nil
+	}
+
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		return "undecodable: " + err.Error(), nil
+	}
+	return "", nil
+}
+
+// removeImage deletes a cached image and the metadata sidecar kept next to it by
+// earlier versions. Metadata of content-addressed images is named after the cache
+// key and is dropped when the cache next starts.
+func removeImage(path string) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if err := os.Remove(path + metaSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// eachImage calls fn for every cached image directly inside dir
+func eachImage(dir string, fn func(path string, info os.FileInfo) error) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !(imageExtensions[strings.ToLower(filepath.Ext(name))] || blobName.MatchString(name)) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", entry.Name(), err)
+		}
+		if err := fn(filepath.Join(dir, entry.Name()), info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCacheDir fills a directory with a valid, a truncated and a mislabelled image,
+// next to files that are not part of the image cache
+func writeCacheDir(t *testing.T) string {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+	valid := buf.Bytes()
+
+	files := map[string][]byte{
+		"map_a_splash.png":   valid,
+		"map_b_splash.png":   valid[:len(valid)/2],
+		"map_c_icon.jpg":     []byte("<html>rate limited</html>"),
+		"maps-snapshot.json": []byte("{}"),
+		"valomap.db":         []byte("SQLite format 3"),
+	}
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o644))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested.png"), 0o755))
+	return dir
+}
+
+func TestStats(t *testing.T) {
+	dir := writeCacheDir(t)
+
+	stats, err := Stats(dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir, stats.Dir)
+	assert.Equal(t, 3, stats.Files)
+	assert.Positive(t, stats.Bytes)
+	assert.False(t, stats.Oldest.IsZero())
+	assert.False(t, stats.Newest.Before(stats.Oldest))
+
+	_, err = Stats(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestVerifyAndPurge(t *testing.T) {
+	dir := writeCacheDir(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "map_b_splash.png"+metaSuffix), []byte("{}"), 0644))
+
+	corrupt, err := Verify(dir, false)
+	require.NoError(t, err)
+	require.Len(t, corrupt, 2)
+	assert.Equal(t, "map_b_splash.png", corrupt[0].Name)
+	assert.Contains(t, corrupt[0].Reason, "undecodable")
+	assert.Equal(t, "map_c_icon.jpg", corrupt[1].Name)
+	assert.Contains(t, corrupt[1].Reason, "not an image")
+
+	// Removing leaves only the valid image, without the metadata of removed ones
+	_, err = Verify(dir, true)
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "map_b_splash.png"+metaSuffix))
+	corrupt, err = Verify(dir, false)
+	require.NoError(t, err)
+	assert.Empty(t, corrupt)
+
+	// Purging also removes metadata kept under cache keys
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "map_a_splash"+metaSuffix), []byte("{}"), 0644))
+	removed, err := Purge(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	// Files that are not cached images survive a purge
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"maps-snapshot.json", "nested.png", "valomap.db"}, names)
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+
+	path, _, err := writeBlob(dir, bytes.NewReader(buf.Bytes()), ".png")
+	require.NoError(t, err)
+	corrupt, err := Verify(dir, false)
+	require.NoError(t, err)
+	assert.Empty(t, corrupt)
+
+	// A decodable image whose content no longer matches its name is still corrupt
+	renamed := filepath.Join(dir, strings.Repeat("0", 64)+".png")
+	require.NoError(t, os.Rename(path, renamed))
+	corrupt, err = Verify(dir, false)
+	require.NoError(t, err)
+	require.Len(t, corrupt, 1)
+	assert.Equal(t, "checksum mismatch", corrupt[0].Reason)
+}
@@ -34,6 +34,32 @@ func (m *MockImageCacheForPrewarm) CacheMapImages(ctx ctx.CTX, maps []domain.Map
 	return args.Get(0).([]domain.Map), args.Error(1)
 }
 
+func (m *MockImageCacheForPrewarm) Resolve(name string) (*CachedFile, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CachedFile), args.Error(1)
+}
+
+func (m *MockImageCacheForPrewarm) Variant(name string, opts VariantOptions) (*CachedFile, error) {
+	args := m.Called(name, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CachedFile), args.Error(1)
+}
+
+func (m *MockImageCacheForPrewarm) Metrics() Metrics {
+	args := m.Called()
+	return args.Get(0).(Metrics)
+}
+
+func (m *MockImageCacheForPrewarm) Images() []ImageInfo {
+	args := m.Called()
+	return args.Get(0).([]ImageInfo)
+}
+
 func (m *MockImageCacheForPrewarm) Shutdown() {
 	m.Called()
 }
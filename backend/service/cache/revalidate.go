@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/sirupsen/logrus"
+)
+
+// metaSuffix is appended to a cache key to name its sidecar metadata file
+const metaSuffix = ".meta.json"
+
+// imageMeta is the sidecar metadata of a cached image: the content-addressed file
+// holding it and what is needed to revalidate it against upstream
+type imageMeta struct {
+	File          string    `json:"file"`
+	URL           string    `json:"url,omitempty"`
+	ETag          string    `json:"etag,omitempty"`
+	LastModified  string    `json:"lastModified,omitempty"`
+	CachedAt      time.Time `json:"cachedAt"`
+	RevalidatedAt time.Time `json:"revalidatedAt"`
+}
+
+// ImageInfo describes a cached image and when it was last checked against upstream
+type ImageInfo struct {
+	Key           string     `json:"key" example:"map_7eaecc1b-4337-bbf6-6ab9-04b8f06b3319_splash"`
+	File          string     `json:"file" example:"5f2b9c0e4d7a1b3c8e6f0a2d4b6c8e0f1a3c5e7b9d1f3a5c7e9b1d3f5a7c9e1b.png"`
+	Size          int64      `json:"size" example:"1048576"`
+	URL           string     `json:"url,omitempty" example:"https://media.valorant-api.com/maps/7eaecc1b-4337-bbf6-6ab9-04b8f06b3319/splash.png"`
+	CachedAt      *time.Time `json:"cachedAt,omitempty" example:"2023-07-01T12:34:56Z"`
+	RevalidatedAt *time.Time `json:"revalidatedAt,omitempty" example:"2023-07-02T12:34:56Z"`
+}
+
+// metaPath returns the sidecar metadata path of the image cached under key
+func metaPath(dir, key string) string {
+	return filepath.Join(dir, key+metaSuffix)
+}
+
+// readMeta loads the sidecar metadata of the image cached under key; keys
+// without metadata return nil
+func readMeta(dir, key string) (*imageMeta, error) {
+	return readMetaFile(metaPath(dir, key))
+}
+
+// readMetaFile loads a sidecar metadata file, returning nil if it does not exist
+func readMetaFile(path string) (*imageMeta, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image metadata: %w", err)
+	}
+
+	var meta imageMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode image metadata: %w", err)
+	}
+	return &meta, nil
+}
+
+// writeMeta saves the sidecar metadata of the image cached under key
+func writeMeta(dir, key string, meta *imageMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode image metadata: %w", err)
+	}
+	if _, err := writeFileAtomic(metaPath(dir, key), bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to save image metadata: %w", err)
+	}
+	return nil
+}
+
+// newMeta builds the metadata of an image freshly downloaded from imageURL into
+// the file at path
+func newMeta(path, imageURL string, resp *http.Response) *imageMeta {
+	now := time.Now().UTC()
+	return &imageMeta{
+		File:          filepath.Base(path),
+		URL:           imageURL,
+		ETag:          resp.Header.Get("ETag"),
+		LastModified:  resp.Header.Get("Last-Modified"),
+		CachedAt:      now,
+		RevalidatedAt: now,
+	}
+}
+
+// revalidateLoop revalidates every cached image each interval until Shutdown
+func (c *imageCache) revalidateLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.revalidateAll(ctx.Background())
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// revalidateAll checks every cached image that has metadata against upstream
+// and returns how many were replaced
+func (c *imageCache) revalidateAll(ctx ctx.CTX) int {
+	c.mutex.RLock()
+	entries := c.index.snapshot()
+	c.mutex.RUnlock()
+
+	changed := 0
+	for _, entry := range entries {
+		updated, err := c.revalidate(ctx, entry)
+		if err != nil {
+			ctx.FieldLogger.WithError(err).WithField("cache_key", entry.key).Warn("Failed to revalidate cached image")
+			continue
+		}
+		if updated {
+			changed++
+		}
+	}
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"image_count": len(entries),
+		"changed":     changed,
+	}).Info("Revalidated cached images")
+	return changed
+}
+
+// revalidate sends a conditional request for a cached image and replaces the
+// file when upstream has changed its content. It reports whether the file was
+// replaced.
+func (c *imageCache) revalidate(ctx ctx.CTX, entry cacheEntry) (bool, error) {
+	meta, err := readMeta(c.cachePath, entry.key)
+	if err != nil || meta == nil || meta.URL == "" {
+		return false, err
+	}
+
+	revalidateCtx, cancel := c.downloadContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(revalidateCtx, http.MethodGet, meta.URL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	if meta.ETag != "" {
+		req.Header.Set("If-None-Match", meta.ETag)
+	}
+	if meta.LastModified != "" {
+		req.Header.Set("If-Modified-Since", meta.LastModified)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to revalidate image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		meta.RevalidatedAt = time.Now().UTC()
+		return false, writeMeta(c.cachePath, entry.key, meta)
+
+	case http.StatusOK:
+		path, size, err := writeBlob(c.cachePath, resp.Body, filepath.Ext(entry.path))
+		if err != nil {
+			return false, fmt.Errorf("failed to replace image: %w", err)
+		}
+		updated := newMeta(path, meta.URL, resp)
+		if path == entry.path {
+			// The same content under new validators is not a change
+			updated.CachedAt = meta.CachedAt
+			return false, writeMeta(c.cachePath, entry.key, updated)
+		}
+		if err := writeMeta(c.cachePath, entry.key, updated); err != nil {
+			return false, err
+		}
+		c.store(entry.key, path, size, true)
+
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"cache_key": entry.key,
+			"url":       meta.URL,
+		}).Info("Replaced cached image changed upstream")
+		return true, nil
+
+	default:
+		return false, fmt.Errorf("received non-OK status code: %d", resp.StatusCode)
+	}
+}
+
+// Images lists the cached images, most recently used first, with their metadata
+func (c *imageCache) Images() []ImageInfo {
+	c.mutex.RLock()
+	entries := c.index.snapshot()
+	c.mutex.RUnlock()
+
+	images := make([]ImageInfo, 0, len(entries))
+	for _, entry := range entries {
+		info := ImageInfo{Key: entry.key, File: filepath.Base(entry.path), Size: entry.size}
+		if meta, err := readMeta(c.cachePath, entry.key); err == nil && meta != nil {
+			info.URL = meta.URL
+			info.CachedAt = &meta.CachedAt
+			if !meta.RevalidatedAt.IsZero() {
+				info.RevalidatedAt = &meta.RevalidatedAt
+			}
+		}
+		images = append(images, info)
+	}
+	return images
+}
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upstreamImage serves one image with an ETag and answers conditional requests
+type upstreamImage struct {
+	mu          sync.Mutex
+	body        string
+	etag        string
+	conditional int
+}
+
+func (u *upstreamImage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		u.conditional++
+		if match == u.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("ETag", u.etag)
+	w.Header().Set("Last-Modified", "Wed, 01 Jan 2025 00:00:00 GMT")
+	w.Write([]byte(u.body))
+}
+
+func (u *upstreamImage) set(body, etag string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.body, u.etag = body, etag
+}
+
+func TestRevalidate(t *testing.T) {
+	upstream := &upstreamImage{body: "first", etag: `"v1"`}
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	imgCache, err := NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: tmpDir}}, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+	cache := imgCache.(*imageCache)
+
+	path, err := cache.GetOrDownloadImage(setupTestContext(), server.URL+"/splash.png", "map_1_splash")
+	require.NoError(t, err)
+
+	meta, err := readMeta(tmpDir, "map_1_splash")
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	assert.Equal(t, filepath.Base(path), meta.File)
+	assert.Equal(t, server.URL+"/splash.png", meta.URL)
+	assert.Equal(t, `"v1"`, meta.ETag)
+	assert.Equal(t, "Wed, 01 Jan 2025 00:00:00 GMT", meta.LastModified)
+	firstCheck := meta.RevalidatedAt
+
+	// An unchanged image only has its revalidation time updated
+	assert.Equal(t, 0, cache.revalidateAll(setupTestContext()))
+	assert.Equal(t, 1, upstream.conditional)
+	meta, err = readMeta(tmpDir, "map_1_splash")
+	require.NoError(t, err)
+	assert.False(t, meta.RevalidatedAt.Before(firstCheck))
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "first", string(content))
+
+	// New validators for the same content only update the metadata
+	upstream.set("first", `"v1-gzip"`)
+	assert.Equal(t, 0, cache.revalidateAll(setupTestContext()))
+	meta, err = readMeta(tmpDir, "map_1_splash")
+	require.NoError(t, err)
+	assert.Equal(t, `"v1-gzip"`, meta.ETag)
+	assert.Equal(t, filepath.Base(path), meta.File)
+	assert.FileExists(t, path)
+
+	// A changed image is stored under its new hash, replacing the old file
+	upstream.set("second version", `"v2"`)
+	assert.Equal(t, 1, cache.revalidateAll(setupTestContext()))
+	assert.NoFileExists(t, path)
+	meta, err = readMeta(tmpDir, "map_1_splash")
+	require.NoError(t, err)
+	assert.Equal(t, `"v2"`, meta.ETag)
+	assert.NotEqual(t, filepath.Base(path), meta.File)
+	content, _ = os.ReadFile(filepath.Join(tmpDir, meta.File))
+	assert.Equal(t, "second version", string(content))
+	assert.Equal(t, int64(len("second version")), cache.Metrics().Bytes)
+
+	file, err := cache.Resolve(meta.File)
+	require.NoError(t, err)
+	assert.Equal(t, meta.RevalidatedAt, file.RevalidatedAt)
+
+	images := cache.Images()
+	require.Len(t, images, 1)
+	assert.Equal(t, "map_1_splash", images[0].Key)
+	assert.Equal(t, meta.File, images[0].File)
+	assert.Equal(t, server.URL+"/splash.png", images[0].URL)
+	require.NotNil(t, images[0].RevalidatedAt)
+
+	// No temporary files are left behind
+	assert.ElementsMatch(t, []string{meta.File, "map_1_splash" + metaSuffix}, dirNames(t, tmpDir))
+}
+
+func TestRevalidateWithoutMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "map_1_splash.png"), []byte("old"), 0644))
+
+	imgCache, err := NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: tmpDir}}, &http.Client{})
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+
+	// Images cached before metadata was kept are listed but not revalidated
+	assert.Equal(t, 0, imgCache.(*imageCache).revalidateAll(setupTestContext()))
+	images := imgCache.Images()
+	require.Len(t, images, 1)
+	assert.Empty(t, images[0].URL)
+	assert.Nil(t, images[0].RevalidatedAt)
+}
+
+func TestEvictedImageIsDownloadedAgain(t *testing.T) {
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		w.Write([]byte("image " + strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".png")))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	names := make(map[string]string)
+	for _, key := range []string{"a", "b"} {
+		path, _, err := writeBlob(tmpDir, strings.NewReader("image "+key), ".png")
+		require.NoError(t, err)
+		require.NoError(t, writeMeta(tmpDir, key, &imageMeta{File: filepath.Base(path), URL: server.URL + "/" + key + ".png"}))
+		names[key] = filepath.Base(path)
+	}
+
+	imgCache, err := NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: tmpDir, MaxEntries: 1}}, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+
+	// The evicted image's file is gone but its metadata is kept
+	images := imgCache.Images()
+	require.Len(t, images, 1)
+	evicted := "a"
+	if images[0].Key == "a" {
+		evicted = "b"
+	}
+	assert.ElementsMatch(t, []string{images[0].File, "a" + metaSuffix, "b" + metaSuffix}, dirNames(t, tmpDir))
+
+	// Requesting it misses once and queues a download of it
+	_, err = imgCache.Resolve(names[evicted])
+	assert.ErrorIs(t, err, ErrNotCached)
+	assert.Eventually(t, func() bool {
+		file, err := imgCache.Resolve(names[evicted])
+		return err == nil && filepath.Base(file.Path) == names[evicted]
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), downloads.Load())
+	assert.Equal(t, 1, imgCache.Metrics().Entries)
+
+	// Metadata without a URL cannot be used to download the image again
+	imgCache.Shutdown()
+	require.NoError(t, writeMeta(tmpDir, "c", &imageMeta{File: strings.Repeat("0", 64) + ".png"}))
+	imgCache, err = NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: tmpDir, MaxEntries: 1}}, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+	assert.NoFileExists(t, metaPath(tmpDir, "c"))
+	assert.FileExists(t, metaPath(tmpDir, images[0].Key))
+}
+
+// dirNames lists the names of the entries in dir
+func dirNames(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
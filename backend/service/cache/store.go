@@ -0,0 +1,437 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/sirupsen/logrus"
+)
+
+// quarantineDir is the subdirectory corrupt images are moved to for inspection
+const quarantineDir = "quarantine"
+
+// ErrCorrupt is returned when a cached file does not match its content hash
+var ErrCorrupt = errors.New("cached image is corrupt")
+
+// blobName matches content-addressed image files: the hex SHA-256 of the content
+// followed by the image's extension
+var blobName = regexp.MustCompile(`^[0-9a-f]{64}\.[a-z0-9]+$`)
+
+// isTempFile reports whether name is a temporary file left by an interrupted write
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".tmp")
+}
+
+// writeBlob stores r in dir under the SHA-256 of its content. The content goes to
+// a temporary file that is renamed into place, so a crash or a concurrent write of
+// the same image never leaves a truncated file behind.
+func writeBlob(dir string, r io.Reader, ext string) (string, int64, error) {
+	tmp, err := os.CreateTemp(dir, ".download-*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	path := filepath.Join(dir, hex.EncodeToString(hash.Sum(nil))+strings.ToLower(ext))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return path, size, nil
+}
+
+// writeFileAtomic writes r to a temporary file next to path and renames it over
+// path, so readers see either the old or the new content but never a partial file
+func writeFileAtomic(path string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// verifyBlob checks that the content of a content-addressed file matches its name
+func verifyBlob(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	name := filepath.Base(path)
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != strings.TrimSuffix(name, filepath.Ext(name)) {
+		return fmt.Errorf("%w: %s has checksum %s", ErrCorrupt, name, sum)
+	}
+	return nil
+}
+
+// check verifies an indexed image before it is used: its size on every read and
+// its content hash on the first read
+func (c *imageCache) check(entry cacheEntry) (os.FileInfo, error) {
+	info, err := os.Stat(entry.path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if info.Size() != entry.size {
+		return nil, fmt.Errorf("%w: %s has %d bytes, expected %d", ErrCorrupt, filepath.Base(entry.path), info.Size(), entry.size)
+	}
+	if entry.verified {
+		return info, nil
+	}
+
+	if err := verifyBlob(entry.path); err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	c.index.markVerified(entry.path)
+	c.mutex.Unlock()
+	return info, nil
+}
+
+// quarantine moves a corrupt file into the quarantine directory and drops the
+// entries stored in it from the index, returning their keys
+func (c *imageCache) quarantine(ctx ctx.CTX, path string, reason error) []string {
+	c.mutex.Lock()
+	keys := c.index.keysAt(path)
+	for _, key := range keys {
+		c.index.remove(key)
+	}
+	c.quarantined++
+	c.mutex.Unlock()
+
+	dir := filepath.Join(c.cachePath, quarantineDir)
+	err := osMkdirAll(dir, 0755)
+	if err == nil {
+		err = os.Rename(path, filepath.Join(dir, filepath.Base(path)))
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		ctx.FieldLogger.WithError(err).WithField("path", path).Warn("Failed to quarantine image, removing it")
+		os.Remove(path)
+	}
+	removeVariants(c.cachePath, path)
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"path":   path,
+		"keys":   keys,
+		"reason": reason.Error(),
+	}).Warn("Quarantined corrupt cached image")
+	return keys
+}
+
+// redownload queues the images of keys to be downloaded again from the URLs in
+// their metadata, skipping any the queue has no room for
+func (c *imageCache) redownload(ctx ctx.CTX, keys []string) {
+	for _, key := range keys {
+		meta, err := readMeta(c.cachePath, key)
+		if err != nil || meta == nil || meta.URL == "" {
+			continue
+		}
+		if !c.enqueue(downloadTask{URL: meta.URL, CacheKey: key, Ctx: ctx}, false) {
+			ctx.FieldLogger.WithField("cache_key", key).Warn("Download queue full, image will be downloaded on next use")
+		}
+	}
+}
+
+// enqueue hands a download to the background workers and reports whether it was
+// queued. With wait set it blocks until there is room, the task's context is done
+// or the cache shuts down; otherwise it gives up when the queue is full.
+func (c *imageCache) enqueue(task downloadTask, wait bool) bool {
+	c.queueMutex.RLock()
+	defer c.queueMutex.RUnlock()
+
+	select {
+	case <-c.quit:
+		return false
+	default:
+	}
+
+	c.wg.Add(1)
+	if task.group != nil {
+		task.group.Add(1)
+	}
+	if wait {
+		select {
+		case c.downloadQueue <- task:
+			return true
+		case <-task.Ctx.StdContext().Done():
+		case <-c.quit:
+		}
+	} else {
+		select {
+		case c.downloadQueue <- task:
+			return true
+		default:
+		}
+	}
+	c.finish(task)
+	return false
+}
+
+// store indexes a cached image as the most recently used, deletes the file it
+// replaces if nothing else uses it, and evicts the least recently used images
+// beyond the limits. The replaced file's name stays an alias of the key, so that
+// catalog URLs still pointing at it serve the new image. verified marks content
+// whose hash was just computed.
+func (c *imageCache) store(cacheKey, filePath string, size int64, verified bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	previous, replaced := c.index.peek(cacheKey)
+	evicted := c.index.add(cacheKey, filePath, size)
+	if verified {
+		c.index.markVerified(filePath)
+	}
+	delete(c.aliases, filepath.Base(filePath))
+	if replaced && previous.path != filePath {
+		c.aliases[filepath.Base(previous.path)] = cacheKey
+		if !c.index.referenced(previous.path) {
+			c.removeBlob(previous.path)
+		}
+	}
+	c.evict(evicted)
+}
+
+// storeVariant counts delta bytes of variants rendered from the image at path
+// against the budget and evicts the least recently used images beyond it
+func (c *imageCache) storeVariant(path string, delta int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.evict(c.index.addVariant(path, delta))
+}
+
+// evict deletes the files of entries dropped from the index once no other entry
+// uses them, and counts them. Their metadata is kept and their file names become
+// aliases of their keys, so that Resolve can download them again. The caller must
+// hold the mutex.
+func (c *imageCache) evict(entries []cacheEntry) {
+	for _, entry := range entries {
+		c.aliases[filepath.Base(entry.path)] = entry.key
+		if !c.index.referenced(entry.path) {
+			c.removeBlob(entry.path)
+		}
+		c.evictions++
+		c.evictedBytes += entry.size
+
+		logrus.WithFields(logrus.Fields{
+			"cache_key": entry.key,
+			"bytes":     entry.size,
+		}).Debug("Evicted image from cache")
+	}
+}
+
+// removeBlob deletes an image file and the variants rendered from it
+func (c *imageCache) removeBlob(path string) {
+	removeFile(path)
+	removeVariants(c.cachePath, path)
+}
+
+// removeFile deletes path, logging failures other than the file being gone
+func removeFile(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logrus.WithError(err).WithField("path", path).Warn("Failed to remove cached file")
+	}
+}
+
+// loadFromDisk finds an image on disk that is missing from the index, such as one
+// downloaded by another process, or one cached under its key by an earlier version
+func (c *imageCache) loadFromDisk(cacheKey, imageURL string) (cacheEntry, bool) {
+	meta, err := readMeta(c.cachePath, cacheKey)
+	if err == nil && meta != nil && blobName.MatchString(meta.File) {
+		path := filepath.Join(c.cachePath, meta.File)
+		if info, err := os.Stat(path); err == nil {
+			return cacheEntry{key: cacheKey, path: path, size: info.Size()}, true
+		}
+	}
+
+	ext := filepath.Ext(imageURL)
+	if ext == "" {
+		return cacheEntry{}, false
+	}
+	legacyPath := filepath.Join(c.cachePath, cacheKey+ext)
+	if _, err := os.Stat(legacyPath); err != nil {
+		return cacheEntry{}, false
+	}
+	entry, err := c.adopt(cacheKey, legacyPath, imageURL)
+	if err != nil {
+		logrus.WithError(err).WithField("path", legacyPath).Warn("Failed to adopt cached image")
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// adopt moves an image cached under its key by an earlier version into a
+// content-addressed file, carrying over its metadata sidecar
+func (c *imageCache) adopt(cacheKey, legacyPath, imageURL string) (cacheEntry, error) {
+	file, err := os.Open(legacyPath)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return cacheEntry{}, err
+	}
+	path, size, err := writeBlob(c.cachePath, file, filepath.Ext(legacyPath))
+	file.Close()
+	if err != nil {
+		return cacheEntry{}, err
+	}
+
+	meta, err := readMetaFile(legacyPath + metaSuffix)
+	if err != nil || meta == nil {
+		meta = &imageMeta{URL: imageURL, CachedAt: info.ModTime().UTC()}
+	}
+	meta.File = filepath.Base(path)
+	if err := writeMeta(c.cachePath, cacheKey, meta); err != nil {
+		return cacheEntry{}, err
+	}
+	removeFile(legacyPath)
+	removeFile(legacyPath + metaSuffix)
+
+	// Keep the original age so that the LRU order survives the move
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+	return cacheEntry{key: cacheKey, path: path, size: size, verified: true}, nil
+}
+
+// scan rebuilds the index from the cache directory, oldest images first, evicting
+// any beyond the current limits. It adopts images cached by earlier versions and
+// removes temporary files, files no key refers to, variants of images no longer
+// cached and metadata without a file, unless it names the URL to download it from.
+func (c *imageCache) scan() error {
+	dirEntries, err := os.ReadDir(c.cachePath)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var keys, legacy, blobs []string
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		switch {
+		case !dirEntry.Type().IsRegular():
+		case isTempFile(name):
+			removeFile(filepath.Join(c.cachePath, name))
+		case strings.HasSuffix(name, metaSuffix):
+			keys = append(keys, strings.TrimSuffix(name, metaSuffix))
+		case blobName.MatchString(name):
+			blobs = append(blobs, name)
+		case imageExtensions[strings.ToLower(filepath.Ext(name))]:
+			legacy = append(legacy, name)
+		}
+	}
+
+	type found struct {
+		entry   cacheEntry
+		modTime time.Time
+	}
+	var images []found
+	for _, name := range legacy {
+		key := strings.TrimSuffix(name, filepath.Ext(name))
+		entry, err := c.adopt(key, filepath.Join(c.cachePath, name), "")
+		if err != nil {
+			logrus.WithError(err).WithField("file", name).Warn("Failed to adopt cached image")
+			continue
+		}
+		info, err := os.Stat(entry.path)
+		if err != nil {
+			continue
+		}
+		images = append(images, found{entry, info.ModTime()})
+	}
+	for _, key := range keys {
+		meta, err := readMeta(c.cachePath, key)
+		if err != nil || meta == nil {
+			// Sidecars of adopted images have already been removed
+			continue
+		}
+		path := filepath.Join(c.cachePath, meta.File)
+		info, err := os.Stat(path)
+		if !blobName.MatchString(meta.File) || err != nil {
+			// Evicted images are downloaded again when their file is requested
+			if blobName.MatchString(meta.File) && meta.URL != "" {
+				c.aliases[meta.File] = key
+			} else {
+				removeFile(metaPath(c.cachePath, key))
+			}
+			continue
+		}
+		images = append(images, found{cacheEntry{key: key, path: path, size: info.Size()}, info.ModTime()})
+	}
+	sort.SliceStable(images, func(i, j int) bool { return images[i].modTime.Before(images[j].modTime) })
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, image := range images {
+		c.evict(c.index.add(image.entry.key, image.entry.path, image.entry.size))
+		if image.entry.verified {
+			c.index.markVerified(image.entry.path)
+		}
+	}
+	for _, name := range blobs {
+		if path := filepath.Join(c.cachePath, name); !c.index.referenced(path) {
+			removeFile(path)
+		}
+	}
+	kept := make(map[string]string, len(images))
+	for _, image := range images {
+		if c.index.referenced(image.entry.path) {
+			kept[blobHash(image.entry.path)] = image.entry.path
+		}
+	}
+
+	// Variants are rendered again on demand, so those of images that are gone or
+	// interrupted mid-write are dropped and the others count against the budget
+	variants, _ := os.ReadDir(filepath.Join(c.cachePath, variantDir))
+	for _, variant := range variants {
+		hash, _, _ := strings.Cut(variant.Name(), "-")
+		source, ok := kept[hash]
+		info, err := variant.Info()
+		if !ok || isTempFile(variant.Name()) || err != nil {
+			removeFile(filepath.Join(c.cachePath, variantDir, variant.Name()))
+			continue
+		}
+		c.evict(c.index.addVariant(source, info.Size()))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"dir":     c.cachePath,
+		"entries": c.index.len(),
+		"bytes":   c.index.bytes,
+		"adopted": len(legacy),
+	}).Info("Indexed cached images")
+	return nil
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBlob(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha256.Sum256([]byte("image"))
+
+	path, size, err := writeBlob(dir, strings.NewReader("image"), ".PNG")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, hex.EncodeToString(sum[:])+".png"), path)
+	assert.Equal(t, int64(5), size)
+	assert.NoError(t, verifyBlob(path))
+
+	// Writing the same content again yields the same file
+	again, _, err := writeBlob(dir, strings.NewReader("image"), ".png")
+	require.NoError(t, err)
+	assert.Equal(t, path, again)
+	assert.Equal(t, []string{filepath.Base(path)}, dirNames(t, dir))
+
+	require.NoError(t, os.WriteFile(path, []byte("imagf"), 0644))
+	assert.ErrorIs(t, verifyBlob(path), ErrCorrupt)
+}
+
+func TestCorruptImageIsQuarantined(t *testing.T) {
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	imgCache, err := NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: tmpDir}}, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+	testCtx := setupTestContext()
+
+	// A file that loses bytes is caught by its size on the next read
+	path, err := imgCache.GetOrDownloadImage(testCtx, server.URL+"/splash.png", "map_1_splash")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("img"), 0644))
+
+	again, err := imgCache.GetOrDownloadImage(testCtx, server.URL+"/splash.png", "map_1_splash")
+	require.NoError(t, err)
+	assert.Equal(t, path, again)
+	assert.Equal(t, int32(2), downloads.Load())
+	content, _ := os.ReadFile(again)
+	assert.Equal(t, "image", string(content))
+	assert.FileExists(t, filepath.Join(tmpDir, quarantineDir, filepath.Base(path)))
+	assert.Equal(t, uint64(1), imgCache.Metrics().Quarantined)
+
+	// Serving a file whose content no longer matches its hash queues a new download
+	require.NoError(t, os.WriteFile(path, []byte("imagf"), 0644))
+	cache := imgCache.(*imageCache)
+	cache.mutex.Lock()
+	cache.index.entries["map_1_splash"].Value.(*cacheEntry).verified = false
+	cache.mutex.Unlock()
+
+	_, err = imgCache.Resolve(filepath.Base(path))
+	assert.ErrorIs(t, err, ErrNotCached)
+	assert.Equal(t, uint64(2), imgCache.Metrics().Quarantined)
+
+	assert.Eventually(t, func() bool {
+		file, err := imgCache.Resolve(filepath.Base(path))
+		return err == nil && file.Size == 5
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(3), downloads.Load())
+}
+
+func TestScanCleansCacheDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644))
+	}
+
+	// An image and sidecar from before content addressing
+	write("map_1_splash.png", "legacy")
+	write("map_1_splash.png"+metaSuffix, `{"url":"https://example.com/splash.png","etag":"\"v1\""}`)
+	// An interrupted download, a file no key refers to and metadata without a file
+	write(".download-123.tmp", "partial")
+	orphan, _, err := writeBlob(tmpDir, strings.NewReader("orphan"), ".png")
+	require.NoError(t, err)
+	write("map_2_icon"+metaSuffix, `{"file":"`+strings.Repeat("0", 64)+`.png"}`)
+	write("maps-snapshot.json", "{}")
+
+	imgCache, err := NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: tmpDir}}, &http.Client{})
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+
+	images := imgCache.Images()
+	require.Len(t, images, 1)
+	assert.Equal(t, "map_1_splash", images[0].Key)
+	assert.Equal(t, "https://example.com/splash.png", images[0].URL)
+	assert.ElementsMatch(t, []string{images[0].File, "map_1_splash" + metaSuffix, "maps-snapshot.json"}, dirNames(t, tmpDir))
+	assert.NoFileExists(t, orphan)
+
+	meta, err := readMeta(tmpDir, "map_1_splash")
+	require.NoError(t, err)
+	assert.Equal(t, `"v1"`, meta.ETag)
+	content, _ := os.ReadFile(filepath.Join(tmpDir, meta.File))
+	assert.True(t, bytes.Equal([]byte("legacy"), content))
+}
+
+func TestReplacedImageKeepsResolving(t *testing.T) {
+	tmpDir := t.TempDir()
+	imgCache, err := NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: tmpDir}}, &http.Client{})
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+	cache := imgCache.(*imageCache)
+
+	first, size, err := writeBlob(tmpDir, strings.NewReader("v1"), ".png")
+	require.NoError(t, err)
+	cache.store("map_1_splash", first, size, true)
+
+	// Revalidation replaces the image while the catalog still links to the old file
+	second, size, err := writeBlob(tmpDir, strings.NewReader("v2"), ".png")
+	require.NoError(t, err)
+	cache.store("map_1_splash", second, size, true)
+	assert.NoFileExists(t, first)
+
+	file, err := imgCache.Resolve(filepath.Base(first))
+	require.NoError(t, err)
+	assert.Equal(t, second, file.Path)
+
+	// Content that comes back under its old name is served directly again
+	third, size, err := writeBlob(tmpDir, strings.NewReader("v1"), ".png")
+	require.NoError(t, err)
+	cache.store("map_1_splash", third, size, true)
+	file, err = imgCache.Resolve(filepath.Base(first))
+	require.NoError(t, err)
+	assert.Equal(t, first, file.Path)
+	file, err = imgCache.Resolve(filepath.Base(second))
+	require.NoError(t, err)
+	assert.Equal(t, first, file.Path)
+
+	_, err = imgCache.Resolve(strings.Repeat("0", 64) + ".png")
+	assert.ErrorIs(t, err, ErrNotCached)
+}
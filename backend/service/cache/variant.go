@@ -0,0 +1,496 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Fit modes of an image variant
+const (
+	// FitContain scales the image to fit within the requested box, keeping its aspect ratio
+	FitContain = "contain"
+
+	// FitCover scales the image to fill the requested box, cropping the overflow around the centre
+	FitCover = "cover"
+
+	// FitFill stretches the image to the requested box
+	FitFill = "fill"
+)
+
+// Output formats of an image variant
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+const (
+	// variantDir is the subdirectory of the cache directory variants are stored in
+	variantDir = "variants"
+
+	// MaxVariantDimension bounds the requested width and height of a variant
+	MaxVariantDimension = 4096
+
+	// maxVariantsPerImage bounds the variants kept for one image; the oldest are
+	// removed beyond it
+	maxVariantsPerImage = 16
+
+	jpegQuality = 85
+)
+
+var (
+	// ErrInvalidVariant is returned for variant options out of range or unknown
+	ErrInvalidVariant = errors.New("invalid image variant")
+
+	// ErrUnsupportedVariant is returned for a conversion the cache cannot perform,
+	// such as encoding WebP, which has no encoder in the standard library
+	ErrUnsupportedVariant = errors.New("unsupported image variant")
+)
+
+// defaultVariantWidths are the widths offered in srcset attributes when the
+// configuration lists none
+var defaultVariantWidths = []int{320, 640, 1280}
+
+// defaultVariantHeights are the heights allowed when the configuration lists
+// none, matching the default widths at 16:9
+var defaultVariantHeights = []int{180, 360, 720}
+
+// VariantOptions describes a resized or converted copy of a cached image. Zero
+// values leave the corresponding property of the original unchanged.
+type VariantOptions struct {
+	Width  int
+	Height int
+	// Fit is one of FitContain (the default), FitCover or FitFill
+	Fit string
+	// Format is one of FormatJPEG, FormatPNG or FormatWebP
+	Format string
+}
+
+// IsZero reports whether the options ask for the original image
+func (o VariantOptions) IsZero() bool {
+	return o.Width == 0 && o.Height == 0 && o.Format == ""
+}
+
+// normalize validates the options and fills in the default fit
+func (o *VariantOptions) normalize() error {
+	if o.Width < 0 || o.Width > MaxVariantDimension || o.Height < 0 || o.Height > MaxVariantDimension {
+		return fmt.Errorf("%w: width and height must be between 1 and %d", ErrInvalidVariant, MaxVariantDimension)
+	}
+
+	switch o.Fit {
+	case "":
+		o.Fit = FitContain
+	case FitContain, FitCover, FitFill:
+	default:
+		return fmt.Errorf("%w: unknown fit %q", ErrInvalidVariant, o.Fit)
+	}
+
+	switch strings.ToLower(o.Format) {
+	case "":
+	case "jpg", FormatJPEG:
+		o.Format = FormatJPEG
+	case FormatPNG, FormatWebP:
+		o.Format = strings.ToLower(o.Format)
+	default:
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidVariant, o.Format)
+	}
+	return nil
+}
+
+// formatOf returns the format of a cached file from its extension
+func formatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return FormatJPEG
+	case ".png":
+		return FormatPNG
+	case ".webp":
+		return FormatWebP
+	default:
+		return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+}
+
+// Variant returns the cached file served under name transformed as opts
+// describe. Variants are rendered on first request and stored on disk next to
+// the original, which is returned as is for zero options. WebP images cannot be
+// decoded and are passed through unchanged unless another format is requested.
+func (c *imageCache) Variant(name string, opts VariantOptions) (*CachedFile, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	if len(c.variantWidths) == 0 && (opts.Width != 0 || opts.Height != 0) {
+		return nil, fmt.Errorf("%w: resizing is disabled", ErrInvalidVariant)
+	}
+	if !variantSize(c.variantWidths, opts.Width) {
+		return nil, fmt.Errorf("%w: width must be one of %v", ErrInvalidVariant, c.variantWidths)
+	}
+	if !variantSize(c.variantHeights, opts.Height) {
+		if len(c.variantHeights) == 0 {
+			return nil, fmt.Errorf("%w: resizing by height is disabled", ErrInvalidVariant)
+		}
+		return nil, fmt.Errorf("%w: height must be one of %v", ErrInvalidVariant, c.variantHeights)
+	}
+	file, err := c.Resolve(name)
+	if err != nil || opts.IsZero() {
+		return file, err
+	}
+
+	source := formatOf(file.Path)
+	if source == FormatWebP {
+		if opts.Format == "" || opts.Format == FormatWebP {
+			return file, nil
+		}
+		return nil, fmt.Errorf("%w: WebP images cannot be converted", ErrUnsupportedVariant)
+	}
+	if opts.Format == FormatWebP {
+		return nil, fmt.Errorf("%w: WebP encoding is not available", ErrUnsupportedVariant)
+	}
+
+	// Sizes beyond the original render the same image, so they share its name
+	bounds, err := imageSize(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	if opts = opts.capped(bounds); opts.IsZero() {
+		return file, nil
+	}
+	if opts.Format == "" {
+		// Animated GIFs would lose their frames, so still images become PNG
+		opts.Format = source
+		if source != FormatJPEG {
+			opts.Format = FormatPNG
+		}
+	}
+
+	path := filepath.Join(c.cachePath, variantDir, variantName(file.Path, opts))
+	info, err := os.Stat(path)
+	if err != nil {
+		// Concurrent requests for the same variant render it once
+		_, err, _ = c.variants.do(context.Background(), path, func() (string, error) {
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+			return path, c.renderVariant(file.Path, path, opts)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if info, err = os.Stat(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CachedFile{Path: path, Size: info.Size(), ModTime: info.ModTime(), RevalidatedAt: file.RevalidatedAt}, nil
+}
+
+// variantSize reports whether a requested width or height is one of the allowed
+// sizes, which bound the variants rendered per image
+func variantSize(allowed []int, n int) bool {
+	return n == 0 || slices.Contains(allowed, n)
+}
+
+// imageSize returns the dimensions of the image at path from its header
+func imageSize(path string) (image.Point, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return image.Point{}, err
+	}
+	defer file.Close()
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return image.Point{}, fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	return image.Pt(cfg.Width, cfg.Height), nil
+}
+
+// capped returns the options with a box larger than an image of the given size
+// shrunk to the size transform renders. Dimensions covering the whole image
+// are zeroed, so that options rendering the same image are named alike.
+func (o VariantOptions) capped(size image.Point) VariantOptions {
+	if size.X == 0 || size.Y == 0 {
+		return o
+	}
+	if o.Fit == FitCover && o.Width > 0 && o.Height > 0 {
+		if scale := math.Max(float64(o.Width)/float64(size.X), float64(o.Height)/float64(size.Y)); scale > 1 {
+			o.Width, o.Height = scaled(o.Width, 1/scale), scaled(o.Height, 1/scale)
+		}
+	}
+	o.Width, o.Height = min(o.Width, size.X), min(o.Height, size.Y)
+	if (o.Width == 0 || o.Width == size.X) && (o.Height == 0 || o.Height == size.Y) {
+		o.Width, o.Height, o.Fit = 0, 0, FitContain
+	}
+	return o
+}
+
+// variantName names the variant of a content-addressed file after its hash and
+// the normalised options, so that a changed original never serves stale variants
+func variantName(source string, opts VariantOptions) string {
+	ext := ".png"
+	if opts.Format == FormatJPEG {
+		ext = ".jpg"
+	}
+	return fmt.Sprintf("%s-%dx%d-%s%s", blobHash(source), opts.Width, opts.Height, opts.Fit, ext)
+}
+
+// blobHash returns the content hash a content-addressed file is named after
+func blobHash(path string) string {
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// renderVariant decodes the image at source, transforms it and writes the result
+// to path, removing the oldest variants of the image beyond the limit. The bytes
+// written count against the cache's budget.
+func (c *imageCache) renderVariant(source, path string, opts VariantOptions) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", filepath.Base(source), err)
+	}
+
+	img = transform(img, opts)
+	var buf bytes.Buffer
+	if opts.Format == FormatJPEG {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode variant: %w", err)
+	}
+
+	if err := osMkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	size, err := writeFileAtomic(path, &buf)
+	if err != nil {
+		return err
+	}
+	freed := pruneVariants(filepath.Dir(path), blobHash(source), maxVariantsPerImage)
+	c.storeVariant(source, size-freed)
+	return nil
+}
+
+// pruneVariants keeps the newest limit variants of the image with the given hash
+// and returns the bytes it freed
+func pruneVariants(dir, hash string, limit int) int64 {
+	paths, err := filepath.Glob(filepath.Join(dir, hash+"-*"))
+	if err != nil || len(paths) <= limit {
+		return 0
+	}
+
+	infos := make(map[string]os.FileInfo, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			infos[path] = info
+		}
+	}
+	modTime := func(path string) int64 {
+		if info := infos[path]; info != nil {
+			return info.ModTime().UnixNano()
+		}
+		return 0
+	}
+	sort.Slice(paths, func(i, j int) bool { return modTime(paths[i]) > modTime(paths[j]) })
+
+	var freed int64
+	for _, path := range paths[limit:] {
+		removeFile(path)
+		if info := infos[path]; info != nil {
+			freed += info.Size()
+		}
+	}
+	return freed
+}
+
+// removeVariants deletes the variants rendered from a content-addressed file
+func removeVariants(cacheDir, source string) {
+	paths, _ := filepath.Glob(filepath.Join(cacheDir, variantDir, blobHash(source)+"-*"))
+	for _, path := range paths {
+		removeFile(path)
+	}
+}
+
+// transform resizes img as opts describe. Images are only ever scaled down: a
+// box larger than the image is shrunk to fit it.
+func transform(img image.Image, opts VariantOptions) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 || (opts.Width == 0 && opts.Height == 0) {
+		return img
+	}
+
+	crop := bounds
+	var dstW, dstH int
+	switch {
+	case opts.Width == 0 || opts.Height == 0 || opts.Fit == FitContain:
+		scale := 1.0
+		if opts.Width > 0 {
+			scale = math.Min(scale, float64(opts.Width)/float64(srcW))
+		}
+		if opts.Height > 0 {
+			scale = math.Min(scale, float64(opts.Height)/float64(srcH))
+		}
+		dstW, dstH = scaled(srcW, scale), scaled(srcH, scale)
+	case opts.Fit == FitCover:
+		scale := math.Max(float64(opts.Width)/float64(srcW), float64(opts.Height)/float64(srcH))
+		dstW, dstH = opts.Width, opts.Height
+		if scale > 1 {
+			dstW, dstH = scaled(dstW, 1/scale), scaled(dstH, 1/scale)
+			scale = 1
+		}
+		cropW, cropH := min(scaled(dstW, 1/scale), srcW), min(scaled(dstH, 1/scale), srcH)
+		x0, y0 := bounds.Min.X+(srcW-cropW)/2, bounds.Min.Y+(srcH-cropH)/2
+		crop = image.Rect(x0, y0, x0+cropW, y0+cropH)
+	default:
+		dstW, dstH = min(opts.Width, srcW), min(opts.Height, srcH)
+	}
+
+	if crop == bounds && dstW == srcW && dstH == srcH {
+		return img
+	}
+	return resample(img, crop, dstW, dstH)
+}
+
+// scaled returns n scaled by factor, rounded and at least 1
+func scaled(n int, factor float64) int {
+	return max(int(math.Round(float64(n)*factor)), 1)
+}
+
+// contribution lists the weights of the source pixels averaged into one
+// destination pixel, starting at source index first
+type contribution struct {
+	first   int
+	weights []float32
+}
+
+// contributions computes the box filter weights mapping srcLen pixels onto
+// dstLen, each destination pixel averaging the source pixels it covers
+func contributions(srcLen, dstLen int) []contribution {
+	scale := float64(srcLen) / float64(dstLen)
+	out := make([]contribution, dstLen)
+	for i := range out {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		first, last := int(start), min(int(math.Ceil(end)), srcLen)
+		weights := make([]float32, last-first)
+		var sum float32
+		for j := first; j < last; j++ {
+			w := float32(math.Min(end, float64(j+1)) - math.Max(start, float64(j)))
+			weights[j-first] = w
+			sum += w
+		}
+		for j := range weights {
+			weights[j] /= sum
+		}
+		out[i] = contribution{first: first, weights: weights}
+	}
+	return out
+}
+
+// resample scales the crop region of img to dstW by dstH with area averaging,
+// first horizontally, then vertically. Pixels are averaged premultiplied so that
+// transparent pixels do not bleed their colour into the result.
+func resample(img image.Image, crop image.Rectangle, dstW, dstH int) *image.RGBA {
+	src := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(src, src.Bounds(), img, crop.Min, draw.Src)
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+
+	// Horizontal pass into a float buffer of dstW by srcH pixels
+	tmp := make([]float32, dstW*srcH*4)
+	columns := contributions(srcW, dstW)
+	for y := 0; y < srcH; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, c := range columns {
+			var r, g, b, a float32
+			for i, w := range c.weights {
+				p := row[(c.first+i)*4:]
+				r += float32(p[0]) * w
+				g += float32(p[1]) * w
+				b += float32(p[2]) * w
+				a += float32(p[3]) * w
+			}
+			t := tmp[(y*dstW+x)*4:]
+			t[0], t[1], t[2], t[3] = r, g, b, a
+		}
+	}
+
+	// Vertical pass into the destination
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	rows := contributions(srcH, dstH)
+	for y, c := range rows {
+		out := dst.Pix[y*dst.Stride:]
+		for x := 0; x < dstW; x++ {
+			var r, g, b, a float32
+			for i, w := range c.weights {
+				t := tmp[((c.first+i)*dstW+x)*4:]
+				r += t[0] * w
+				g += t[1] * w
+				b += t[2] * w
+				a += t[3] * w
+			}
+			p := out[x*4:]
+			p[0], p[1], p[2], p[3] = clampByte(r), clampByte(g), clampByte(b), clampByte(a)
+		}
+	}
+	return dst
+}
+
+// clampByte rounds v to the nearest byte value
+func clampByte(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	default:
+		return uint8(v + 0.5)
+	}
+}
+
+// srcSet returns a srcset attribute offering the cached image at path in each
+// configured width narrower than the original, followed by the original. It is
+// empty for images that cannot be decoded, such as WebP.
+func (c *imageCache) srcSet(path string) string {
+	if len(c.variantWidths) == 0 {
+		return ""
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	cfg, _, err := image.DecodeConfig(file)
+	file.Close()
+	if err != nil {
+		return ""
+	}
+
+	url := "/api/cache/" + filepath.Base(path)
+	var candidates []string
+	for _, width := range c.variantWidths {
+		if width > 0 && width < cfg.Width {
+			candidates = append(candidates, fmt.Sprintf("%s?w=%d %dw", url, width, width))
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	candidates = append(candidates, fmt.Sprintf("%s %dw", url, cfg.Width))
+	return strings.Join(candidates, ", ")
+}
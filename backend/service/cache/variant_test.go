@@ -0,0 +1,294 @@
+package cache
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// halvesPNG encodes a width by height image, red on the left half and blue on the right
+func halvesPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// imageServer serves the same content for every path
+func imageServer(data []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+}
+
+func decodeFile(t *testing.T, path string) (image.Image, string) {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	img, format, err := image.Decode(file)
+	require.NoError(t, err)
+	return img, format
+}
+
+func TestTransform(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	tests := []struct {
+		name string
+		opts VariantOptions
+		want image.Point
+	}{
+		{"width only", VariantOptions{Width: 100, Fit: FitContain}, image.Pt(100, 50)},
+		{"height only", VariantOptions{Height: 50, Fit: FitCover}, image.Pt(100, 50)},
+		{"contain", VariantOptions{Width: 100, Height: 100, Fit: FitContain}, image.Pt(100, 50)},
+		{"cover", VariantOptions{Width: 100, Height: 100, Fit: FitCover}, image.Pt(100, 100)},
+		{"fill", VariantOptions{Width: 100, Height: 100, Fit: FitFill}, image.Pt(100, 100)},
+		{"never scaled up", VariantOptions{Width: 800, Fit: FitContain}, image.Pt(400, 200)},
+		{"cover larger than the image", VariantOptions{Width: 800, Height: 800, Fit: FitCover}, image.Pt(200, 200)},
+		{"fill larger than the image", VariantOptions{Width: 800, Height: 100, Fit: FitFill}, image.Pt(400, 100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, transform(src, tt.opts).Bounds().Size())
+		})
+	}
+}
+
+func TestResampleAveragesPixels(t *testing.T) {
+	img, _ := decodeFile(t, writeTemp(t, halvesPNG(t, 4, 2)))
+
+	// Shrinking to one pixel averages both halves
+	r, g, b, a := resample(img, img.Bounds(), 1, 1).At(0, 0).RGBA()
+	assert.InDelta(t, 0x7f, r>>8, 1)
+	assert.Zero(t, g)
+	assert.InDelta(t, 0x7f, b>>8, 1)
+	assert.Equal(t, uint32(0xffff), a)
+
+	// Covering a square crops to the centre, where both colours remain
+	cover := transform(img, VariantOptions{Width: 2, Height: 2, Fit: FitCover})
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, cover.At(0, 0))
+	assert.Equal(t, color.RGBA{B: 255, A: 255}, cover.At(1, 1))
+}
+
+func writeTemp(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "image.png")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestVariant(t *testing.T) {
+	server := imageServer(halvesPNG(t, 400, 200))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	imgCache, err := NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: tmpDir}}, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+
+	path, err := imgCache.GetOrDownloadImage(setupTestContext(), server.URL+"/splash.png", "map_1_splash")
+	require.NoError(t, err)
+	name := filepath.Base(path)
+
+	// Zero options serve the original
+	file, err := imgCache.Variant(name, VariantOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, path, file.Path)
+
+	// A resized variant is rendered once and stored next to the original
+	file, err = imgCache.Variant(name, VariantOptions{Width: 320})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, variantDir, blobHash(path)+"-320x0-contain.png"), file.Path)
+	img, format := decodeFile(t, file.Path)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, image.Pt(320, 160), img.Bounds().Size())
+
+	again, err := imgCache.Variant(name, VariantOptions{Width: 320, Fit: FitContain})
+	require.NoError(t, err)
+	assert.Equal(t, file.ModTime, again.ModTime)
+
+	// Widths and heights are checked against their own sizes, so a box need not be square
+	file, err = imgCache.Variant(name, VariantOptions{Width: 320, Height: 180, Fit: FitCover})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, variantDir, blobHash(path)+"-320x180-cover.png"), file.Path)
+	img, _ = decodeFile(t, file.Path)
+	assert.Equal(t, image.Pt(320, 180), img.Bounds().Size())
+
+	// A box beyond the original is capped before the variant is named
+	file, err = imgCache.Variant(name, VariantOptions{Width: 320, Height: 360, Fit: FitCover, Format: "jpg"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, variantDir, blobHash(path)+"-178x200-cover.jpg"), file.Path)
+	img, format = decodeFile(t, file.Path)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, image.Pt(178, 200), img.Bounds().Size())
+
+	// Sizes covering the whole image serve the original
+	for _, opts := range []VariantOptions{{Width: 640}, {Width: 640, Height: 360}, {Width: 1280, Height: 720, Fit: FitContain}} {
+		file, err = imgCache.Variant(name, opts)
+		require.NoError(t, err)
+		assert.Equal(t, path, file.Path, opts)
+	}
+
+	// Invalid, unconfigured and unsupported options are rejected
+	_, err = imgCache.Variant(name, VariantOptions{Fit: "stretch"})
+	assert.ErrorIs(t, err, ErrInvalidVariant)
+	_, err = imgCache.Variant(name, VariantOptions{Width: MaxVariantDimension + 1})
+	assert.ErrorIs(t, err, ErrInvalidVariant)
+	_, err = imgCache.Variant(name, VariantOptions{Width: 100})
+	assert.ErrorIs(t, err, ErrInvalidVariant)
+	_, err = imgCache.Variant(name, VariantOptions{Width: 320, Height: 99})
+	assert.ErrorIs(t, err, ErrInvalidVariant)
+	_, err = imgCache.Variant(name, VariantOptions{Height: 320})
+	assert.ErrorIs(t, err, ErrInvalidVariant)
+	_, err = imgCache.Variant(name, VariantOptions{Format: FormatWebP})
+	assert.ErrorIs(t, err, ErrUnsupportedVariant)
+	_, err = imgCache.Variant("missing.png", VariantOptions{Width: 320})
+	assert.ErrorIs(t, err, ErrNotCached)
+}
+
+func TestVariantBytesCountAgainstBudget(t *testing.T) {
+	icons := imageServer(halvesPNG(t, 800, 400))
+	defer icons.Close()
+	splashes := imageServer(halvesPNG(t, 1600, 800))
+	defer splashes.Close()
+
+	imgCache, err := NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: t.TempDir()}}, http.DefaultClient)
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+	testCtx := setupTestContext()
+
+	icon, err := imgCache.GetOrDownloadImage(testCtx, icons.URL+"/icon.png", "map_1_icon")
+	require.NoError(t, err)
+	splash, err := imgCache.GetOrDownloadImage(testCtx, splashes.URL+"/splash.png", "map_1_splash")
+	require.NoError(t, err)
+	images := imgCache.Metrics().Bytes
+
+	variant, err := imgCache.Variant(filepath.Base(splash), VariantOptions{Width: 1280})
+	require.NoError(t, err)
+	assert.Equal(t, images+variant.Size, imgCache.Metrics().Bytes)
+
+	// Once the budget is full, further variants evict the least recently used image
+	cache := imgCache.(*imageCache)
+	cache.mutex.Lock()
+	cache.index.maxBytes = images + variant.Size
+	cache.mutex.Unlock()
+
+	_, err = imgCache.Variant(filepath.Base(splash), VariantOptions{Width: 640})
+	require.NoError(t, err)
+	assert.Equal(t, 1, imgCache.Metrics().Entries)
+	assert.NoFileExists(t, icon)
+	assert.LessOrEqual(t, imgCache.Metrics().Bytes, cache.index.maxBytes)
+}
+
+func TestVariantPassesWebPThrough(t *testing.T) {
+	server := imageServer([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "))
+	defer server.Close()
+
+	imgCache, err := NewImageCache(&config.Config{Cache: config.CacheConfig{Directory: t.TempDir()}}, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+
+	path, err := imgCache.GetOrDownloadImage(setupTestContext(), server.URL+"/splash.webp", "map_1_splash")
+	require.NoError(t, err)
+
+	file, err := imgCache.Variant(filepath.Base(path), VariantOptions{Width: 320, Format: FormatWebP})
+	require.NoError(t, err)
+	assert.Equal(t, path, file.Path)
+
+	_, err = imgCache.Variant(filepath.Base(path), VariantOptions{Format: FormatPNG})
+	assert.ErrorIs(t, err, ErrUnsupportedVariant)
+}
+
+func TestVariantsFollowTheirImage(t *testing.T) {
+	server := imageServer(halvesPNG(t, 400, 200))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	cfg := &config.Config{Cache: config.CacheConfig{Directory: tmpDir}}
+	imgCache, err := NewImageCache(cfg, server.Client())
+	require.NoError(t, err)
+
+	path, err := imgCache.GetOrDownloadImage(setupTestContext(), server.URL+"/splash.png", "map_1_splash")
+	require.NoError(t, err)
+	variant, err := imgCache.Variant(filepath.Base(path), VariantOptions{Width: 320})
+	require.NoError(t, err)
+
+	// Variants of images no longer cached are dropped at startup
+	orphan := filepath.Join(tmpDir, variantDir, strings.Repeat("0", 64)+"-320x0-contain.png")
+	require.NoError(t, os.WriteFile(orphan, []byte("stale"), 0644))
+	imgCache.Shutdown()
+
+	imgCache, err = NewImageCache(cfg, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+	assert.FileExists(t, variant.Path)
+	assert.NoFileExists(t, orphan)
+
+	// Replacing an image, as revalidation does, removes the variants of the old one
+	replacement, size, err := writeBlob(tmpDir, bytes.NewReader(halvesPNG(t, 2, 2)), ".png")
+	require.NoError(t, err)
+	imgCache.(*imageCache).store("map_1_splash", replacement, size, true)
+	assert.NoFileExists(t, path)
+	assert.NoFileExists(t, variant.Path)
+}
+
+func TestPruneVariants(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"abc-1x0-contain.png", "abc-2x0-contain.png", "abc-3x0-contain.png", "def-1x0-contain.png"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	old := filepath.Join(dir, "abc-1x0-contain.png")
+	require.NoError(t, os.Chtimes(old, time.Unix(0, 0), time.Unix(0, 0)))
+
+	pruneVariants(dir, "abc", 2)
+	assert.Equal(t, []string{"abc-2x0-contain.png", "abc-3x0-contain.png", "def-1x0-contain.png"}, dirNames(t, dir))
+}
+
+func TestCacheMapImagesSrcSet(t *testing.T) {
+	server := imageServer(halvesPNG(t, 800, 400))
+	defer server.Close()
+
+	cfg := &config.Config{Cache: config.CacheConfig{Directory: t.TempDir(), VariantWidths: []int{320, 640, 1280}}}
+	imgCache, err := NewImageCache(cfg, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+
+	maps, err := imgCache.CacheMapImages(setupTestContext(), []domain.Map{
+		{UUID: "1", Splash: server.URL + "/splash.png", DisplayIcon: server.URL + "/icon.png"},
+	})
+	require.NoError(t, err)
+
+	splash := maps[0].Splash
+	assert.Equal(t, splash+"?w=320 320w, "+splash+"?w=640 640w, "+splash+" 800w", maps[0].SplashSrcSet)
+	assert.NotEmpty(t, maps[0].DisplayIconSrcSet)
+
+	// Without configured widths no srcset is offered
+	cfg.Cache.VariantWidths = []int{}
+	imgCache, err = NewImageCache(cfg, server.Client())
+	require.NoError(t, err)
+	defer imgCache.Shutdown()
+	maps, err = imgCache.CacheMapImages(setupTestContext(), []domain.Map{{UUID: "1", Splash: server.URL + "/splash.png"}})
+	require.NoError(t, err)
+	assert.Empty(t, maps[0].SplashSrcSet)
+}
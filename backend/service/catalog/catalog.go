@@ -0,0 +1,294 @@
+package catalog
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/source"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCatalogEmpty = errors.New("map catalog is empty")
+)
+
+// Fetcher loads the full map list from an upstream source. Maps served by a
+// fallback source come with an error wrapping source.ErrFallback.
+type Fetcher func(ctx ctx.CTX) ([]domain.Map, error)
+
+// Status describes the freshness of the catalog contents
+type Status struct {
+	MapCount      int       `json:"mapCount" example:"12"`
+	LastRefreshed time.Time `json:"lastRefreshed" example:"2023-07-01T12:34:56Z"`
+	Age           string    `json:"age" example:"2m3s"`
+	Stale         bool      `json:"stale" example:"false"`
+	LastError     string    `json:"lastError,omitempty" example:"failed to make API request"`
+}
+
+// MapCatalog keeps the last good map list in memory
+type MapCatalog interface {
+	// Maps returns the cached map list, fetching it first if the catalog is empty
+	Maps(ctx ctx.CTX) ([]domain.Map, error)
+
+	// Refresh fetches the map list from upstream and replaces the cached copy on
+	// success; fallback maps are only taken while the catalog is empty
+	Refresh(ctx ctx.CTX) error
+
+	// Age returns how long ago the catalog was last refreshed successfully
+	Age() time.Duration
+
+	// Status returns a snapshot of the catalog freshness
+	Status() Status
+
+	// Start begins loading the catalog, waiting a bounded time for the first load,
+	// then refreshes it in the background
+	Start()
+
+	// Stop halts the background refresh
+	Stop()
+}
+
+type mapCatalog struct {
+	fetch           Fetcher
+	ttl             time.Duration
+	refreshInterval time.Duration
+	fetchTimeout    time.Duration
+	startupTimeout  time.Duration
+
+	mutex         sync.RWMutex
+	maps          []domain.Map
+	lastRefreshed time.Time
+	lastErr       error
+
+	// refreshMutex guards inflight, the refresh callers currently share
+	refreshMutex sync.Mutex
+	inflight     *refreshCall
+
+	stopOnce sync.Once
+	quit     chan struct{}
+	now      func() time.Time
+}
+
+// refreshCall is a refresh in progress, finished once done is closed
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// New creates a map catalog backed by the given fetcher
+func New(fetch Fetcher, cfg config.CatalogConfig) MapCatalog {
+	return &mapCatalog{
+		fetch:           fetch,
+		ttl:             cfg.TTL,
+		refreshInterval: cfg.RefreshInterval,
+		fetchTimeout:    cfg.FetchTimeout,
+		startupTimeout:  cfg.StartupTimeout,
+		quit:            make(chan struct{}),
+		now:             time.Now,
+	}
+}
+
+// Maps returns the cached map list, fetching it first if the catalog is empty.
+// Concurrent callers of an empty catalog share one fetch, which does not depend on
+// any of their contexts: a caller that goes away only stops waiting. Expired data
+// is still served while a refresh runs in the background.
+func (c *mapCatalog) Maps(ctx ctx.CTX) ([]domain.Map, error) {
+	c.mutex.RLock()
+	maps := c.maps
+	stale := c.isStale()
+	c.mutex.RUnlock()
+
+	if len(maps) == 0 {
+		err := c.refreshShared(ctx)
+		c.mutex.RLock()
+		maps = c.maps
+		c.mutex.RUnlock()
+		if len(maps) == 0 {
+			return nil, err
+		}
+		return copyMaps(maps), nil
+	}
+
+	if stale {
+		ctx.FieldLogger.WithField("age", c.Age().String()).Debug("Serving stale map catalog while refreshing")
+		go c.refreshAsync()
+	}
+
+	return copyMaps(maps), nil
+}
+
+// Refresh fetches the map list from upstream and replaces the cached copy on
+// success. Fallback maps are only taken while the catalog is empty, and never
+// count as a refresh, so that the last good upstream data keeps being served
+// and the catalog shows as stale until upstream recovers.
+func (c *mapCatalog) Refresh(ctx ctx.CTX) error {
+	maps, err := c.fetch(ctx)
+	if err == nil && len(maps) == 0 {
+		err = ErrCatalogEmpty
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if errors.Is(err, source.ErrFallback) && len(c.maps) == 0 && len(maps) > 0 {
+		c.maps = maps
+		c.lastErr = err
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"error":     err,
+			"map_count": len(maps),
+		}).Warn("Map catalog loaded from fallback source")
+		return err
+	}
+
+	if err != nil {
+		c.lastErr = err
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"error":      err,
+			"cached_len": len(c.maps),
+		}).Warn("Failed to refresh map catalog")
+		return err
+	}
+
+	c.maps = maps
+	c.lastRefreshed = c.now()
+	c.lastErr = nil
+
+	ctx.FieldLogger.WithField("map_count", len(maps)).Info("Map catalog refreshed")
+	return nil
+}
+
+// Age returns how long ago the catalog was last refreshed successfully
+func (c *mapCatalog) Age() time.Duration {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.lastRefreshed.IsZero() {
+		return 0
+	}
+	return c.now().Sub(c.lastRefreshed)
+}
+
+// Status returns a snapshot of the catalog freshness
+func (c *mapCatalog) Status() Status {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	status := Status{
+		MapCount:      len(c.maps),
+		LastRefreshed: c.lastRefreshed,
+		Stale:         c.isStale(),
+	}
+	if !c.lastRefreshed.IsZero() {
+		status.Age = c.now().Sub(c.lastRefreshed).Round(time.Second).String()
+	}
+	if c.lastErr != nil {
+		status.LastError = c.lastErr.Error()
+	}
+	return status
+}
+
+// Start begins loading the catalog and waits up to the startup timeout for it, so
+// that the first requests are usually served from memory without startup hanging
+// on an unreachable upstream. The load goes on in the background after that, and
+// the catalog is refreshed every refresh interval.
+func (c *mapCatalog) Start() {
+	call := c.startRefresh()
+	if c.startupTimeout > 0 {
+		timer := time.NewTimer(c.startupTimeout)
+		select {
+		case <-call.done:
+		case <-timer.C:
+			logrus.WithField("timeout", c.startupTimeout.String()).Warn("Map catalog is still loading, continuing startup without it")
+		}
+		timer.Stop()
+	}
+	if c.refreshInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.refreshAsync()
+			case <-c.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the background refresh
+func (c *mapCatalog) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.quit)
+	})
+}
+
+// refreshAsync starts a refresh unless another one is already running
+func (c *mapCatalog) refreshAsync() {
+	c.startRefresh()
+}
+
+// refreshShared waits for the running refresh, starting one if needed, and returns
+// its result. It stops waiting when ctx is done, leaving the refresh to finish for
+// the other callers.
+func (c *mapCatalog) refreshShared(ctx ctx.CTX) error {
+	call := c.startRefresh()
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.StdContext().Done():
+		return ctx.StdContext().Err()
+	}
+}
+
+// startRefresh returns the running refresh, or starts one on a background context
+// bounded by the fetch timeout
+func (c *mapCatalog) startRefresh() *refreshCall {
+	c.refreshMutex.Lock()
+	defer c.refreshMutex.Unlock()
+	if c.inflight != nil {
+		return c.inflight
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	c.inflight = call
+	go func() {
+		fetchCtx := ctx.Background()
+		if c.fetchTimeout > 0 {
+			var cancel func()
+			fetchCtx, cancel = ctx.WithTimeout(fetchCtx, c.fetchTimeout)
+			defer cancel()
+		}
+		call.err = c.Refresh(fetchCtx)
+
+		c.refreshMutex.Lock()
+		c.inflight = nil
+		c.refreshMutex.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// isStale reports whether the cached data is older than the TTL; callers must hold the mutex
+func (c *mapCatalog) isStale() bool {
+	if c.lastRefreshed.IsZero() {
+		return true
+	}
+	return c.ttl > 0 && c.now().Sub(c.lastRefreshed) > c.ttl
+}
+
+// copyMaps returns a copy of the map list so callers cannot modify the cached slice
+func copyMaps(maps []domain.Map) []domain.Map {
+	result := make([]domain.Map, len(maps))
+	copy(result, maps)
+	return result
+}
@@ -0,0 +1,310 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/source"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestContext creates a context for testing
+func setupTestContext() ctx.CTX {
+	logger := logrus.New()
+	logger.Out = io.Discard // Suppress logging during tests
+	return ctx.CTX{FieldLogger: logrus.NewEntry(logger)}
+}
+
+// testMaps returns a small map list for catalog tests
+func testMaps() []domain.Map {
+	return []domain.Map{
+		{UUID: "map1", DisplayName: "Map One"},
+		{UUID: "map2", DisplayName: "Map Two"},
+	}
+}
+
+func TestMaps_FetchesOnFirstUse(t *testing.T) {
+	var calls int32
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		atomic.AddInt32(&calls, 1)
+		return testMaps(), nil
+	}, config.CatalogConfig{TTL: time.Hour})
+
+	testCtx := setupTestContext()
+
+	maps, err := c.Maps(testCtx)
+	require.NoError(t, err)
+	assert.Len(t, maps, 2)
+
+	// Second call is served from memory
+	maps, err = c.Maps(testCtx)
+	require.NoError(t, err)
+	assert.Len(t, maps, 2)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMaps_ColdCallersShareOneFetch(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return testMaps(), nil
+	}, config.CatalogConfig{TTL: time.Hour})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			maps, err := c.Maps(setupTestContext())
+			assert.NoError(t, err)
+			assert.Len(t, maps, 2)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond) // Let the other callers reach the catalog
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMaps_ReturnsCopy(t *testing.T) {
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		return testMaps(), nil
+	}, config.CatalogConfig{TTL: time.Hour})
+
+	testCtx := setupTestContext()
+
+	maps, err := c.Maps(testCtx)
+	require.NoError(t, err)
+	maps[0].DisplayName = "Modified"
+
+	maps, err = c.Maps(testCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "Map One", maps[0].DisplayName)
+}
+
+func TestMaps_EmptyCatalogError(t *testing.T) {
+	fetchErr := errors.New("upstream down")
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		return nil, fetchErr
+	}, config.CatalogConfig{TTL: time.Hour})
+
+	maps, err := c.Maps(setupTestContext())
+	assert.ErrorIs(t, err, fetchErr)
+	assert.Nil(t, maps)
+	assert.Equal(t, "upstream down", c.Status().LastError)
+
+	// An empty upstream response is also an error
+	c = New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		return []domain.Map{}, nil
+	}, config.CatalogConfig{TTL: time.Hour})
+
+	_, err = c.Maps(setupTestContext())
+	assert.ErrorIs(t, err, ErrCatalogEmpty)
+}
+
+func TestMaps_ServesStaleWhenUpstreamFails(t *testing.T) {
+	var fail atomic.Bool
+	refreshed := make(chan struct{}, 1)
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		defer func() {
+			select {
+			case refreshed <- struct{}{}:
+			default:
+			}
+		}()
+		if fail.Load() {
+			return nil, errors.New("upstream down")
+		}
+		return testMaps(), nil
+	}, config.CatalogConfig{TTL: time.Minute})
+
+	// Control the clock so the data can be expired
+	now := time.Now()
+	impl := c.(*mapCatalog)
+	impl.now = func() time.Time { return now }
+
+	testCtx := setupTestContext()
+	_, err := c.Maps(testCtx)
+	require.NoError(t, err)
+	<-refreshed
+
+	// Expire the data and break the upstream
+	fail.Store(true)
+	impl.mutex.Lock()
+	impl.now = func() time.Time { return now.Add(2 * time.Minute) }
+	impl.mutex.Unlock()
+
+	maps, err := c.Maps(testCtx)
+	require.NoError(t, err)
+	assert.Len(t, maps, 2)
+
+	// Wait for the background refresh attempt to finish
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("background refresh was not triggered")
+	}
+	assert.Eventually(t, func() bool {
+		return c.Status().LastError != ""
+	}, time.Second, 10*time.Millisecond)
+
+	status := c.Status()
+	assert.True(t, status.Stale)
+	assert.Equal(t, 2, status.MapCount)
+	assert.Equal(t, "2m0s", status.Age)
+	assert.Equal(t, 2*time.Minute, c.Age())
+}
+
+func TestRefresh_FallbackKeepsUpstreamMaps(t *testing.T) {
+	fallback := []domain.Map{{UUID: "static", DisplayName: "Static"}}
+	fallbackErr := fmt.Errorf("%w from static: upstream down", source.ErrFallback)
+	var useFallback atomic.Bool
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		if useFallback.Load() {
+			return fallback, fallbackErr
+		}
+		return testMaps(), nil
+	}, config.CatalogConfig{TTL: time.Minute})
+
+	now := time.Now()
+	impl := c.(*mapCatalog)
+	impl.now = func() time.Time { return now }
+	testCtx := setupTestContext()
+	require.NoError(t, c.Refresh(testCtx))
+
+	// An outage answered by a fallback leaves the upstream maps and their age alone
+	useFallback.Store(true)
+	impl.now = func() time.Time { return now.Add(2 * time.Minute) }
+	assert.ErrorIs(t, c.Refresh(testCtx), source.ErrFallback)
+
+	maps, err := c.Maps(testCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "map1", maps[0].UUID)
+	status := c.Status()
+	assert.True(t, status.Stale)
+	assert.Equal(t, "2m0s", status.Age)
+	assert.Contains(t, status.LastError, "upstream down")
+}
+
+func TestMaps_ColdStartTakesFallback(t *testing.T) {
+	fallbackErr := fmt.Errorf("%w from static: upstream down", source.ErrFallback)
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		return []domain.Map{{UUID: "static"}}, fallbackErr
+	}, config.CatalogConfig{TTL: time.Hour})
+
+	maps, err := c.Maps(setupTestContext())
+	require.NoError(t, err)
+	assert.Equal(t, "static", maps[0].UUID)
+
+	// The fallback is not mistaken for fresh data
+	status := c.Status()
+	assert.True(t, status.LastRefreshed.IsZero())
+	assert.True(t, status.Stale)
+	assert.NotEmpty(t, status.LastError)
+}
+
+func TestStart_LoadsCatalog(t *testing.T) {
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		return testMaps(), nil
+	}, config.CatalogConfig{TTL: time.Hour, StartupTimeout: time.Second})
+
+	// Without a refresh interval the catalog is still loaded before Start returns
+	c.Start()
+	defer c.Stop()
+	assert.Equal(t, 2, c.Status().MapCount)
+	assert.False(t, c.Status().Stale)
+}
+
+func TestStart_DoesNotWaitPastStartupTimeout(t *testing.T) {
+	release := make(chan struct{})
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		<-release
+		return testMaps(), nil
+	}, config.CatalogConfig{TTL: time.Hour, StartupTimeout: 20 * time.Millisecond})
+
+	started := time.Now()
+	c.Start()
+	defer c.Stop()
+	assert.Less(t, time.Since(started), time.Second)
+	assert.Equal(t, 0, c.Status().MapCount)
+
+	// The load carries on in the background
+	close(release)
+	assert.Eventually(t, func() bool { return c.Status().MapCount == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestMaps_CancelledCallerDoesNotCancelSharedFetch(t *testing.T) {
+	release := make(chan struct{})
+	fetchErr := make(chan error, 1)
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		<-release
+		fetchErr <- ctx.Err()
+		return testMaps(), nil
+	}, config.CatalogConfig{TTL: time.Hour, FetchTimeout: time.Minute})
+
+	// The first caller disconnects while the fetch it started is running
+	first, cancel := ctx.WithCancel(ctx.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Maps(first)
+		done <- err
+	}()
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// A caller still waiting gets the maps
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	maps, err := c.Maps(setupTestContext())
+	require.NoError(t, err)
+	assert.Len(t, maps, 2)
+	assert.NoError(t, <-fetchErr)
+}
+
+func TestStartStop_BackgroundRefresh(t *testing.T) {
+	var calls int32
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		atomic.AddInt32(&calls, 1)
+		return testMaps(), nil
+	}, config.CatalogConfig{TTL: time.Hour, RefreshInterval: 10 * time.Millisecond})
+
+	c.Start()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&calls) >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	c.Stop()
+	c.Stop() // Stopping twice must not panic
+
+	assert.Equal(t, 2, c.Status().MapCount)
+	assert.False(t, c.Status().Stale)
+}
+
+func TestStatus_Empty(t *testing.T) {
+	c := New(func(ctx ctx.CTX) ([]domain.Map, error) {
+		return testMaps(), nil
+	}, config.CatalogConfig{})
+
+	status := c.Status()
+	assert.Equal(t, 0, status.MapCount)
+	assert.True(t, status.Stale)
+	assert.Empty(t, status.Age)
+	assert.Equal(t, time.Duration(0), c.Age())
+}
@@ -0,0 +1,132 @@
+package history
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+)
+
+const (
+	// DefaultQueueSize bounds the draws waiting to be written by an AsyncStore
+	DefaultQueueSize = 1024
+
+	// maxBatch bounds the draw records written in one transaction
+	maxBatch = 256
+)
+
+var (
+	ErrQueueFull = errors.New("draw history queue is full")
+	ErrClosed    = errors.New("draw history store is closed")
+)
+
+var (
+	_ Store = (*AsyncStore)(nil)
+)
+
+// AsyncStore records draws in the background so that drawing never waits on the
+// database. Queued draws are written by a single worker, combined into as few
+// transactions as the backlog allows. Reads go straight to the wrapped store, so
+// a draw may take a moment to appear in them.
+type AsyncStore struct {
+	Store
+	queue chan []Draw
+	// mutex keeps Close from closing queue during a send
+	mutex  sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewAsyncStore starts writing draws to store in the background, holding up to
+// size records in its queue; a size below 1 uses DefaultQueueSize
+func NewAsyncStore(store Store, size int) *AsyncStore {
+	if size < 1 {
+		size = DefaultQueueSize
+	}
+	a := &AsyncStore{
+		Store: store,
+		queue: make(chan []Draw, size),
+		done:  make(chan struct{}),
+	}
+	go a.write()
+	return a
+}
+
+// Record queues the draws to be saved together, failing with ErrQueueFull
+// rather than waiting when the queue has no room
+func (a *AsyncStore) Record(ctx ctx.CTX, draws ...Draw) error {
+	if len(draws) == 0 {
+		return nil
+	}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.closed {
+		return ErrClosed
+	}
+	select {
+	case a.queue <- draws:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting draws and waits until the queued ones are written
+func (a *AsyncStore) Close() {
+	a.mutex.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mutex.Unlock()
+	<-a.done
+}
+
+// write saves queued draws until the queue is closed and drained
+func (a *AsyncStore) write() {
+	defer close(a.done)
+
+	for draws := range a.queue {
+		batch := [][]Draw{draws}
+		size := len(draws)
+	collect:
+		for size < maxBatch {
+			select {
+			case more, ok := <-a.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, more)
+				size += len(more)
+			default:
+				break collect
+			}
+		}
+		a.save(batch, size)
+	}
+}
+
+// save writes a batch in one transaction. If that fails, each draw of the batch
+// is written on its own, so that one bad record does not lose the others.
+func (a *AsyncStore) save(batch [][]Draw, size int) {
+	c := ctx.Background()
+	all := make([]Draw, 0, size)
+	for _, draws := range batch {
+		all = append(all, draws...)
+	}
+	err := a.Store.Record(c, all...)
+	if err == nil {
+		return
+	}
+
+	if len(batch) > 1 {
+		for _, draws := range batch {
+			if err := a.Store.Record(c, draws...); err != nil {
+				c.FieldLogger.WithError(err).WithField("draws", len(draws)).Warn("Failed to record draw history")
+			}
+		}
+		return
+	}
+	c.FieldLogger.WithError(err).WithField("draws", len(all)).Warn("Failed to record draw history")
+}
@@ -0,0 +1,63 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrDisabled     = errors.New("draw history is not enabled")
+	ErrInvalidQuery = errors.New("invalid history query")
+)
+
+type HistoryService struct {
+	store Store
+}
+
+// NewService creates a history service reading from store; a nil store means history is disabled
+func NewService(store Store) Service {
+	return &HistoryService{store: store}
+}
+
+// List returns the draws matching the query, newest first
+func (h *HistoryService) List(ctx ctx.CTX, query Query) (*Page, error) {
+	if h.store == nil {
+		return nil, ErrDisabled
+	}
+
+	if query.Limit == 0 {
+		query.Limit = DefaultLimit
+	}
+	if query.Limit < 0 || query.Limit > MaxLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxLimit)
+	}
+	if query.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidQuery)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+
+	draws, total, err := h.store.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"session_id": query.SessionID,
+		"map":        query.Map,
+		"returned":   len(draws),
+		"total":      total,
+	}).Debug("Listed draw history")
+
+	return &Page{
+		Draws:  draws,
+		Total:  total,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}, nil
+}
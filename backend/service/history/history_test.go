@@ -0,0 +1,249 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore returns a store on a migrated SQLite database in a temporary directory
+func newTestStore(t *testing.T) *SQLStore {
+	db, err := database.Open(database.DriverSQLite, filepath.Join(t.TempDir(), "history.db"), database.Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, database.NewMigrationManager(db, database.SQLite).ApplyMigrations(Migrations))
+	return NewSQLStore(db, database.DriverSQLite)
+}
+
+func TestSQLStore(t *testing.T) {
+	store := newTestStore(t)
+	c := ctx.Background()
+	day := time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC)
+
+	require.NoError(t, store.Record(c,
+		Draw{ID: "a", SessionID: "scrims", MapUUID: "uuid-ascent", MapName: "Ascent", Kind: KindSingle, Position: 1, Seed: 1, DrawnAt: day},
+		Draw{ID: "b", MapUUID: "uuid-bind", MapName: "Bind", Kind: KindSingle, Position: 1, Seed: -2, DrawnAt: day.Add(time.Hour)},
+	))
+	// A series shares its seed and time and keeps its play order
+	require.NoError(t, store.Record(c,
+		Draw{ID: "c", SessionID: "scrims", MapUUID: "uuid-split", MapName: "Split", Kind: KindSeries, Position: 1, Pool: "competitive", Seed: 3, DrawnAt: day.Add(24 * time.Hour)},
+		Draw{ID: "d", SessionID: "scrims", MapUUID: "uuid-ascent", MapName: "Ascent", Kind: KindSeries, Position: 2, Pool: "competitive", Seed: 3, DrawnAt: day.Add(24 * time.Hour)},
+	))
+	assert.NoError(t, store.Record(c))
+
+	// The draw filter and candidates round-trip
+	filtered := Draw{ID: "f", MapUUID: "uuid-lotus", MapName: "Lotus", Kind: KindSingle, Position: 1, DrawnAt: day.Add(-time.Hour),
+		Banned: []string{"uuid-bind", "uuid,split"}, StandardOnly: true, Weighted: true, Candidates: []string{"uuid-ascent", "uuid-lotus"}}
+	require.NoError(t, store.Record(c, filtered))
+	draws, _, err := store.List(c, Query{Map: "Lotus", Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []Draw{filtered}, draws)
+	_, err = store.db.Exec("DELETE FROM draw_history WHERE id = 'f'")
+	require.NoError(t, err)
+
+	ids := func(draws []Draw) []string {
+		out := []string{}
+		for _, d := range draws {
+			out = append(out, d.ID)
+		}
+		return out
+	}
+
+	// Newest first, series in play order
+	draws, total, err := store.List(c, Query{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []string{"c", "d", "b", "a"}, ids(draws))
+	assert.Equal(t, Draw{ID: "d", SessionID: "scrims", MapUUID: "uuid-ascent", MapName: "Ascent", Kind: KindSeries, Position: 2, Pool: "competitive", Seed: 3, DrawnAt: day.Add(24 * time.Hour)}, draws[1])
+	assert.Equal(t, int64(-2), draws[2].Seed)
+
+	// Pagination keeps the total
+	draws, total, err = store.List(c, Query{Limit: 2, Offset: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []string{"b", "a"}, ids(draws))
+
+	// Filters combine
+	draws, total, err = store.List(c, Query{SessionID: "scrims", Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []string{"c", "d", "a"}, ids(draws))
+
+	draws, _, err = store.List(c, Query{Map: "ascent", Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"d", "a"}, ids(draws))
+
+	draws, _, err = store.List(c, Query{Map: "uuid-bind", Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids(draws))
+
+	draws, _, err = store.List(c, Query{From: day.Add(time.Hour), To: day.Add(24 * time.Hour), Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids(draws))
+
+	draws, total, err = store.List(c, Query{SessionID: "nobody", Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, draws)
+
+	// Each walks the filtered draws oldest first, ignoring pagination
+	var walked []Draw
+	assert.NoError(t, store.Each(c, Query{SessionID: "scrims", Limit: 1, Offset: 1}, func(d Draw) error {
+		walked = append(walked, d)
+		return nil
+	}))
+	assert.Equal(t, []string{"a", "c", "d"}, ids(walked))
+
+	stop := errors.New("stop")
+	assert.ErrorIs(t, store.Each(c, Query{}, func(Draw) error { return stop }), stop)
+
+	// Duplicate IDs roll back the whole batch
+	err = store.Record(c,
+		Draw{ID: "e", MapUUID: "uuid-haven", MapName: "Haven", Kind: KindSeries, Position: 1, DrawnAt: day},
+		Draw{ID: "a", MapUUID: "uuid-haven", MapName: "Haven", Kind: KindSeries, Position: 2, DrawnAt: day},
+	)
+	assert.Error(t, err)
+	_, total, err = store.List(c, Query{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+}
+
+// stubStore returns canned results and remembers the last query
+type stubStore struct {
+	draws []Draw
+	total int
+	err   error
+	query Query
+}
+
+func (s *stubStore) Record(ctx ctx.CTX, draws ...Draw) error {
+	return s.err
+}
+
+func (s *stubStore) List(ctx ctx.CTX, query Query) ([]Draw, int, error) {
+	s.query = query
+	return s.draws, s.total, s.err
+}
+
+func (s *stubStore) Each(ctx ctx.CTX, query Query, fn func(Draw) error) error {
+	return s.err
+}
+
+func TestHistoryServiceList(t *testing.T) {
+	c := ctx.Background()
+	store := &stubStore{draws: []Draw{{ID: "a"}}, total: 12}
+	service := NewService(store)
+
+	// The default page size applies
+	page, err := service.List(c, Query{SessionID: "scrims", Offset: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, &Page{Draws: []Draw{{ID: "a"}}, Total: 12, Limit: DefaultLimit, Offset: 5}, page)
+	assert.Equal(t, Query{SessionID: "scrims", Limit: DefaultLimit, Offset: 5}, store.query)
+
+	// Invalid queries never reach the store
+	now := time.Now()
+	for _, query := range []Query{
+		{Limit: MaxLimit + 1},
+		{Limit: -1},
+		{Offset: -1},
+		{From: now, To: now},
+		{From: now, To: now.Add(-time.Hour)},
+	} {
+		_, err := service.List(c, query)
+		assert.ErrorIs(t, err, ErrInvalidQuery)
+	}
+
+	// Store errors are passed through
+	store.err = errors.New("connection refused")
+	_, err = service.List(c, Query{})
+	assert.EqualError(t, err, "connection refused")
+
+	// Without a store history is disabled
+	_, err = NewService(nil).List(c, Query{})
+	assert.ErrorIs(t, err, ErrDisabled)
+}
+
+func TestMigrationsRollback(t *testing.T) {
+	store := newTestStore(t)
+	manager := database.NewMigrationManager(store.db, database.SQLite)
+
+	require.NoError(t, manager.RollbackTo(Migrations, 0))
+	var tables int
+	require.NoError(t, store.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'draw_history'").Scan(&tables))
+	assert.Equal(t, 0, tables)
+
+	// The schema can be rebuilt after a full rollback
+	require.NoError(t, manager.ApplyMigrations(Migrations))
+	assert.NoError(t, store.Record(ctx.Background(), Draw{ID: "a", MapUUID: "uuid-ascent", MapName: "Ascent", Kind: KindSingle, Position: 1, DrawnAt: time.Now()}))
+}
+
+func TestMigrationsDefaultEmptyLists(t *testing.T) {
+	store := newTestStore(t)
+
+	// Rows recorded before the filter columns existed read back without lists
+	_, err := store.db.Exec(`INSERT INTO draw_history (id, map_uuid, map_name, kind, position, seed, drawn_at)
+		VALUES ('a', 'uuid-ascent', 'Ascent', 'single', 1, 0, ?)`, time.Now().UTC())
+	require.NoError(t, err)
+
+	draws, _, err := store.List(ctx.Background(), Query{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, draws, 1)
+	assert.Nil(t, draws[0].Banned)
+	assert.Nil(t, draws[0].Candidates)
+}
+
+// blockingStore records the batches it receives, holding each write until release is closed
+type blockingStore struct {
+	stubStore
+	release chan struct{}
+	writes  atomic.Int32
+	mutex   sync.Mutex
+	batches [][]Draw
+}
+
+func (s *blockingStore) Record(ctx ctx.CTX, draws ...Draw) error {
+	s.writes.Add(1)
+	<-s.release
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batches = append(s.batches, draws)
+	return nil
+}
+
+func TestAsyncStore(t *testing.T) {
+	c := ctx.Background()
+	store := &blockingStore{stubStore: stubStore{draws: []Draw{{ID: "a"}}, total: 1}, release: make(chan struct{})}
+	async := NewAsyncStore(store, 2)
+
+	// The worker holds the first draw while two more fill the queue
+	require.NoError(t, async.Record(c, Draw{ID: "1"}))
+	assert.Eventually(t, func() bool { return store.writes.Load() == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, async.Record(c, Draw{ID: "2"}))
+	require.NoError(t, async.Record(c, Draw{ID: "3"}, Draw{ID: "4"}))
+	assert.ErrorIs(t, async.Record(c, Draw{ID: "5"}), ErrQueueFull)
+
+	// Reads go to the wrapped store
+	draws, total, err := async.List(c, Query{})
+	require.NoError(t, err)
+	assert.Equal(t, []Draw{{ID: "a"}}, draws)
+	assert.Equal(t, 1, total)
+
+	// Close writes what is queued, combining it into one batch
+	close(store.release)
+	async.Close()
+	assert.Equal(t, [][]Draw{
+		{{ID: "1"}},
+		{{ID: "2"}, {ID: "3"}, {ID: "4"}},
+	}, store.batches)
+	assert.ErrorIs(t, async.Record(c, Draw{ID: "6"}), ErrClosed)
+	async.Close()
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS draw_history;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS draw_history (
+	id VARCHAR(36) PRIMARY KEY,
+	session_id VARCHAR(255) NOT NULL DEFAULT '',
+	map_uuid VARCHAR(64) NOT NULL,
+	map_name VARCHAR(255) NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	position INTEGER NOT NULL,
+	pool VARCHAR(32) NOT NULL DEFAULT '',
+	seed BIGINT NOT NULL,
+	drawn_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_draw_history_drawn_at ON draw_history (drawn_at);
+CREATE INDEX IF NOT EXISTS idx_draw_history_session ON draw_history (session_id, drawn_at);
+CREATE INDEX IF NOT EXISTS idx_draw_history_map ON draw_history (map_uuid, drawn_at);
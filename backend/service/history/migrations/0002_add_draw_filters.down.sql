@@ -0,0 +1,4 @@
+ALTER TABLE draw_history DROP COLUMN candidates;
+ALTER TABLE draw_history DROP COLUMN weighted;
+ALTER TABLE draw_history DROP COLUMN standard_only;
+ALTER TABLE draw_history DROP COLUMN banned;
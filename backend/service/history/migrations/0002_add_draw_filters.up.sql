@@ -0,0 +1,4 @@
+ALTER TABLE draw_history ADD COLUMN banned TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE draw_history ADD COLUMN standard_only BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE draw_history ADD COLUMN weighted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE draw_history ADD COLUMN candidates TEXT NOT NULL DEFAULT '[]';
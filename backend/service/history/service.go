@@ -0,0 +1,74 @@
+package history
+
+import (
+	"time"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service"
+)
+
+const (
+	// KindSingle marks a map drawn by a single roulette spin
+	KindSingle = "single"
+
+	// KindSeries marks a map drawn as part of a best-of series
+	KindSeries = "series"
+
+	// DefaultLimit is the page size when none is requested
+	DefaultLimit = 50
+
+	// MaxLimit caps the page size accepted by List
+	MaxLimit = 200
+)
+
+// Draw is one persisted roulette result. A series is stored as one draw per map,
+// sharing a seed and numbered by position.
+type Draw struct {
+	ID        string    `json:"id" example:"0b8f3c2e-5d0a-4c55-9a53-2f9a8d1f6b7e"`
+	SessionID string    `json:"sessionId,omitempty" example:"scrim-night"`
+	MapUUID   string    `json:"mapUuid" example:"7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+	MapName   string    `json:"mapName" example:"Ascent"`
+	Kind      string    `json:"kind" example:"single"`
+	Position  int       `json:"position" example:"1"`
+	Pool      string    `json:"pool,omitempty" example:"competitive"`
+	Seed      int64     `json:"seed,string" example:"8675309"`
+	DrawnAt   time.Time `json:"drawnAt"`
+	// Banned and StandardOnly are the filter the draw was made with
+	Banned       []string `json:"banned,omitempty" example:"2c9d57ec-4431-9c5e-2939-8f9ef6dd5cba"`
+	StandardOnly bool     `json:"standard" example:"true"`
+	// Weighted is set when maps were not equally likely, from caller weights or down-weighted session history
+	Weighted bool `json:"weighted" example:"false"`
+	// Candidates lists the UUIDs of the maps the draw chose from
+	Candidates []string `json:"candidates,omitempty" example:"2c9d57ec-4431-9c5e-2939-8f9ef6dd5cba,7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+}
+
+// Query filters and paginates the draw history. Zero values leave a filter unset.
+type Query struct {
+	SessionID string
+	// Map matches either the map UUID or its display name, ignoring case
+	Map string
+	// From and To bound DrawnAt; From is inclusive and To exclusive
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// Page is one page of draws, newest first
+type Page struct {
+	Draws  []Draw `json:"draws"`
+	Total  int    `json:"total" example:"128"`
+	Limit  int    `json:"limit" example:"50"`
+	Offset int    `json:"offset" example:"0"`
+}
+
+var (
+	_ Service = (*HistoryService)(nil)
+)
+
+type Service interface {
+	service.Service
+
+	// List returns the draws matching the query, newest first
+	List(ctx ctx.CTX, query Query) (*Page, error)
+}
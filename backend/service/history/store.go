@@ -0,0 +1,231 @@
+package history
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/pkg/database"
+)
+
+// Migrations create the draw history schema; they are valid for both SQLite and PostgreSQL
+var Migrations = database.MustLoadMigrations(migrationFiles, "migrations")
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// drawColumns lists the draw_history columns in the order scanned by scanDraw
+const drawColumns = "id, session_id, map_uuid, map_name, kind, position, pool, seed, drawn_at, banned, standard_only, weighted, candidates"
+
+var (
+	_ Store = (*SQLStore)(nil)
+)
+
+// Store persists roulette draws
+type Store interface {
+	// Record saves the draws in a single transaction
+	Record(ctx ctx.CTX, draws ...Draw) error
+
+	// List returns the draws matching the query, newest first, with the total match count
+	List(ctx ctx.CTX, query Query) ([]Draw, int, error)
+
+	// Each calls fn for every draw matching the query's filters, oldest first, ignoring its limit and offset
+	Each(ctx ctx.CTX, query Query, fn func(Draw) error) error
+}
+
+// SQLStore keeps the draw history in a SQL database migrated with Migrations
+type SQLStore struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewSQLStore creates a store on db, which was opened with the given database driver
+func NewSQLStore(db *sql.DB, driver string) *SQLStore {
+	return &SQLStore{db: db, driver: driver}
+}
+
+// Record saves the draws in a single transaction
+func (s *SQLStore) Record(ctx ctx.CTX, draws ...Draw) error {
+	if len(draws) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx.StdContext(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx.StdContext(), s.rebind(`
+		INSERT INTO draw_history (`+drawColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`))
+	if err != nil {
+		return fmt.Errorf("failed to prepare draw insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, d := range draws {
+		_, err := stmt.ExecContext(ctx.StdContext(), d.ID, d.SessionID, d.MapUUID, d.MapName, d.Kind, d.Position, d.Pool, d.Seed, truncate(d.DrawnAt),
+			encodeList(d.Banned), d.StandardOnly, d.Weighted, encodeList(d.Candidates))
+		if err != nil {
+			return fmt.Errorf("failed to record draw %s: %w", d.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit draws: %w", err)
+	}
+	return nil
+}
+
+// List returns the draws matching the query, newest first, with the total match count
+func (s *SQLStore) List(ctx ctx.CTX, query Query) ([]Draw, int, error) {
+	where, args := whereClause(query)
+
+	var total int
+	row := s.db.QueryRowContext(ctx.StdContext(), s.rebind("SELECT COUNT(*) FROM draw_history"+where), args...)
+	if err := row.Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count draws: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx.StdContext(), s.rebind(`
+		SELECT `+drawColumns+`
+		FROM draw_history`+where+`
+		ORDER BY drawn_at DESC, position ASC, id ASC
+		LIMIT ? OFFSET ?
+	`), append(args, query.Limit, query.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list draws: %w", err)
+	}
+	defer rows.Close()
+
+	draws := []Draw{}
+	for rows.Next() {
+		d, err := scanDraw(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		draws = append(draws, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating over draws: %w", err)
+	}
+
+	return draws, total, nil
+}
+
+// Each calls fn for every draw matching the query's filters, oldest first, ignoring its limit and offset.
+// It stops at the first error returned by fn.
+func (s *SQLStore) Each(ctx ctx.CTX, query Query, fn func(Draw) error) error {
+	where, args := whereClause(query)
+
+	rows, err := s.db.QueryContext(ctx.StdContext(), s.rebind(`
+		SELECT `+drawColumns+`
+		FROM draw_history`+where+`
+		ORDER BY drawn_at ASC, position ASC, id ASC
+	`), args...)
+	if err != nil {
+		return fmt.Errorf("failed to list draws: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanDraw(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over draws: %w", err)
+	}
+	return nil
+}
+
+// whereClause builds the WHERE clause and arguments for the query's filters
+func whereClause(query Query) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if query.SessionID != "" {
+		conditions = append(conditions, "session_id = ?")
+		args = append(args, query.SessionID)
+	}
+	if query.Map != "" {
+		conditions = append(conditions, "(map_uuid = ? OR LOWER(map_name) = LOWER(?))")
+		args = append(args, query.Map, query.Map)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "drawn_at >= ?")
+		args = append(args, query.From.UTC())
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "drawn_at < ?")
+		args = append(args, query.To.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// scanDraw reads a row selected with drawColumns
+func scanDraw(rows *sql.Rows) (Draw, error) {
+	var (
+		d                  Draw
+		banned, candidates string
+	)
+	if err := rows.Scan(&d.ID, &d.SessionID, &d.MapUUID, &d.MapName, &d.Kind, &d.Position, &d.Pool, &d.Seed, &d.DrawnAt,
+		&banned, &d.StandardOnly, &d.Weighted, &candidates); err != nil {
+		return Draw{}, fmt.Errorf("failed to scan draw row: %w", err)
+	}
+	d.DrawnAt = d.DrawnAt.UTC()
+	var err error
+	if d.Banned, err = decodeList(banned); err != nil {
+		return Draw{}, fmt.Errorf("failed to decode banned maps of draw %s: %w", d.ID, err)
+	}
+	if d.Candidates, err = decodeList(candidates); err != nil {
+		return Draw{}, fmt.Errorf("failed to decode candidates of draw %s: %w", d.ID, err)
+	}
+	return d, nil
+}
+
+// encodeList stores a list of map IDs in a single column as a JSON array, so
+// that IDs supplied by clients survive whatever characters they contain
+func encodeList(values []string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(values) // A string slice always encodes
+	return string(data)
+}
+
+// decodeList reverses encodeList
+func decodeList(value string) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values, nil
+}
+
+func (s *SQLStore) rebind(query string) string {
+	return database.Rebind(s.driver, query)
+}
+
+// truncate drops sub-microsecond precision, which PostgreSQL timestamps cannot hold
+func truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
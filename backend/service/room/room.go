@@ -0,0 +1,274 @@
+package room
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jungtechou/valomap/event"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/roulette"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RoomTTL is how long a room without subscribers or spins is kept
+	RoomTTL = 6 * time.Hour
+
+	// SubscriberBuffer is how many events a client may lag behind before it is disconnected
+	SubscriberBuffer = 16
+
+	// sessionPrefix scopes the roulette no-repeat history to the room
+	sessionPrefix = "room:"
+)
+
+var (
+	ErrRoomNotFound = errors.New("room not found")
+	ErrNotHost      = errors.New("only the room host can spin")
+	ErrClosed       = errors.New("room service is shut down")
+)
+
+// roomState is a room together with its event log and subscribers
+type roomState struct {
+	mutex     sync.Mutex
+	id        string
+	name      string
+	hostToken string
+	log       *event.Log
+	lastSpin  *Spin
+	subs      map[chan event.Event]struct{}
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+type RoomService struct {
+	roulette roulette.Service
+
+	mutex  sync.Mutex
+	rooms  map[string]*roomState
+	closed bool
+	now    func() time.Time
+}
+
+// NewService creates a room service that spins through the roulette service.
+// The cleanup function closes every room so that open streams end before the server stops.
+func NewService(rouletteService roulette.Service) (Service, func()) {
+	service := &RoomService{
+		roulette: rouletteService,
+		rooms:    make(map[string]*roomState),
+		now:      time.Now,
+	}
+	return service, service.Close
+}
+
+// Create opens a room and returns it with its host token
+func (s *RoomService) Create(ctx ctx.CTX, name string) (*Created, error) {
+	now := s.now()
+	r := &roomState{
+		id:        uuid.NewString(),
+		name:      name,
+		hostToken: uuid.NewString(),
+		log:       event.NewLog(event.DefaultReplaySize),
+		subs:      make(map[chan event.Event]struct{}),
+		createdAt: now,
+		updatedAt: now,
+	}
+
+	opened, err := event.New(r.id, event.TypeRoomOpened, map[string]string{"name": name})
+	if err != nil {
+		return nil, err
+	}
+	r.log.Append(opened)
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil, ErrClosed
+	}
+	s.pruneExpired(now)
+	s.rooms[r.id] = r
+	s.mutex.Unlock()
+
+	ctx.FieldLogger.WithField("room_id", r.id).Info("Created roulette room")
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return &Created{Room: r.snapshot(), HostToken: r.hostToken}, nil
+}
+
+// Get returns the current state of a room
+func (s *RoomService) Get(ctx ctx.CTX, id string) (*Room, error) {
+	r, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	room := r.snapshot()
+	return &room, nil
+}
+
+// Spin draws a map for the room and broadcasts it to every subscriber.
+// Spins share the room's no-repeat history, so a lobby does not get the same map twice in a row.
+func (s *RoomService) Spin(ctx ctx.CTX, id, hostToken string, filter roulette.MapFilter, seed *int64) (*event.Event, error) {
+	r, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hostToken), []byte(r.hostToken)) != 1 {
+		return nil, ErrNotHost
+	}
+
+	filter.SessionID = sessionPrefix + id
+	result, err := s.roulette.DrawMap(ctx, filter, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	spin := &Spin{Map: result.Map, Seed: result.Seed, Candidates: result.Candidates}
+	evt, err := event.New(id, event.TypeSpin, spin)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	r.lastSpin = spin
+	evt = r.publish(ctx, evt, s.now())
+	subscribers := len(r.subs)
+	r.mutex.Unlock()
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"room_id":     id,
+		"event_id":    evt.ID,
+		"map_name":    spin.Map.DisplayName,
+		"subscribers": subscribers,
+	}).Info("Broadcast roulette spin")
+
+	return &evt, nil
+}
+
+// Subscribe joins a room, replaying retained events newer than lastEventID
+func (s *RoomService) Subscribe(ctx ctx.CTX, id string, lastEventID uint64) (*Subscription, error) {
+	r, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan event.Event, SubscriberBuffer)
+
+	// Registering under the room lock means no event is both replayed and delivered, or missed
+	r.mutex.Lock()
+	replay := r.log.Since(lastEventID)
+	r.subs[ch] = struct{}{}
+	r.mutex.Unlock()
+
+	var once sync.Once
+	return &Subscription{
+		Replay: replay,
+		Events: ch,
+		cancel: func() {
+			once.Do(func() {
+				r.mutex.Lock()
+				defer r.mutex.Unlock()
+				if _, ok := r.subs[ch]; ok {
+					delete(r.subs, ch)
+					close(ch)
+				}
+				r.updatedAt = s.now()
+			})
+		},
+	}, nil
+}
+
+// Close ends every subscription and rejects further use
+func (s *RoomService) Close() {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return
+	}
+	s.closed = true
+	rooms := s.rooms
+	s.rooms = make(map[string]*roomState)
+	s.mutex.Unlock()
+
+	logger := logrus.WithField("component", "room")
+	for id, r := range rooms {
+		closed, err := event.New(id, event.TypeRoomClosed, nil)
+		if err != nil {
+			continue
+		}
+
+		r.mutex.Lock()
+		r.publish(ctx.Background(), closed, s.now())
+		for ch := range r.subs {
+			delete(r.subs, ch)
+			close(ch)
+		}
+		r.mutex.Unlock()
+	}
+	logger.WithField("rooms", len(rooms)).Info("Closed roulette rooms")
+}
+
+// lookup finds an open room by ID
+func (s *RoomService) lookup(id string) (*roomState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	r, ok := s.rooms[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRoomNotFound, id)
+	}
+	return r, nil
+}
+
+// pruneExpired drops idle rooms nobody is subscribed to; callers must hold the mutex
+func (s *RoomService) pruneExpired(now time.Time) {
+	for id, r := range s.rooms {
+		r.mutex.Lock()
+		expired := len(r.subs) == 0 && now.Sub(r.updatedAt) > RoomTTL
+		r.mutex.Unlock()
+		if expired {
+			delete(s.rooms, id)
+		}
+	}
+}
+
+// publish logs the event and delivers it to every subscriber; callers must hold the room mutex.
+// Subscribers whose buffer is full are disconnected and can catch up by reconnecting.
+func (r *roomState) publish(ctx ctx.CTX, evt event.Event, now time.Time) event.Event {
+	evt = r.log.Append(evt)
+	r.updatedAt = now
+
+	for ch := range r.subs {
+		select {
+		case ch <- evt:
+		default:
+			delete(r.subs, ch)
+			close(ch)
+			ctx.FieldLogger.WithField("room_id", r.id).Warn("Dropped slow room subscriber")
+		}
+	}
+	return evt
+}
+
+// snapshot copies the room's public state; callers must hold the room mutex
+func (r *roomState) snapshot() Room {
+	return Room{
+		ID:          r.id,
+		Name:        r.name,
+		Subscribers: len(r.subs),
+		LastEventID: r.log.LastID(),
+		LastSpin:    r.lastSpin,
+		CreatedAt:   r.createdAt,
+		UpdatedAt:   r.updatedAt,
+	}
+}
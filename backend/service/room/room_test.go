@@ -0,0 +1,211 @@
+package room
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/event"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/roulette"
+	"github.com/jungtechou/valomap/service/session"
+	"github.com/jungtechou/valomap/service/source"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestContext creates a context for testing
+func setupTestContext() ctx.CTX {
+	logger := logrus.New()
+	logger.Out = io.Discard // Suppress logging during tests
+	return ctx.CTX{FieldLogger: logrus.NewEntry(logger)}
+}
+
+// newTestService creates a room service over a five-map roulette with one-pick history
+func newTestService(t *testing.T) *RoomService {
+	var maps []domain.Map
+	for i := 1; i <= 5; i++ {
+		maps = append(maps, domain.Map{UUID: fmt.Sprintf("map%d", i), DisplayName: fmt.Sprintf("Map %d", i), TacticalDescription: "A/B Sites"})
+	}
+
+	rouletteService, cleanup := roulette.NewService(source.NewStaticSource(domain.MapPool{Maps: maps}), nil,
+		session.NewMemoryStore(1, time.Hour), nil, nil, &config.Config{
+			Catalog: config.CatalogConfig{TTL: time.Hour},
+			Session: config.SessionConfig{Mode: session.ModeExclude},
+		})
+	t.Cleanup(cleanup)
+
+	service, closeRooms := NewService(rouletteService)
+	t.Cleanup(closeRooms)
+	return service.(*RoomService)
+}
+
+// decodeSpin reads the payload of a roulette.spin event
+func decodeSpin(t *testing.T, evt event.Event) Spin {
+	require.Equal(t, event.TypeSpin, evt.Type)
+	var spin Spin
+	require.NoError(t, json.Unmarshal(evt.Data, &spin))
+	return spin
+}
+
+func TestRoom_SpinBroadcasts(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+
+	created, err := service.Create(testCtx, "Friday scrims")
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.HostToken)
+	assert.Equal(t, uint64(1), created.LastEventID)
+
+	first, err := service.Subscribe(testCtx, created.ID, 0)
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := service.Subscribe(testCtx, created.ID, created.LastEventID)
+	require.NoError(t, err)
+	defer second.Close()
+
+	// A fresh subscriber replays the room.opened event, an up-to-date one nothing
+	require.Len(t, first.Replay, 1)
+	assert.Equal(t, event.TypeRoomOpened, first.Replay[0].Type)
+	assert.Empty(t, second.Replay)
+
+	_, err = service.Spin(testCtx, created.ID, "wrong", roulette.MapFilter{}, nil)
+	assert.ErrorIs(t, err, ErrNotHost)
+
+	seed := int64(42)
+	evt, err := service.Spin(testCtx, created.ID, created.HostToken, roulette.MapFilter{}, &seed)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), evt.ID)
+	spin := decodeSpin(t, *evt)
+	assert.Equal(t, seed, spin.Seed)
+
+	// Every subscriber sees the same map
+	for _, sub := range []*Subscription{first, second} {
+		select {
+		case got := <-sub.Events:
+			assert.Equal(t, *evt, got)
+		case <-time.After(time.Second):
+			t.Fatal("subscriber did not receive the spin")
+		}
+	}
+
+	// The next spin avoids the previous map through the room's session history
+	next, err := service.Spin(testCtx, created.ID, created.HostToken, roulette.MapFilter{}, &seed)
+	require.NoError(t, err)
+	assert.NotEqual(t, spin.Map.UUID, decodeSpin(t, *next).Map.UUID)
+
+	room, err := service.Get(testCtx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, room.Subscribers)
+	assert.Equal(t, uint64(3), room.LastEventID)
+	assert.Equal(t, decodeSpin(t, *next).Map, room.LastSpin.Map)
+}
+
+func TestRoom_Replay(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+
+	created, err := service.Create(testCtx, "")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := service.Spin(testCtx, created.ID, created.HostToken, roulette.MapFilter{}, nil)
+		require.NoError(t, err)
+	}
+
+	// A reconnecting client resumes after the last event it saw
+	sub, err := service.Subscribe(testCtx, created.ID, 2)
+	require.NoError(t, err)
+	defer sub.Close()
+	require.Len(t, sub.Replay, 2)
+	assert.Equal(t, uint64(3), sub.Replay[0].ID)
+	assert.Equal(t, uint64(4), sub.Replay[1].ID)
+}
+
+func TestRoom_SlowSubscriberIsDropped(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+
+	created, err := service.Create(testCtx, "")
+	require.NoError(t, err)
+	sub, err := service.Subscribe(testCtx, created.ID, created.LastEventID)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	for i := 0; i <= SubscriberBuffer; i++ {
+		_, err := service.Spin(testCtx, created.ID, created.HostToken, roulette.MapFilter{}, nil)
+		require.NoError(t, err)
+	}
+
+	// The buffered events drain, then the channel is closed
+	received := 0
+	for range sub.Events {
+		received++
+	}
+	assert.Equal(t, SubscriberBuffer, received)
+
+	room, err := service.Get(testCtx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, room.Subscribers)
+}
+
+func TestRoom_Close(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+
+	created, err := service.Create(testCtx, "")
+	require.NoError(t, err)
+	sub, err := service.Subscribe(testCtx, created.ID, created.LastEventID)
+	require.NoError(t, err)
+
+	service.Close()
+	service.Close() // Closing twice must not panic
+
+	// Subscribers are told the room closed before their channel ends
+	evt, ok := <-sub.Events
+	require.True(t, ok)
+	assert.Equal(t, event.TypeRoomClosed, evt.Type)
+	_, ok = <-sub.Events
+	assert.False(t, ok)
+	sub.Close()
+
+	_, err = service.Get(testCtx, created.ID)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = service.Create(testCtx, "")
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestRoom_NotFoundAndPrune(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+
+	_, err := service.Get(testCtx, "missing")
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+	_, err = service.Subscribe(testCtx, "missing", 0)
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+	_, err = service.Spin(testCtx, "missing", "", roulette.MapFilter{}, nil)
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+
+	idle, err := service.Create(testCtx, "")
+	require.NoError(t, err)
+	watched, err := service.Create(testCtx, "")
+	require.NoError(t, err)
+	sub, err := service.Subscribe(testCtx, watched.ID, 0)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// Idle rooms are pruned, rooms with subscribers are kept
+	now := time.Now()
+	service.now = func() time.Time { return now.Add(RoomTTL + time.Minute) }
+	_, err = service.Create(testCtx, "")
+	require.NoError(t, err)
+
+	_, err = service.Get(testCtx, idle.ID)
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+	_, err = service.Get(testCtx, watched.ID)
+	assert.NoError(t, err)
+}
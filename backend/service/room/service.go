@@ -0,0 +1,73 @@
+package room
+
+import (
+	"time"
+
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/event"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service"
+	"github.com/jungtechou/valomap/service/roulette"
+)
+
+// Room is a snapshot of a roulette room
+type Room struct {
+	ID          string    `json:"id" example:"0b8f3c2e-5d0a-4c55-9a53-2f9a8d1f6b7e"`
+	Name        string    `json:"name,omitempty" example:"Friday scrims"`
+	Subscribers int       `json:"subscribers" example:"4"`
+	LastEventID uint64    `json:"lastEventId,string" example:"3"`
+	LastSpin    *Spin     `json:"lastSpin,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Created is a new room together with the token that lets its host spin
+type Created struct {
+	Room
+	HostToken string `json:"hostToken" example:"5f1d7c9e-3b0a-4a8e-9d55-6c2f0b7a1e34"`
+}
+
+// Spin is the payload of a roulette.spin event
+type Spin struct {
+	Map        domain.Map `json:"map"`
+	Seed       int64      `json:"seed,string" example:"8675309"`
+	Candidates []string   `json:"candidates"`
+}
+
+// Subscription delivers a room's events to one client
+type Subscription struct {
+	// Replay holds the retained events after the client's last seen ID, oldest first
+	Replay []event.Event
+	// Events receives new events; it is closed when the room closes or the client falls too far behind
+	Events <-chan event.Event
+
+	cancel func()
+}
+
+// Close stops the subscription; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.cancel()
+}
+
+var (
+	_ Service = (*RoomService)(nil)
+)
+
+type Service interface {
+	service.Service
+
+	// Create opens a room and returns it with its host token
+	Create(ctx ctx.CTX, name string) (*Created, error)
+
+	// Get returns the current state of a room
+	Get(ctx ctx.CTX, id string) (*Room, error)
+
+	// Spin draws a map for the room and broadcasts it to every subscriber
+	Spin(ctx ctx.CTX, id, hostToken string, filter roulette.MapFilter, seed *int64) (*event.Event, error)
+
+	// Subscribe joins a room, replaying retained events newer than lastEventID
+	Subscribe(ctx ctx.CTX, id string, lastEventID uint64) (*Subscription, error)
+
+	// Close ends every subscription and rejects further use
+	Close()
+}
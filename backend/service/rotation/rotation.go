@@ -0,0 +1,156 @@
+package rotation
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownPool     = errors.New("unknown map pool")
+	ErrNoActiveSeason  = errors.New("no active season for map pool")
+	ErrInvalidSeason   = errors.New("invalid rotation season")
+	ErrInvalidRotation = errors.New("invalid rotation file")
+)
+
+// Rotation resolves which maps are in each pool's rotation at a point in time
+type Rotation struct {
+	// seasons holds each pool's seasons ordered by effective date
+	seasons map[string][]domain.Season
+}
+
+// rotationFile is the on-disk layout of a rotation file; JSON files are read as YAML
+type rotationFile struct {
+	Seasons []config.SeasonConfig `yaml:"seasons"`
+}
+
+// New creates a rotation from the given seasons
+func New(seasons []domain.Season) (*Rotation, error) {
+	r := &Rotation{seasons: make(map[string][]domain.Season)}
+
+	for _, season := range seasons {
+		if !IsRotationPool(season.Pool) {
+			return nil, fmt.Errorf("%w: season %q has unknown pool %q", ErrInvalidSeason, season.Name, season.Pool)
+		}
+		if len(season.MapUUIDs) == 0 {
+			return nil, fmt.Errorf("%w: season %q has no maps", ErrInvalidSeason, season.Name)
+		}
+		for _, existing := range r.seasons[season.Pool] {
+			if existing.EffectiveFrom.Equal(season.EffectiveFrom) {
+				return nil, fmt.Errorf("%w: seasons %q and %q of pool %q start at the same time",
+					ErrInvalidSeason, existing.Name, season.Name, season.Pool)
+			}
+		}
+		r.seasons[season.Pool] = append(r.seasons[season.Pool], season)
+	}
+
+	for pool := range r.seasons {
+		sort.Slice(r.seasons[pool], func(i, j int) bool {
+			return r.seasons[pool][i].EffectiveFrom.Before(r.seasons[pool][j].EffectiveFrom)
+		})
+	}
+
+	return r, nil
+}
+
+// Load builds the rotation from inline config seasons, then the rotation file,
+// and finally the given fallback seasons when neither is configured
+func Load(cfg config.RotationConfig, fallback []domain.Season) (*Rotation, error) {
+	switch {
+	case len(cfg.Seasons) > 0:
+		seasons, err := ParseSeasons(cfg.Seasons)
+		if err != nil {
+			return nil, err
+		}
+		return New(seasons)
+	case cfg.Path != "":
+		seasons, err := LoadFile(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		return New(seasons)
+	default:
+		return New(fallback)
+	}
+}
+
+// LoadFile reads seasons from a YAML or JSON rotation file
+func LoadFile(path string) ([]domain.Season, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rotation file: %w", err)
+	}
+
+	var file rotationFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRotation, err)
+	}
+
+	return ParseSeasons(file.Seasons)
+}
+
+// ParseSeasons converts configured seasons to domain seasons.
+// Effective dates are either RFC 3339 timestamps or plain dates in UTC.
+func ParseSeasons(configs []config.SeasonConfig) ([]domain.Season, error) {
+	seasons := make([]domain.Season, 0, len(configs))
+	for _, c := range configs {
+		effective, err := parseEffective(c.Effective)
+		if err != nil {
+			return nil, fmt.Errorf("%w: season %q: %v", ErrInvalidSeason, c.Name, err)
+		}
+		seasons = append(seasons, domain.Season{
+			Name:          c.Name,
+			Pool:          c.Pool,
+			EffectiveFrom: effective,
+			MapUUIDs:      c.Maps,
+		})
+	}
+	return seasons, nil
+}
+
+// Current returns the season of the pool that is in effect at the given time
+func (r *Rotation) Current(pool string, at time.Time) (domain.Season, error) {
+	if !IsRotationPool(pool) {
+		return domain.Season{}, fmt.Errorf("%w: %q", ErrUnknownPool, pool)
+	}
+
+	seasons := r.seasons[pool]
+	for i := len(seasons) - 1; i >= 0; i-- {
+		if !seasons[i].EffectiveFrom.After(at) {
+			return seasons[i], nil
+		}
+	}
+
+	return domain.Season{}, fmt.Errorf("%w: %q", ErrNoActiveSeason, pool)
+}
+
+// Seasons returns every season of the pool ordered by effective date
+func (r *Rotation) Seasons(pool string) []domain.Season {
+	return append([]domain.Season(nil), r.seasons[pool]...)
+}
+
+// IsRotationPool reports whether the pool is a dated rotation, as opposed to all maps
+func IsRotationPool(pool string) bool {
+	return pool == domain.PoolCompetitive || pool == domain.PoolPremier
+}
+
+// IsPool reports whether the name is a pool accepted by the map filters
+func IsPool(pool string) bool {
+	return pool == domain.PoolAll || IsRotationPool(pool)
+}
+
+func parseEffective(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("missing effective date")
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
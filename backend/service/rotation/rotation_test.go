@@ -0,0 +1,132 @@
+package rotation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSeasons returns two competitive seasons and one premier season
+func testSeasons() []domain.Season {
+	return []domain.Season{
+		{Name: "Act 2", Pool: domain.PoolCompetitive, EffectiveFrom: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), MapUUIDs: []string{"map2", "map3"}},
+		{Name: "Act 1", Pool: domain.PoolCompetitive, EffectiveFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), MapUUIDs: []string{"map1", "map2"}},
+		{Name: "Premier", Pool: domain.PoolPremier, EffectiveFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), MapUUIDs: []string{"map4"}},
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	r, err := New(testSeasons())
+	require.NoError(t, err)
+
+	// Each season lasts until the next one of the same pool begins
+	season, err := r.Current(domain.PoolCompetitive, time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "Act 1", season.Name)
+
+	season, err = r.Current(domain.PoolCompetitive, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "Act 2", season.Name)
+
+	season, err = r.Current(domain.PoolPremier, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"map4"}, season.MapUUIDs)
+
+	// Nothing is active before the first season
+	_, err = r.Current(domain.PoolCompetitive, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, ErrNoActiveSeason)
+
+	// "all" and unknown names are not rotations
+	_, err = r.Current(domain.PoolAll, time.Now())
+	assert.ErrorIs(t, err, ErrUnknownPool)
+	_, err = r.Current("casual", time.Now())
+	assert.ErrorIs(t, err, ErrUnknownPool)
+
+	// Seasons are listed in order
+	seasons := r.Seasons(domain.PoolCompetitive)
+	require.Len(t, seasons, 2)
+	assert.Equal(t, "Act 1", seasons[0].Name)
+}
+
+func TestNew_Validation(t *testing.T) {
+	_, err := New([]domain.Season{{Name: "Bad", Pool: "casual", MapUUIDs: []string{"map1"}}})
+	assert.ErrorIs(t, err, ErrInvalidSeason)
+
+	_, err = New([]domain.Season{{Name: "Empty", Pool: domain.PoolCompetitive}})
+	assert.ErrorIs(t, err, ErrInvalidSeason)
+
+	duplicate := testSeasons()
+	duplicate[0].EffectiveFrom = duplicate[1].EffectiveFrom
+	_, err = New(duplicate)
+	assert.ErrorIs(t, err, ErrInvalidSeason)
+}
+
+func TestLoad(t *testing.T) {
+	fallback := testSeasons()
+
+	// Nothing configured uses the fallback
+	r, err := Load(config.RotationConfig{}, fallback)
+	require.NoError(t, err)
+	assert.Len(t, r.Seasons(domain.PoolCompetitive), 2)
+
+	// Inline seasons win over the file
+	r, err = Load(config.RotationConfig{
+		Path: "/does/not/exist.yaml",
+		Seasons: []config.SeasonConfig{
+			{Name: "Inline", Pool: domain.PoolPremier, Effective: "2025-06-01", Maps: []string{"map9"}},
+		},
+	}, fallback)
+	require.NoError(t, err)
+	assert.Empty(t, r.Seasons(domain.PoolCompetitive))
+	season, err := r.Current(domain.PoolPremier, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "Inline", season.Name)
+
+	// Rotation files may be YAML or JSON
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "rotation.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`
+seasons:
+  - name: File Season
+    pool: competitive
+    effective: 2025-01-08
+    maps: [map1, map2, map3]
+`), 0o644))
+	jsonPath := filepath.Join(dir, "rotation.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(
+		`{"seasons":[{"name":"JSON Season","pool":"premier","effective":"2025-01-08T12:00:00Z","maps":["map4"]}]}`,
+	), 0o644))
+
+	r, err = Load(config.RotationConfig{Path: yamlPath}, fallback)
+	require.NoError(t, err)
+	season, err = r.Current(domain.PoolCompetitive, time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "File Season", season.Name)
+	assert.Len(t, season.MapUUIDs, 3)
+
+	r, err = Load(config.RotationConfig{Path: jsonPath}, fallback)
+	require.NoError(t, err)
+	season, err = r.Current(domain.PoolPremier, time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "JSON Season", season.Name)
+
+	// Broken files and dates are reported
+	_, err = Load(config.RotationConfig{Path: filepath.Join(dir, "missing.yaml")}, fallback)
+	assert.Error(t, err)
+
+	badPath := filepath.Join(dir, "bad.yaml")
+	require.NoError(t, os.WriteFile(badPath, []byte("seasons: [\n"), 0o644))
+	_, err = Load(config.RotationConfig{Path: badPath}, fallback)
+	assert.ErrorIs(t, err, ErrInvalidRotation)
+
+	_, err = Load(config.RotationConfig{Seasons: []config.SeasonConfig{
+		{Name: "Undated", Pool: domain.PoolCompetitive, Maps: []string{"map1"}},
+	}}, fallback)
+	assert.ErrorIs(t, err, ErrInvalidSeason)
+}
@@ -1,84 +1,108 @@
 package roulette
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
-	"net/http"
+	"math"
+	"math/rand/v2"
+	"sort"
 	"time"
 
+	"github.com/jungtechou/valomap/config"
 	domain "github.com/jungtechou/valomap/domain/map"
 	"github.com/jungtechou/valomap/pkg/ctx"
 	"github.com/jungtechou/valomap/service/cache"
+	"github.com/jungtechou/valomap/service/catalog"
+	"github.com/jungtechou/valomap/service/history"
+	"github.com/jungtechou/valomap/service/rotation"
+	"github.com/jungtechou/valomap/service/session"
+	"github.com/jungtechou/valomap/service/source"
+
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
-const (
-	apiURL = "https://valorant-api.com/v1/maps"
-)
+// seedStream is the fixed PCG stream used for seeded draws; changing it changes every draw outcome
+const seedStream = 0x76616c6f6d6170
 
 var (
-	ErrEmptyMapList   = errors.New("received empty map list from API")
-	ErrAPIRequest     = errors.New("failed to make API request")
-	ErrAPIResponse    = errors.New("received invalid API response")
+	ErrEmptyMapList   = source.ErrEmptyMapList
+	ErrAPIRequest     = source.ErrAPIRequest
+	ErrAPIResponse    = source.ErrAPIResponse
 	ErrNoStandardMaps = errors.New("no standard maps found")
 	ErrNoFilteredMaps = errors.New("no maps found matching filter criteria")
+	ErrInvalidWeight  = errors.New("map weights must be finite and non-negative")
+	ErrZeroWeights    = errors.New("all candidate maps have zero weight")
+	ErrNoSessions     = errors.New("session history is not enabled")
+	ErrInvalidCount   = errors.New("invalid series count")
+	ErrNotEnoughMaps  = errors.New("not enough maps for series")
+	ErrUnknownPool    = rotation.ErrUnknownPool
+	ErrNoActivePool   = rotation.ErrNoActiveSeason
+	ErrEmptyPool      = errors.New("no maps from the active pool are available")
 )
 
 type RouletteService struct {
-	client     *http.Client
-	rng        *rand.Rand // Thread-safe random number generator
+	source     source.MapSource
 	imageCache cache.ImageCache
-}
+	catalog    catalog.MapCatalog
+	rotation   *rotation.Rotation
 
-func NewService(c *http.Client, imageCache cache.ImageCache) Service {
-	// Create a new random source with current time seed
-	source := rand.NewSource(time.Now().UnixNano())
-	return &RouletteService{
-		client:     c,
-		rng:        rand.New(source),
-		imageCache: imageCache,
-	}
+	sessions     session.Store
+	sessionMode  string
+	recentWeight float64
+
+	draws history.Store
 }
 
-// fetchMaps fetches all maps from the API
-func (s *RouletteService) fetchMaps(ctx ctx.CTX) ([]domain.Map, error) {
-	// Make the API request
-	resp, err := s.client.Get(apiURL)
-	if err != nil {
-		ctx.FieldLogger.WithFields(logrus.Fields{
-			"error": err,
-			"url":   apiURL,
-		}).Error("Failed to fetch maps from API")
-		return nil, fmt.Errorf("%w: %v", ErrAPIRequest, err)
+// NewService creates a roulette service whose map list is kept in an in-memory catalog.
+// It waits up to catalog.startup_timeout for the first load, which continues in the
+// background if upstream is slow. Draws are persisted to the history store when one is given.
+// The returned cleanup function stops the catalog's background refresh.
+func NewService(src source.MapSource, imageCache cache.ImageCache, sessions session.Store, draws history.Store, rot *rotation.Rotation, cfg *config.Config) (Service, func()) {
+	s := &RouletteService{
+		source:      src,
+		imageCache:  imageCache,
+		rotation:    rot,
+		sessions:    sessions,
+		sessionMode: session.ModeExclude,
+		draws:       draws,
 	}
-	defer resp.Body.Close()
 
-	// Check for non-200 status code
-	if resp.StatusCode != http.StatusOK {
-		ctx.FieldLogger.WithFields(logrus.Fields{
-			"status_code": resp.StatusCode,
-			"url":         apiURL,
-		}).Error("Received non-OK status code from API")
-		return nil, fmt.Errorf("%w: status code %d", ErrAPIResponse, resp.StatusCode)
+	var catalogCfg config.CatalogConfig
+	if cfg != nil {
+		catalogCfg = cfg.Catalog
+		if cfg.Session.Mode != "" {
+			s.sessionMode = cfg.Session.Mode
+		}
+		s.recentWeight = cfg.Session.RecentWeight
 	}
+	s.catalog = catalog.New(s.fetchMaps, catalogCfg)
+	s.catalog.Start()
 
-	// Parse the response body
-	var mapResp domain.MapResponse
-	if err := json.NewDecoder(resp.Body).Decode(&mapResp); err != nil {
-		ctx.FieldLogger.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("Failed to decode API response")
-		return nil, fmt.Errorf("%w: %v", ErrAPIResponse, err)
+	return s, s.catalog.Stop
+}
+
+// loadMaps returns maps from the catalog, or straight from the source when no catalog is set
+func (s *RouletteService) loadMaps(ctx ctx.CTX) ([]domain.Map, error) {
+	if s.catalog == nil {
+		maps, err := s.fetchMaps(ctx)
+		if errors.Is(err, source.ErrFallback) {
+			return maps, nil
+		}
+		return maps, err
 	}
+	return s.catalog.Maps(ctx)
+}
 
-	// Validate the response data
-	maps := mapResp.Data
-	if len(maps) == 0 {
-		ctx.FieldLogger.Error("Received empty map list from API")
-		return nil, ErrEmptyMapList
+// fetchMaps fetches all maps from the map source
+func (s *RouletteService) fetchMaps(ctx ctx.CTX) ([]domain.Map, error) {
+	// Fallback maps are still processed, and returned with the error so that the
+	// catalog can tell them from upstream data
+	maps, err := s.source.FetchMaps(ctx)
+	if err != nil && !errors.Is(err, source.ErrFallback) {
+		return nil, err
 	}
+	fallbackErr := err
 
 	// Process map images via caching service if available
 	if s.imageCache != nil {
@@ -90,15 +114,36 @@ func (s *RouletteService) fetchMaps(ctx ctx.CTX) ([]domain.Map, error) {
 	}
 
 	// Log successful fetch
-	ctx.FieldLogger.WithField("map_count", len(maps)).Info("Successfully fetched maps from API")
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"map_count": len(maps),
+		"source":    s.source.Name(),
+	}).Info("Successfully fetched maps")
 
-	return maps, nil
+	return maps, fallbackErr
 }
 
 // filterMaps applies the provided filters to the map list
 func (s *RouletteService) filterMaps(ctx ctx.CTX, maps []domain.Map, filter MapFilter) ([]domain.Map, error) {
 	var filteredMaps []domain.Map
 
+	// Restrict to the active rotation when a pool is requested
+	if filter.Pool != "" && filter.Pool != domain.PoolAll {
+		season, err := s.currentSeason(filter.Pool)
+		if err != nil {
+			ctx.FieldLogger.WithError(err).WithField("pool", filter.Pool).Error("Failed to resolve map pool")
+			return nil, err
+		}
+
+		maps = seasonMaps(maps, season)
+		if len(maps) == 0 {
+			ctx.FieldLogger.WithFields(logrus.Fields{
+				"pool":   filter.Pool,
+				"season": season.Name,
+			}).Error("No maps from the active pool are available")
+			return nil, ErrEmptyPool
+		}
+	}
+
 	// Create a set of banned map IDs for faster lookup
 	bannedMaps := make(map[string]bool)
 	for _, id := range filter.BannedMapIDs {
@@ -155,26 +200,376 @@ func (s *RouletteService) filterMaps(ctx ctx.CTX, maps []domain.Map, filter MapF
 
 // GetRandomMap returns a random map filtered by the provided options
 func (s *RouletteService) GetRandomMap(ctx ctx.CTX, filter MapFilter) (*domain.Map, error) {
+	result, err := s.DrawMap(ctx, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Map, nil
+}
+
+// DrawMap selects a map using the given seed, or a freshly generated one when seed is nil.
+// Candidates are ordered by UUID before selection so that the same seed and filter
+// always yield the same map for the same map pool.
+func (s *RouletteService) DrawMap(ctx ctx.CTX, filter MapFilter, seed *int64) (*DrawResult, error) {
+	return s.drawMap(ctx, filter, seed, true)
+}
+
+// DrawMapUnrecorded selects a map exactly like DrawMap, but records it neither as a
+// session pick nor in the draw history. It serves maps drawn on behalf of another
+// feature, such as a veto decider, which would otherwise skew the roulette statistics.
+func (s *RouletteService) DrawMapUnrecorded(ctx ctx.CTX, filter MapFilter, seed *int64) (*DrawResult, error) {
+	return s.drawMap(ctx, filter, seed, false)
+}
+
+// drawMap selects a map for DrawMap, recording it when record is set
+func (s *RouletteService) drawMap(ctx ctx.CTX, filter MapFilter, seed *int64, record bool) (*DrawResult, error) {
+	candidates, weights, err := s.prepareDraw(ctx, filter, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	drawSeed := s.newSeed()
+	if seed != nil {
+		drawSeed = *seed
+	}
+
+	// Select a map from the ordered list, honouring weights when given
+	selectedMap := candidates[pickIndex(newDrawRand(drawSeed), len(candidates), weights)]
+
+	if record {
+		s.recordPick(ctx, filter.SessionID, selectedMap.UUID)
+		s.recordDraws(ctx, filter, history.KindSingle, drawSeed, []domain.Map{selectedMap}, candidates, weights != nil)
+	}
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"seed":       drawSeed,
+		"map_uuid":   selectedMap.UUID,
+		"candidates": len(candidates),
+	}).Debug("Drew map")
+
+	return &DrawResult{
+		Map:        selectedMap,
+		Seed:       drawSeed,
+		Candidates: mapUUIDs(candidates),
+	}, nil
+}
+
+// DrawSeries selects count distinct maps in play order, e.g. for a Bo3 or Bo5.
+// Like DrawMap, the same seed and filter always produce the same series.
+func (s *RouletteService) DrawSeries(ctx ctx.CTX, filter MapFilter, count int, seed *int64) (*SeriesResult, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("%w: count must be at least 1, got %d", ErrInvalidCount, count)
+	}
+
+	candidates, weights, err := s.prepareDraw(ctx, filter, count)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zero-weight maps can never be drawn, so they do not count towards the pool
+	eligible := len(candidates)
+	if weights != nil {
+		eligible = 0
+		for _, w := range weights {
+			if w > 0 {
+				eligible++
+			}
+		}
+	}
+	if eligible < count {
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"count":    count,
+			"eligible": eligible,
+		}).Error("Not enough maps for series")
+		return nil, fmt.Errorf("%w: requested %d maps but only %d are eligible", ErrNotEnoughMaps, count, eligible)
+	}
+
+	drawSeed := s.newSeed()
+	if seed != nil {
+		drawSeed = *seed
+	}
+
+	// Draw without replacement from a single generator
+	r := newDrawRand(drawSeed)
+	remaining := append([]domain.Map(nil), candidates...)
+	var remainingWeights []float64
+	if weights != nil {
+		remainingWeights = append([]float64(nil), weights...)
+	}
+
+	picks := make([]domain.Map, 0, count)
+	for len(picks) < count {
+		index := pickIndex(r, len(remaining), remainingWeights)
+		picks = append(picks, remaining[index])
+
+		remaining = append(remaining[:index], remaining[index+1:]...)
+		if remainingWeights != nil {
+			remainingWeights = append(remainingWeights[:index], remainingWeights[index+1:]...)
+		}
+	}
+
+	for _, m := range picks {
+		s.recordPick(ctx, filter.SessionID, m.UUID)
+	}
+	s.recordDraws(ctx, filter, history.KindSeries, drawSeed, picks, candidates, weights != nil)
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"seed":       drawSeed,
+		"count":      count,
+		"candidates": len(candidates),
+	}).Debug("Drew map series")
+
+	return &SeriesResult{
+		Maps:       picks,
+		Seed:       drawSeed,
+		Candidates: mapUUIDs(candidates),
+	}, nil
+}
+
+// prepareDraw loads, filters and canonically orders the candidates for a draw of need maps.
+// The returned weights line up with the candidates and are nil for a uniform draw.
+func (s *RouletteService) prepareDraw(ctx ctx.CTX, filter MapFilter, need int) ([]domain.Map, []float64, error) {
 	// Log filter options
 	ctx.FieldLogger.WithFields(logrus.Fields{
 		"standard_only": filter.StandardOnly,
+		"pool":          filter.Pool,
+		"weighted":      len(filter.Weights) > 0,
 	}).Debug("Map filter options")
 
-	// Fetch all maps
-	maps, err := s.fetchMaps(ctx)
+	// Reject bad weights before touching the catalog
+	if err := validateWeights(filter.Weights); err != nil {
+		return nil, nil, err
+	}
+
+	// Load all maps
+	maps, err := s.loadMaps(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Apply filters
 	filteredMaps, err := s.filterMaps(ctx, maps, filter)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// Steer away from the session's recent picks
+	filteredMaps, filter = s.applySessionHistory(ctx, filteredMaps, filter, need)
+
+	// Put candidates in a canonical order so the seed alone determines the pick
+	sort.Slice(filteredMaps, func(i, j int) bool {
+		return filteredMaps[i].UUID < filteredMaps[j].UUID
+	})
+
+	if len(filter.Weights) == 0 {
+		return filteredMaps, nil, nil
+	}
+
+	weights, err := candidateWeights(filteredMaps, filter.Weights)
+	if err != nil {
+		ctx.FieldLogger.WithField("candidates", len(filteredMaps)).Error("All candidate maps have zero weight")
+		return nil, nil, err
+	}
+	return filteredMaps, weights, nil
+}
+
+// newSeed generates a seed for draws where the client did not supply one.
+// The math/rand/v2 top-level source is safe for concurrent use without a shared lock.
+func (s *RouletteService) newSeed() int64 {
+	return rand.Int64()
+}
+
+// newDrawRand returns the deterministic generator for a seed.
+// Each draw gets its own generator, so concurrent draws share no mutable state.
+func newDrawRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), seedStream))
+}
+
+// mapUUIDs lists the UUIDs of the given maps in order
+func mapUUIDs(maps []domain.Map) []string {
+	uuids := make([]string, len(maps))
+	for i, m := range maps {
+		uuids[i] = m.UUID
+	}
+	return uuids
+}
+
+// applySessionHistory excludes or down-weights maps the session picked recently.
+// History is advisory: store failures are logged and the draw goes ahead without it,
+// and exclusions are skipped when they would leave fewer than need maps.
+func (s *RouletteService) applySessionHistory(ctx ctx.CTX, maps []domain.Map, filter MapFilter, need int) ([]domain.Map, MapFilter) {
+	if filter.SessionID == "" || s.sessions == nil {
+		return maps, filter
+	}
+
+	recent, err := s.sessions.Recent(ctx, filter.SessionID)
+	if err != nil {
+		ctx.FieldLogger.WithError(err).Warn("Failed to load session history, drawing without it")
+		return maps, filter
+	}
+	if len(recent) == 0 {
+		return maps, filter
+	}
+
+	recentMaps := make(map[string]bool, len(recent))
+	for _, uuid := range recent {
+		recentMaps[uuid] = true
 	}
 
-	// Select a random map from the filtered list
-	selectedMap := filteredMaps[s.rng.Intn(len(filteredMaps))]
-	return &selectedMap, nil
+	if s.sessionMode == session.ModeDownweight {
+		weights := make(map[string]float64, len(filter.Weights)+len(recent))
+		for uuid, w := range filter.Weights {
+			weights[uuid] = w
+		}
+		for uuid := range recentMaps {
+			w, ok := weights[uuid]
+			if !ok {
+				w = 1
+			}
+			weights[uuid] = w * s.recentWeight
+		}
+
+		// Never let history alone leave the draw without weight to pick from
+		if positiveWeights(maps, weights) < need {
+			ctx.FieldLogger.WithField("session_id", filter.SessionID).Info("Session history leaves no weight to draw from, ignoring it")
+			return maps, filter
+		}
+		filter.Weights = weights
+		return maps, filter
+	}
+
+	var fresh []domain.Map
+	for _, m := range maps {
+		if !recentMaps[m.UUID] {
+			fresh = append(fresh, m)
+		}
+	}
+
+	// Never let history alone shrink the pool below what the draw needs
+	if len(fresh) < need {
+		ctx.FieldLogger.WithField("session_id", filter.SessionID).Info("Session history covers every candidate, ignoring it")
+		return maps, filter
+	}
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"session_id": filter.SessionID,
+		"excluded":   len(maps) - len(fresh),
+	}).Debug("Excluded recent session picks")
+
+	return fresh, filter
+}
+
+// recordPick appends a drawn map to the session history
+func (s *RouletteService) recordPick(ctx ctx.CTX, sessionID, mapUUID string) {
+	if sessionID == "" || s.sessions == nil {
+		return
+	}
+	if err := s.sessions.Push(ctx, sessionID, mapUUID); err != nil {
+		ctx.FieldLogger.WithError(err).Warn("Failed to record pick in session history")
+	}
+}
+
+// recordDraws persists drawn maps to the history store together with the filter and candidates.
+// Like session history it is best effort: a failed write is logged and the draw still succeeds.
+// The store may queue the write, so a draw can show up in the history shortly after it returns.
+func (s *RouletteService) recordDraws(ctx ctx.CTX, filter MapFilter, kind string, seed int64, maps []domain.Map, candidates []domain.Map, weighted bool) {
+	if s.draws == nil {
+		return
+	}
+
+	drawnAt := time.Now()
+	candidateUUIDs := mapUUIDs(candidates)
+	draws := make([]history.Draw, len(maps))
+	for i, m := range maps {
+		draws[i] = history.Draw{
+			ID:           uuid.NewString(),
+			SessionID:    filter.SessionID,
+			MapUUID:      m.UUID,
+			MapName:      m.DisplayName,
+			Kind:         kind,
+			Position:     i + 1,
+			Pool:         filter.Pool,
+			Seed:         seed,
+			DrawnAt:      drawnAt,
+			Banned:       filter.BannedMapIDs,
+			StandardOnly: filter.StandardOnly,
+			Weighted:     weighted,
+			Candidates:   candidateUUIDs,
+		}
+	}
+
+	if err := s.draws.Record(ctx, draws...); err != nil {
+		ctx.FieldLogger.WithError(err).Warn("Failed to record draw history")
+	}
+}
+
+// validateWeights rejects negative, NaN and infinite weights
+func validateWeights(weights map[string]float64) error {
+	for uuid, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("%w: %s has weight %v", ErrInvalidWeight, uuid, w)
+		}
+	}
+	return nil
+}
+
+// candidateWeights returns the weight of each candidate, defaulting to 1 for maps without an entry
+func candidateWeights(maps []domain.Map, weights map[string]float64) ([]float64, error) {
+	result := make([]float64, len(maps))
+	var total float64
+	for i, m := range maps {
+		w, ok := weights[m.UUID]
+		if !ok {
+			w = 1
+		}
+		result[i] = w
+		total += w
+	}
+
+	if total == 0 {
+		return nil, ErrZeroWeights
+	}
+	return result, nil
+}
+
+// positiveWeights counts the maps with a positive weight, maps without one weighing 1
+func positiveWeights(maps []domain.Map, weights map[string]float64) int {
+	count := 0
+	for _, m := range maps {
+		if w, ok := weights[m.UUID]; !ok || w > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// pickIndex draws an index in [0, n), with probability proportional to weights when given.
+// At least one weight must be positive.
+func pickIndex(r *rand.Rand, n int, weights []float64) int {
+	if weights == nil {
+		return r.IntN(n)
+	}
+
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	target := r.Float64() * total
+
+	last := 0
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		if target < w {
+			return i
+		}
+		target -= w
+		last = i
+	}
+
+	// Floating point rounding can leave a sliver past the final bucket
+	return last
 }
 
 // GetAllMaps returns all available maps
@@ -182,8 +577,8 @@ func (s *RouletteService) GetAllMaps(ctx ctx.CTX) ([]domain.Map, error) {
 	// Log the request
 	ctx.FieldLogger.Info("Fetching all maps")
 
-	// Fetch all maps from the API
-	maps, err := s.fetchMaps(ctx)
+	// Load all maps from the catalog
+	maps, err := s.loadMaps(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -193,3 +588,104 @@ func (s *RouletteService) GetAllMaps(ctx ctx.CTX) ([]domain.Map, error) {
 
 	return maps, nil
 }
+
+// GetPool returns the maps in the active rotation of the named pool; an empty name means competitive
+func (s *RouletteService) GetPool(ctx ctx.CTX, name string) (*domain.MapPool, error) {
+	if name == "" {
+		name = domain.PoolCompetitive
+	}
+	if !rotation.IsPool(name) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPool, name)
+	}
+
+	maps, err := s.loadMaps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == domain.PoolAll {
+		return &domain.MapPool{Name: name, Maps: maps}, nil
+	}
+
+	season, err := s.currentSeason(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := &domain.MapPool{
+		Name:          name,
+		Season:        season.Name,
+		EffectiveFrom: season.EffectiveFrom,
+		Maps:          seasonMaps(maps, season),
+	}
+
+	// A rotation naming maps the catalog does not know is a config mistake worth surfacing
+	if missing := len(season.MapUUIDs) - len(pool.Maps); missing > 0 {
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"pool":    name,
+			"season":  season.Name,
+			"missing": missing,
+		}).Warn("Rotation lists maps that are not in the catalog")
+	}
+
+	return pool, nil
+}
+
+// currentSeason returns the season of the pool that is in effect now
+func (s *RouletteService) currentSeason(pool string) (domain.Season, error) {
+	if s.rotation == nil {
+		return domain.Season{}, fmt.Errorf("%w: %q", ErrNoActivePool, pool)
+	}
+	return s.rotation.Current(pool, time.Now())
+}
+
+// seasonMaps returns the maps in the season's rotation, in rotation order
+func seasonMaps(maps []domain.Map, season domain.Season) []domain.Map {
+	byUUID := make(map[string]domain.Map, len(maps))
+	for _, m := range maps {
+		byUUID[m.UUID] = m
+	}
+
+	result := make([]domain.Map, 0, len(season.MapUUIDs))
+	for _, uuid := range season.MapUUIDs {
+		if m, ok := byUUID[uuid]; ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// SessionHistory returns the recent picks remembered for a session
+func (s *RouletteService) SessionHistory(ctx ctx.CTX, sessionID string) (*SessionHistory, error) {
+	if s.sessions == nil {
+		return nil, ErrNoSessions
+	}
+
+	recent, err := s.sessions.Recent(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionHistory{
+		SessionID: sessionID,
+		Recent:    recent,
+		Limit:     s.sessions.Limit(),
+		Mode:      s.sessionMode,
+	}, nil
+}
+
+// ResetSession forgets a session's recent picks
+func (s *RouletteService) ResetSession(ctx ctx.CTX, sessionID string) error {
+	if s.sessions == nil {
+		return ErrNoSessions
+	}
+	return s.sessions.Reset(ctx, sessionID)
+}
+
+// CatalogStatus returns the freshness of the in-memory map catalog
+func (s *RouletteService) CatalogStatus() catalog.Status {
+	if s.catalog == nil {
+		return catalog.Status{}
+	}
+	return s.catalog.Status()
+}
@@ -4,13 +4,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
-	"math/rand"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/jungtechou/valomap/config"
 	domain "github.com/jungtechou/valomap/domain/map"
 	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/source"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
 )
@@ -47,9 +48,7 @@ func createTestMaps(count int) []domain.Map {
 // BenchmarkFilterMaps benchmarks the performance of map filtering
 func BenchmarkFilterMaps(b *testing.B) {
 	testCtx := setupBenchmarkContext()
-	service := &RouletteService{
-		rng: rand.New(rand.NewSource(42)), // Fixed seed for reproducibility
-	}
+	service := &RouletteService{}
 
 	testCases := []struct {
 		name      string
@@ -193,11 +192,8 @@ func BenchmarkGetRandomMap(b *testing.B) {
 			// Configure mock behavior
 			mockTransport.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(resp, nil).Maybe()
 
-			// Create service with deterministic RNG
-			source := rand.NewSource(time.Now().UnixNano())
 			service := &RouletteService{
-				client: httpClient,
-				rng:    rand.New(source),
+				source: source.NewHTTPSource(httpClient, "", 0),
 			}
 
 			// Reset timer before benchmark loop
@@ -216,6 +212,35 @@ func BenchmarkGetRandomMap(b *testing.B) {
 	}
 }
 
+// BenchmarkGetRandomMapParallel benchmarks concurrent draws against a warm catalog.
+// Run with -race and a high -cpu value to check draws do not contend on shared state.
+func BenchmarkGetRandomMapParallel(b *testing.B) {
+	testCtx := setupBenchmarkContext()
+	mapSource := source.NewStaticSource(domain.MapPool{Maps: createTestMaps(20)})
+
+	service, cleanup := NewService(mapSource, nil, nil, nil, nil, &config.Config{
+		Catalog: config.CatalogConfig{TTL: time.Hour},
+	})
+	defer cleanup()
+
+	// Warm the catalog so the loop only measures selection
+	if _, err := service.GetAllMaps(testCtx); err != nil {
+		b.Fatal(err)
+	}
+
+	filter := MapFilter{StandardOnly: true, BannedMapIDs: []string{"map-A"}}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := service.GetRandomMap(testCtx, filter); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
 // Helper to create API response JSON
 func createAPIResponse(maps []domain.Map) []byte {
 	resp := domain.MapResponse{
@@ -293,12 +318,12 @@ func BenchmarkFetchMaps(b *testing.B) {
 			var service *RouletteService
 			if tc.withCache {
 				service = &RouletteService{
-					client:     httpClient,
+					source:     source.NewHTTPSource(httpClient, "", 0),
 					imageCache: mockCache,
 				}
 			} else {
 				service = &RouletteService{
-					client: httpClient,
+					source: source.NewHTTPSource(httpClient, "", 0),
 				}
 			}
 
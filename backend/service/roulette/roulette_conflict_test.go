@@ -3,17 +3,28 @@ package roulette
 import (
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/jungtechou/valomap/config"
 	domain "github.com/jungtechou/valomap/domain/map"
 	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/source"
 	"github.com/stretchr/testify/assert"
 )
 
 // Import setupTestContext from roulette_test.go - it's already defined there
 
+// newMapServer starts a stub map API serving the given maps
+func newMapServer(maps []domain.Map) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(createAPIResponse(maps))
+	}))
+}
+
 // mockRouletteService is a testable variant of RouletteService with replaceable functionality
 type mockRouletteService struct {
 	RouletteService
@@ -40,15 +51,14 @@ func TestConcurrentRandomMapAccess(t *testing.T) {
 		Timeout: 5 * time.Second,
 	}
 
-	// Use a shared RNG to test for potential thread safety issues
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
+	// Serve maps from a local stub instead of the public API
+	server := newMapServer(createTestMaps(10))
+	defer server.Close()
 
 	// Create mock service
 	service := &mockRouletteService{
 		RouletteService: RouletteService{
-			client: client,
-			rng:    rng,
+			source: source.NewHTTPSource(client, server.URL, 0),
 		},
 	}
 
@@ -129,14 +139,8 @@ func TestConcurrentFilterMapAccess(t *testing.T) {
 		t.Skip("Skipping concurrent test in short mode")
 	}
 
-	// Use a shared RNG
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
-
 	// Create service
-	service := &RouletteService{
-		rng: rng,
-	}
+	service := &RouletteService{}
 
 	// Generate test maps
 	testMaps := createTestMaps(20)
@@ -199,17 +203,13 @@ func TestConcurrentFilterMapAccess(t *testing.T) {
 	assert.Empty(t, errors, "Concurrent access should not cause errors")
 }
 
-// TestRaceConditions tests for potential race conditions when accessing the shared random number generator
+// TestRaceConditions tests for potential race conditions when drawing maps concurrently
 func TestRaceConditions(t *testing.T) {
 	// Skip in short mode
 	if testing.Short() {
 		t.Skip("Skipping race condition test in short mode")
 	}
 
-	// Create mock service with shared RNG
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
-
 	// Add HTTP client to prevent nil pointer dereference
 	client := &http.Client{
 		Timeout: 5 * time.Second,
@@ -217,8 +217,7 @@ func TestRaceConditions(t *testing.T) {
 
 	service := &mockRouletteService{
 		RouletteService: RouletteService{
-			rng:    rng,
-			client: client, // Initialize the client
+			source: source.NewHTTPSource(client, "", 0), // Initialize the source
 		},
 	}
 
@@ -258,3 +257,64 @@ func TestRaceConditions(t *testing.T) {
 	// Note: To detect actual race conditions, run with -race flag:
 	// go test -race ./...
 }
+
+// TestConcurrentDrawsWithCatalog draws from many goroutines through a shared catalog.
+// Run with -race to verify seed generation and selection share no unsynchronized state.
+func TestConcurrentDrawsWithCatalog(t *testing.T) {
+	mapSource := source.NewStaticSource(domain.MapPool{Maps: createTestMaps(10)})
+	service, cleanup := NewService(mapSource, nil, nil, nil, nil, &config.Config{
+		Catalog: config.CatalogConfig{TTL: time.Hour},
+	})
+	defer cleanup()
+
+	const numGoroutines = 64
+	const drawsPerGoroutine = 50
+
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+
+	type draw struct {
+		filter MapFilter
+		result *DrawResult
+	}
+
+	draws := make(chan draw, numGoroutines*drawsPerGoroutine)
+	errs := make(chan error, numGoroutines*drawsPerGoroutine)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			testCtx := setupTestContext()
+
+			for j := 0; j < drawsPerGoroutine; j++ {
+				filter := MapFilter{StandardOnly: id%2 == 0}
+				result, err := service.DrawMap(testCtx, filter, nil)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				draws <- draw{filter: filter, result: result}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(draws)
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("unexpected draw error: %v", err)
+	}
+
+	// Every generated seed must still reproduce its own draw
+	seen := 0
+	for d := range draws {
+		seed := d.result.Seed
+		replay, err := service.DrawMap(setupTestContext(), d.filter, &seed)
+		if assert.NoError(t, err) {
+			assert.Equal(t, d.result.Map.UUID, replay.Map.UUID)
+		}
+		seen++
+	}
+	assert.Equal(t, numGoroutines*drawsPerGoroutine, seen)
+}
@@ -51,7 +51,7 @@ To check for race conditions, run tests with the race detector:
 go test -race ./...
 ```
 
-This is especially important for this service due to the shared random number generator
+This is especially important for this service due to concurrent draws through the shared map catalog
 and potential concurrent access to external resources.
 */
 
@@ -5,12 +5,19 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
-	"math/rand"
 	"net/http"
+	"sort"
 	"testing"
+	"time"
 
+	"github.com/jungtechou/valomap/config"
 	domain "github.com/jungtechou/valomap/domain/map"
 	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/cache"
+	"github.com/jungtechou/valomap/service/history"
+	"github.com/jungtechou/valomap/service/rotation"
+	"github.com/jungtechou/valomap/service/session"
+	"github.com/jungtechou/valomap/service/source"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -53,6 +60,36 @@ func (m *MockImageCache) PrewarmCache(ctx ctx.CTX, urlMap map[string]string) err
 	return args.Error(0)
 }
 
+// Resolve mocks the Resolve method
+func (m *MockImageCache) Resolve(name string) (*cache.CachedFile, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*cache.CachedFile), args.Error(1)
+}
+
+// Variant mocks the Variant method
+func (m *MockImageCache) Variant(name string, opts cache.VariantOptions) (*cache.CachedFile, error) {
+	args := m.Called(name, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*cache.CachedFile), args.Error(1)
+}
+
+// Metrics mocks the Metrics method
+func (m *MockImageCache) Metrics() cache.Metrics {
+	args := m.Called()
+	return args.Get(0).(cache.Metrics)
+}
+
+// Images mocks the Images method
+func (m *MockImageCache) Images() []cache.ImageInfo {
+	args := m.Called()
+	return args.Get(0).([]cache.ImageInfo)
+}
+
 // Shutdown mocks the Shutdown method
 func (m *MockImageCache) Shutdown() {
 	m.Called()
@@ -65,6 +102,7 @@ func setupTestContext() ctx.CTX {
 	return ctx.CTX{FieldLogger: logrus.NewEntry(logger)}
 }
 
+
 // TestGetRandomMap tests the basic functionality of GetRandomMap
 func TestGetRandomMap(t *testing.T) {
 	// Create mock transport and HTTP client
@@ -101,10 +139,8 @@ func TestGetRandomMap(t *testing.T) {
 	// Configure mock to return the response
 	mt.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(httpResp, nil)
 
-	// Create service with deterministic RNG
 	service := &RouletteService{
-		client: httpClient,
-		rng:    rand.New(rand.NewSource(42)), // Use fixed seed for predictable results
+		source: source.NewHTTPSource(httpClient, "", 0),
 	}
 
 	// Get random map
@@ -123,9 +159,7 @@ func TestGetRandomMap(t *testing.T) {
 // TestFilterMaps tests the map filtering functionality
 func TestFilterMaps(t *testing.T) {
 	testCtx := setupTestContext()
-	service := &RouletteService{
-		rng: rand.New(rand.NewSource(42)),
-	}
+	service := &RouletteService{}
 
 	// Create test maps
 	standardMap1 := domain.Map{UUID: "map1", DisplayName: "Map One", TacticalDescription: "Standard map"}
@@ -162,27 +196,36 @@ func TestFilterMaps(t *testing.T) {
 
 // TestNewService tests the NewService function
 func TestNewService(t *testing.T) {
-	// Create mock HTTP client and cache
-	mockClient := &http.Client{}
+	// Create map source and mock cache
+	mapSource := source.NewHTTPSource(&http.Client{}, "", 0)
 	mockCache := new(MockImageCache)
+	sessions := session.NewMemoryStore(3, time.Hour)
 
 	// Expected mock call
 	mockCache.On("PrewarmCache", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 	// Test NewService
-	service := NewService(mockClient, mockCache)
+	service, cleanup := NewService(mapSource, mockCache, sessions, nil, nil, &config.Config{
+		Catalog: config.CatalogConfig{TTL: time.Minute, RefreshInterval: time.Minute},
+		Session: config.SessionConfig{Mode: session.ModeDownweight, RecentWeight: 0.5},
+	})
+	defer cleanup()
 
 	// Verify results
 	assert.NotNil(t, service)
+	assert.NotNil(t, cleanup)
 
 	// Assert the service is a *RouletteService
 	rouletteService, ok := service.(*RouletteService)
 	assert.True(t, ok, "Service should be a *RouletteService")
 
 	// Check fields
-	assert.Equal(t, mockClient, rouletteService.client)
+	assert.Equal(t, mapSource, rouletteService.source)
 	assert.Equal(t, mockCache, rouletteService.imageCache)
-	assert.NotNil(t, rouletteService.rng)
+	assert.NotNil(t, rouletteService.catalog)
+	assert.Equal(t, sessions, rouletteService.sessions)
+	assert.Equal(t, session.ModeDownweight, rouletteService.sessionMode)
+	assert.Equal(t, 0.5, rouletteService.recentWeight)
 
 	// Verify mock expectations
 	mockCache.AssertExpectations(t)
@@ -230,9 +273,8 @@ func TestGetAllMaps(t *testing.T) {
 
 	// Create service
 	service := &RouletteService{
-		client:     httpClient,
+		source:     source.NewHTTPSource(httpClient, "", 0),
 		imageCache: mockCache,
-		rng:        rand.New(rand.NewSource(42)),
 	}
 
 	// Get all maps
@@ -299,7 +341,7 @@ func TestFetchMaps(t *testing.T) {
 
 	// Create service
 	service := &RouletteService{
-		client: httpClient,
+		source: source.NewHTTPSource(httpClient, "", 0),
 	}
 
 	// Call fetchMaps
@@ -337,3 +379,468 @@ func TestFetchMaps(t *testing.T) {
 	// Verify all expectations
 	mt.AssertExpectations(t)
 }
+
+// TestGetRandomMapUsesCatalog tests that repeated requests are served from the catalog
+func TestGetRandomMapUsesCatalog(t *testing.T) {
+	// Create mock transport and HTTP client
+	mt := new(mockTransport)
+	httpClient := &http.Client{Transport: mt}
+
+	testMaps := []domain.Map{
+		{UUID: "map1", DisplayName: "Map One", TacticalDescription: "Standard map"},
+		{UUID: "map2", DisplayName: "Map Two", TacticalDescription: "Standard map"},
+	}
+	httpResp := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(createAPIResponse(testMaps))),
+	}
+
+	// The upstream must only be hit once
+	mt.On("RoundTrip", mock.AnythingOfType("*http.Request")).Return(httpResp, nil).Once()
+
+	service, cleanup := NewService(source.NewHTTPSource(httpClient, "", 0), nil, nil, nil, nil, &config.Config{
+		Catalog: config.CatalogConfig{TTL: time.Hour},
+	})
+	defer cleanup()
+
+	testCtx := setupTestContext()
+	for i := 0; i < 5; i++ {
+		result, err := service.GetRandomMap(testCtx, MapFilter{})
+		assert.NoError(t, err)
+		assert.Contains(t, []string{"map1", "map2"}, result.UUID)
+	}
+
+	status := service.CatalogStatus()
+	assert.Equal(t, 2, status.MapCount)
+	assert.False(t, status.Stale)
+
+	mt.AssertExpectations(t)
+}
+
+// TestDrawMapReproducible tests that the same seed and filter always select the same map
+func TestDrawMapReproducible(t *testing.T) {
+	testMaps := createTestMaps(10)
+	service := &RouletteService{
+		source: source.NewStaticSource(domain.MapPool{Maps: testMaps}),
+	}
+	testCtx := setupTestContext()
+	filter := MapFilter{StandardOnly: true, BannedMapIDs: []string{"map-B"}}
+
+	seed := int64(8675309)
+	first, err := service.DrawMap(testCtx, filter, &seed)
+	assert.NoError(t, err)
+	assert.Equal(t, seed, first.Seed)
+
+	// Candidates are in canonical UUID order and exclude filtered maps
+	assert.True(t, sort.StringsAreSorted(first.Candidates))
+	assert.NotContains(t, first.Candidates, "map-B")
+	assert.NotContains(t, first.Candidates, "map-A") // Non-standard map
+	assert.Contains(t, first.Candidates, first.Map.UUID)
+
+	// Upstream ordering must not affect the outcome
+	reversed := make([]domain.Map, len(testMaps))
+	for i, m := range testMaps {
+		reversed[len(testMaps)-1-i] = m
+	}
+	service.source = source.NewStaticSource(domain.MapPool{Maps: reversed})
+
+	for i := 0; i < 5; i++ {
+		again, err := service.DrawMap(testCtx, filter, &seed)
+		assert.NoError(t, err)
+		assert.Equal(t, first.Map.UUID, again.Map.UUID)
+		assert.Equal(t, first.Candidates, again.Candidates)
+	}
+
+	// Different seeds eventually select different maps
+	picked := make(map[string]bool)
+	for s := int64(0); s < 50; s++ {
+		result, err := service.DrawMap(testCtx, filter, &s)
+		assert.NoError(t, err)
+		picked[result.Map.UUID] = true
+	}
+	assert.Greater(t, len(picked), 1)
+}
+
+// TestDrawMapGeneratesSeed tests that a seed is generated and returned when none is given
+func TestDrawMapGeneratesSeed(t *testing.T) {
+	service := &RouletteService{
+		source: source.NewStaticSource(domain.MapPool{Maps: createTestMaps(5)}),
+	}
+	testCtx := setupTestContext()
+
+	result, err := service.DrawMap(testCtx, MapFilter{}, nil)
+	assert.NoError(t, err)
+
+	// Replaying the returned seed gives the same map
+	replay, err := service.DrawMap(testCtx, MapFilter{}, &result.Seed)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Map.UUID, replay.Map.UUID)
+
+	// Filter errors are passed through
+	_, err = service.DrawMap(testCtx, MapFilter{BannedMapIDs: result.Candidates}, nil)
+	assert.ErrorIs(t, err, ErrNoFilteredMaps)
+}
+
+// TestDrawMapWeighted tests that weights bias the draw and are validated
+func TestDrawMapWeighted(t *testing.T) {
+	service := &RouletteService{
+		source: source.NewStaticSource(domain.MapPool{Maps: createTestMaps(4)}),
+	}
+	testCtx := setupTestContext()
+
+	// Zero weights exclude maps, leaving a single possible pick
+	only := MapFilter{Weights: map[string]float64{"map-A": 0, "map-B": 0, "map-C": 0}}
+	for s := int64(0); s < 20; s++ {
+		result, err := service.DrawMap(testCtx, only, &s)
+		assert.NoError(t, err)
+		assert.Equal(t, "map-D", result.Map.UUID)
+		assert.Len(t, result.Candidates, 4)
+	}
+
+	// A heavily weighted map dominates the draws
+	biased := MapFilter{Weights: map[string]float64{"map-B": 97}}
+	counts := make(map[string]int)
+	for s := int64(0); s < 1000; s++ {
+		result, err := service.DrawMap(testCtx, biased, &s)
+		assert.NoError(t, err)
+		counts[result.Map.UUID]++
+	}
+	assert.Greater(t, counts["map-B"], 900)
+	assert.Greater(t, len(counts), 1, "Unlisted maps keep a default weight of 1")
+
+	// Weighted draws are reproducible
+	seed := int64(8675309)
+	first, err := service.DrawMap(testCtx, biased, &seed)
+	assert.NoError(t, err)
+	again, err := service.DrawMap(testCtx, biased, &seed)
+	assert.NoError(t, err)
+	assert.Equal(t, first.Map.UUID, again.Map.UUID)
+
+	// Negative weights are rejected
+	_, err = service.DrawMap(testCtx, MapFilter{Weights: map[string]float64{"map-A": -1}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidWeight)
+
+	// All candidates weighing zero leaves nothing to draw
+	allZero := MapFilter{
+		BannedMapIDs: []string{"map-D"},
+		Weights:      map[string]float64{"map-A": 0, "map-B": 0, "map-C": 0},
+	}
+	_, err = service.DrawMap(testCtx, allZero, nil)
+	assert.ErrorIs(t, err, ErrZeroWeights)
+}
+
+// TestDrawMapSessionHistory tests that a session avoids its recent picks
+func TestDrawMapSessionHistory(t *testing.T) {
+	service := &RouletteService{
+		source:      source.NewStaticSource(domain.MapPool{Maps: createTestMaps(5)}),
+		sessions:    session.NewMemoryStore(3, time.Hour),
+		sessionMode: session.ModeExclude,
+	}
+	testCtx := setupTestContext()
+	filter := MapFilter{SessionID: "scrim"}
+
+	// Consecutive draws never repeat one of the last three picks
+	var picks []string
+	for i := 0; i < 12; i++ {
+		result, err := service.DrawMap(testCtx, filter, nil)
+		assert.NoError(t, err)
+		for j := len(picks) - 1; j >= 0 && j >= len(picks)-3; j-- {
+			assert.NotEqual(t, picks[j], result.Map.UUID)
+		}
+		picks = append(picks, result.Map.UUID)
+	}
+
+	history, err := service.SessionHistory(testCtx, "scrim")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{picks[11], picks[10], picks[9]}, history.Recent)
+	assert.Equal(t, 3, history.Limit)
+	assert.Equal(t, session.ModeExclude, history.Mode)
+
+	// History never empties the pool on its own
+	twoMaps := MapFilter{SessionID: "scrim", BannedMapIDs: []string{"map-A", "map-B", "map-C"}}
+	for i := 0; i < 3; i++ {
+		_, err := service.DrawMap(testCtx, twoMaps, nil)
+		assert.NoError(t, err)
+	}
+
+	// Resetting forgets the picks
+	assert.NoError(t, service.ResetSession(testCtx, "scrim"))
+	history, err = service.SessionHistory(testCtx, "scrim")
+	assert.NoError(t, err)
+	assert.Empty(t, history.Recent)
+
+	// Draws without a session are not recorded
+	_, err = service.DrawMap(testCtx, MapFilter{}, nil)
+	assert.NoError(t, err)
+	history, err = service.SessionHistory(testCtx, "scrim")
+	assert.NoError(t, err)
+	assert.Empty(t, history.Recent)
+}
+
+// TestDrawMapSessionDownweight tests that down-weight mode keeps recent maps with a lower weight
+func TestDrawMapSessionDownweight(t *testing.T) {
+	sessions := session.NewMemoryStore(1, time.Hour)
+	service := &RouletteService{
+		source:       source.NewStaticSource(domain.MapPool{Maps: createTestMaps(2)}),
+		sessions:     sessions,
+		sessionMode:  session.ModeDownweight,
+		recentWeight: 0,
+	}
+	testCtx := setupTestContext()
+
+	// A zero recent weight forces the two maps to alternate
+	first, err := service.DrawMap(testCtx, MapFilter{SessionID: "scrim"}, nil)
+	assert.NoError(t, err)
+	second, err := service.DrawMap(testCtx, MapFilter{SessionID: "scrim"}, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.Map.UUID, second.Map.UUID)
+	assert.Len(t, second.Candidates, 2, "Down-weighted maps remain candidates")
+
+	// Caller weights are combined with, not replaced by, the history
+	weights := map[string]float64{first.Map.UUID: 5}
+	third, err := service.DrawMap(testCtx, MapFilter{SessionID: "scrim", Weights: weights}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{first.Map.UUID: 5}, weights)
+
+	// History that would leave no weight to draw from is ignored rather than failing the draw
+	onlyRecent := make(map[string]float64)
+	for _, uuid := range second.Candidates {
+		if uuid != third.Map.UUID {
+			onlyRecent[uuid] = 0
+		}
+	}
+	fourth, err := service.DrawMap(testCtx, MapFilter{SessionID: "scrim", Weights: onlyRecent}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, third.Map.UUID, fourth.Map.UUID)
+
+	// Without a store, history endpoints report that sessions are disabled
+	service.sessions = nil
+	_, err = service.SessionHistory(testCtx, "scrim")
+	assert.ErrorIs(t, err, ErrNoSessions)
+	assert.ErrorIs(t, service.ResetSession(testCtx, "scrim"), ErrNoSessions)
+}
+
+// TestDrawSeries tests that a series contains distinct maps and is reproducible
+func TestDrawSeries(t *testing.T) {
+	service := &RouletteService{
+		source:      source.NewStaticSource(domain.MapPool{Maps: createTestMaps(10)}),
+		sessions:    session.NewMemoryStore(5, time.Hour),
+		sessionMode: session.ModeExclude,
+	}
+	testCtx := setupTestContext()
+	filter := MapFilter{StandardOnly: true, BannedMapIDs: []string{"map-B"}}
+
+	seed := int64(8675309)
+	series, err := service.DrawSeries(testCtx, filter, 5, &seed)
+	assert.NoError(t, err)
+	assert.Len(t, series.Maps, 5)
+	assert.Equal(t, seed, series.Seed)
+	assert.Len(t, series.Candidates, 7) // 10 maps, 2 non-standard, 1 banned
+
+	seen := make(map[string]bool)
+	for _, m := range series.Maps {
+		assert.False(t, seen[m.UUID], "Series maps must be distinct")
+		assert.Contains(t, series.Candidates, m.UUID)
+		seen[m.UUID] = true
+	}
+
+	// The same seed replays the same series in the same order
+	again, err := service.DrawSeries(testCtx, filter, 5, &seed)
+	assert.NoError(t, err)
+	assert.Equal(t, series.Maps, again.Maps)
+
+	// A series of one matches a single draw with the same seed
+	single, err := service.DrawMap(testCtx, filter, &seed)
+	assert.NoError(t, err)
+	one, err := service.DrawSeries(testCtx, filter, 1, &seed)
+	assert.NoError(t, err)
+	assert.Equal(t, single.Map.UUID, one.Maps[0].UUID)
+
+	// The whole pool can be drawn
+	all, err := service.DrawSeries(testCtx, filter, 7, nil)
+	assert.NoError(t, err)
+	assert.Len(t, all.Maps, 7)
+
+	// Asking for more maps than the pool holds is an error
+	_, err = service.DrawSeries(testCtx, filter, 8, nil)
+	assert.ErrorIs(t, err, ErrNotEnoughMaps)
+
+	// Zero-weight maps do not count towards the pool
+	zeroed := MapFilter{Weights: map[string]float64{"map-A": 0, "map-B": 0, "map-C": 0}}
+	weighted, err := service.DrawSeries(testCtx, zeroed, 7, nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, mapUUIDs(weighted.Maps), "map-A")
+	_, err = service.DrawSeries(testCtx, zeroed, 8, nil)
+	assert.ErrorIs(t, err, ErrNotEnoughMaps)
+
+	_, err = service.DrawSeries(testCtx, filter, 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidCount)
+
+	// Session history is honoured only while the pool stays large enough
+	sessionFilter := MapFilter{SessionID: "scrim"}
+	first, err := service.DrawSeries(testCtx, sessionFilter, 5, nil)
+	assert.NoError(t, err)
+	second, err := service.DrawSeries(testCtx, sessionFilter, 5, nil)
+	assert.NoError(t, err)
+	for _, m := range second.Maps {
+		assert.NotContains(t, mapUUIDs(first.Maps), m.UUID)
+	}
+	_, err = service.DrawSeries(testCtx, sessionFilter, 10, nil)
+	assert.NoError(t, err)
+}
+
+// recordingStore collects the draws recorded by the roulette service
+type recordingStore struct {
+	draws []history.Draw
+	err   error
+}
+
+func (r *recordingStore) Record(ctx ctx.CTX, draws ...history.Draw) error {
+	r.draws = append(r.draws, draws...)
+	return r.err
+}
+
+func (r *recordingStore) List(ctx ctx.CTX, query history.Query) ([]history.Draw, int, error) {
+	return r.draws, len(r.draws), nil
+}
+
+func (r *recordingStore) Each(ctx ctx.CTX, query history.Query, fn func(history.Draw) error) error {
+	return nil
+}
+
+// TestDrawHistory tests that single draws and series are persisted to the history store
+func TestDrawHistory(t *testing.T) {
+	store := &recordingStore{}
+	service := &RouletteService{
+		source: source.NewStaticSource(domain.MapPool{Maps: createTestMaps(5)}),
+		draws:  store,
+	}
+	testCtx := setupTestContext()
+
+	seed := int64(42)
+	single, err := service.DrawMap(testCtx, MapFilter{SessionID: "scrim", BannedMapIDs: []string{"map-A"}, StandardOnly: true}, &seed)
+	assert.NoError(t, err)
+	assert.Len(t, store.draws, 1)
+	assert.Equal(t, "scrim", store.draws[0].SessionID)
+	assert.Equal(t, single.Map.UUID, store.draws[0].MapUUID)
+	assert.Equal(t, single.Map.DisplayName, store.draws[0].MapName)
+	assert.Equal(t, history.KindSingle, store.draws[0].Kind)
+	assert.Equal(t, 1, store.draws[0].Position)
+	assert.Equal(t, seed, store.draws[0].Seed)
+	assert.Equal(t, []string{"map-A"}, store.draws[0].Banned)
+	assert.True(t, store.draws[0].StandardOnly)
+	assert.False(t, store.draws[0].Weighted)
+	assert.Equal(t, single.Candidates, store.draws[0].Candidates)
+	assert.NotEmpty(t, store.draws[0].ID)
+	assert.False(t, store.draws[0].DrawnAt.IsZero())
+
+	series, err := service.DrawSeries(testCtx, MapFilter{}, 3, &seed)
+	assert.NoError(t, err)
+	assert.Len(t, store.draws, 4)
+	for i, m := range series.Maps {
+		d := store.draws[i+1]
+		assert.Equal(t, m.UUID, d.MapUUID)
+		assert.Equal(t, history.KindSeries, d.Kind)
+		assert.Equal(t, i+1, d.Position)
+		assert.Equal(t, seed, d.Seed)
+		assert.Equal(t, store.draws[1].DrawnAt, d.DrawnAt)
+	}
+
+	// Weighted draws are flagged
+	_, err = service.DrawMap(testCtx, MapFilter{Weights: map[string]float64{"map-A": 2}}, nil)
+	assert.NoError(t, err)
+	assert.True(t, store.draws[4].Weighted)
+
+	// Unrecorded draws pick the same map but leave the history alone
+	unrecorded, err := service.DrawMapUnrecorded(testCtx, MapFilter{SessionID: "scrim", BannedMapIDs: []string{"map-A"}, StandardOnly: true}, &seed)
+	assert.NoError(t, err)
+	assert.Equal(t, single, unrecorded)
+	assert.Len(t, store.draws, 5)
+
+	// A failing store does not fail the draw
+	store.err = errors.New("database is locked")
+	_, err = service.DrawMap(testCtx, MapFilter{}, nil)
+	assert.NoError(t, err)
+}
+
+// newTestRotation creates a rotation with a current and an upcoming competitive season
+func newTestRotation(t *testing.T) *rotation.Rotation {
+	r, err := rotation.New([]domain.Season{
+		{Name: "Current", Pool: domain.PoolCompetitive, EffectiveFrom: time.Now().Add(-24 * time.Hour), MapUUIDs: []string{"map-D", "map-B", "map-C", "map-gone"}},
+		{Name: "Upcoming", Pool: domain.PoolCompetitive, EffectiveFrom: time.Now().Add(24 * time.Hour), MapUUIDs: []string{"map-E"}},
+		{Name: "Premier", Pool: domain.PoolPremier, EffectiveFrom: time.Now().Add(-24 * time.Hour), MapUUIDs: []string{"map-A"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// TestDrawMapPool tests that a pool filter limits draws to the active rotation
+func TestDrawMapPool(t *testing.T) {
+	service := &RouletteService{
+		source:   source.NewStaticSource(domain.MapPool{Maps: createTestMaps(5)}),
+		rotation: newTestRotation(t),
+	}
+	testCtx := setupTestContext()
+
+	result, err := service.DrawMap(testCtx, MapFilter{Pool: domain.PoolCompetitive}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"map-B", "map-C", "map-D"}, result.Candidates)
+
+	// Pool, standard and ban filters combine
+	series, err := service.DrawSeries(testCtx, MapFilter{Pool: domain.PoolCompetitive, BannedMapIDs: []string{"map-B"}}, 2, nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"map-C", "map-D"}, series.Candidates)
+
+	// "all" applies no rotation
+	result, err = service.DrawMap(testCtx, MapFilter{Pool: domain.PoolAll}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, result.Candidates, 5)
+
+	// map-A is the only premier map, but it is not a standard map
+	_, err = service.DrawMap(testCtx, MapFilter{Pool: domain.PoolPremier, StandardOnly: true}, nil)
+	assert.ErrorIs(t, err, ErrNoStandardMaps)
+
+	_, err = service.DrawMap(testCtx, MapFilter{Pool: "casual"}, nil)
+	assert.ErrorIs(t, err, ErrUnknownPool)
+
+	// Without a rotation no pool is active
+	service.rotation = nil
+	_, err = service.DrawMap(testCtx, MapFilter{Pool: domain.PoolCompetitive}, nil)
+	assert.ErrorIs(t, err, ErrNoActivePool)
+}
+
+// TestGetPool tests listing the maps of the active rotation
+func TestGetPool(t *testing.T) {
+	service := &RouletteService{
+		source:   source.NewStaticSource(domain.MapPool{Maps: createTestMaps(5)}),
+		rotation: newTestRotation(t),
+	}
+	testCtx := setupTestContext()
+
+	// Competitive is the default; maps keep the rotation's order and unknown UUIDs are skipped
+	pool, err := service.GetPool(testCtx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PoolCompetitive, pool.Name)
+	assert.Equal(t, "Current", pool.Season)
+	assert.False(t, pool.EffectiveFrom.IsZero())
+	assert.Equal(t, []string{"map-D", "map-B", "map-C"}, mapUUIDs(pool.Maps))
+
+	pool, err = service.GetPool(testCtx, domain.PoolAll)
+	assert.NoError(t, err)
+	assert.Len(t, pool.Maps, 5)
+	assert.Empty(t, pool.Season)
+
+	_, err = service.GetPool(testCtx, "casual")
+	assert.ErrorIs(t, err, ErrUnknownPool)
+
+	// A rotation that names no known maps leaves draws with nothing to pick
+	r, err := rotation.New([]domain.Season{
+		{Name: "Stale", Pool: domain.PoolCompetitive, MapUUIDs: []string{"map-gone"}},
+	})
+	assert.NoError(t, err)
+	service.rotation = r
+	_, err = service.DrawMap(testCtx, MapFilter{Pool: domain.PoolCompetitive}, nil)
+	assert.ErrorIs(t, err, ErrEmptyPool)
+}
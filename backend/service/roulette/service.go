@@ -4,12 +4,47 @@ import (
 	domain "github.com/jungtechou/valomap/domain/map"
 	"github.com/jungtechou/valomap/pkg/ctx"
 	"github.com/jungtechou/valomap/service"
+	"github.com/jungtechou/valomap/service/catalog"
 )
 
 // MapFilter defines the filtering options for map selection
 type MapFilter struct {
 	StandardOnly bool
 	BannedMapIDs []string
+	// Weights biases selection by map UUID; maps without an entry weigh 1 and a weight of 0 excludes the map
+	Weights map[string]float64
+	// Pool restricts candidates to the active rotation of a pool (competitive, premier or all)
+	Pool string
+	// SessionID ties the draw to a session whose recent picks are avoided; empty disables history
+	SessionID string
+}
+
+// DrawResult is the outcome of a seeded roulette draw
+type DrawResult struct {
+	// Map is the selected map
+	Map domain.Map
+	// Seed reproduces this draw when passed back with the same filter
+	Seed int64
+	// Candidates lists the UUIDs of the eligible maps in the order used for selection
+	Candidates []string
+}
+
+// SeriesResult is the outcome of a seeded multi-map draw
+type SeriesResult struct {
+	// Maps are the distinct selected maps in play order
+	Maps []domain.Map
+	// Seed reproduces this series when passed back with the same filter and count
+	Seed int64
+	// Candidates lists the UUIDs of the eligible maps in the order used for selection
+	Candidates []string
+}
+
+// SessionHistory lists the picks a session currently avoids
+type SessionHistory struct {
+	SessionID string   `json:"sessionId" example:"scrim-night"`
+	Recent    []string `json:"recent" example:"7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+	Limit     int      `json:"limit" example:"3"`
+	Mode      string   `json:"mode" example:"exclude"`
 }
 
 var (
@@ -22,6 +57,27 @@ type Service interface {
 	// GetRandomMap returns a random map filtered by the provided options
 	GetRandomMap(ctx ctx.CTX, filter MapFilter) (*domain.Map, error)
 
+	// DrawMap returns a map chosen by the given seed, generating one when seed is nil
+	DrawMap(ctx ctx.CTX, filter MapFilter, seed *int64) (*DrawResult, error)
+
+	// DrawMapUnrecorded draws like DrawMap but leaves the session and draw history untouched
+	DrawMapUnrecorded(ctx ctx.CTX, filter MapFilter, seed *int64) (*DrawResult, error)
+
+	// DrawSeries returns count distinct maps in play order, generating a seed when seed is nil
+	DrawSeries(ctx ctx.CTX, filter MapFilter, count int, seed *int64) (*SeriesResult, error)
+
 	// GetAllMaps returns all available maps
 	GetAllMaps(ctx ctx.CTX) ([]domain.Map, error)
+
+	// GetPool returns the maps in the active rotation of the named pool
+	GetPool(ctx ctx.CTX, name string) (*domain.MapPool, error)
+
+	// SessionHistory returns the recent picks remembered for a session
+	SessionHistory(ctx ctx.CTX, sessionID string) (*SessionHistory, error)
+
+	// ResetSession forgets a session's recent picks
+	ResetSession(ctx ctx.CTX, sessionID string) error
+
+	// CatalogStatus returns the freshness of the in-memory map catalog
+	CatalogStatus() catalog.Status
 }
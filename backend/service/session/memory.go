@@ -0,0 +1,124 @@
+package session
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+)
+
+// MemoryStore keeps session history in process memory
+type MemoryStore struct {
+	size int
+	ttl  time.Duration
+
+	mutex    sync.Mutex
+	sessions map[string]*memorySession
+	now      func() time.Time
+}
+
+type memorySession struct {
+	recent    []string
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an in-memory store remembering size picks per session.
+// Sessions idle for longer than ttl are forgotten; a zero ttl keeps them forever.
+func NewMemoryStore(size int, ttl time.Duration) *MemoryStore {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &MemoryStore{
+		size:     size,
+		ttl:      ttl,
+		sessions: make(map[string]*memorySession),
+		now:      time.Now,
+	}
+}
+
+// Recent returns the session's remembered map UUIDs, newest first
+func (m *MemoryStore) Recent(ctx ctx.CTX, sessionID string) ([]string, error) {
+	if sessionID == "" {
+		return nil, ErrMissingSessionID
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sess := m.lookup(sessionID)
+	if sess == nil {
+		return []string{}, nil
+	}
+	return append([]string(nil), sess.recent...), nil
+}
+
+// Push records a pick, dropping the oldest entries beyond the history size
+func (m *MemoryStore) Push(ctx ctx.CTX, sessionID string, mapUUID string) error {
+	if sessionID == "" {
+		return ErrMissingSessionID
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sess := m.lookup(sessionID)
+	if sess == nil {
+		sess = &memorySession{}
+		m.sessions[sessionID] = sess
+	}
+
+	sess.recent = append([]string{mapUUID}, sess.recent...)
+	if len(sess.recent) > m.size {
+		sess.recent = sess.recent[:m.size]
+	}
+	if m.ttl > 0 {
+		sess.expiresAt = m.now().Add(m.ttl)
+	}
+
+	m.evictExpired()
+	return nil
+}
+
+// Reset forgets the session's history
+func (m *MemoryStore) Reset(ctx ctx.CTX, sessionID string) error {
+	if sessionID == "" {
+		return ErrMissingSessionID
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.sessions, sessionID)
+	return nil
+}
+
+// Limit returns how many picks are remembered per session
+func (m *MemoryStore) Limit() int {
+	return m.size
+}
+
+// lookup returns a live session or nil; callers must hold the mutex
+func (m *MemoryStore) lookup(sessionID string) *memorySession {
+	sess, ok := m.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+	if !sess.expiresAt.IsZero() && m.now().After(sess.expiresAt) {
+		delete(m.sessions, sessionID)
+		return nil
+	}
+	return sess
+}
+
+// evictExpired drops idle sessions so abandoned IDs do not accumulate; callers must hold the mutex
+func (m *MemoryStore) evictExpired() {
+	if m.ttl <= 0 {
+		return
+	}
+	now := m.now()
+	for id, sess := range m.sessions {
+		if now.After(sess.expiresAt) {
+			delete(m.sessions, id)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix = "valomap:session:"
+)
+
+// RedisStore keeps session history in Redis lists so it is shared between instances
+type RedisStore struct {
+	client redis.UniversalClient
+	size   int
+	ttl    time.Duration
+}
+
+// NewRedisStore creates a Redis-backed store remembering size picks per session.
+// Each session key expires after ttl without picks; a zero ttl keeps keys forever.
+func NewRedisStore(client redis.UniversalClient, size int, ttl time.Duration) *RedisStore {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &RedisStore{
+		client: client,
+		size:   size,
+		ttl:    ttl,
+	}
+}
+
+// Recent returns the session's remembered map UUIDs, newest first
+func (r *RedisStore) Recent(ctx ctx.CTX, sessionID string) ([]string, error) {
+	if sessionID == "" {
+		return nil, ErrMissingSessionID
+	}
+
+	recent, err := r.client.LRange(ctx.StdContext(), r.key(sessionID), 0, int64(r.size-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+	}
+	return recent, nil
+}
+
+// Push records a pick, dropping the oldest entries beyond the history size
+func (r *RedisStore) Push(ctx ctx.CTX, sessionID string, mapUUID string) error {
+	if sessionID == "" {
+		return ErrMissingSessionID
+	}
+
+	key := r.key(sessionID)
+	reqCtx := ctx.StdContext()
+	_, err := r.client.TxPipelined(reqCtx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(reqCtx, key, mapUUID)
+		pipe.LTrim(reqCtx, key, 0, int64(r.size-1))
+		if r.ttl > 0 {
+			pipe.Expire(reqCtx, key, r.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+	}
+	return nil
+}
+
+// Reset forgets the session's history
+func (r *RedisStore) Reset(ctx ctx.CTX, sessionID string) error {
+	if sessionID == "" {
+		return ErrMissingSessionID
+	}
+
+	if err := r.client.Del(ctx.StdContext(), r.key(sessionID)).Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+	}
+	return nil
+}
+
+// Limit returns how many picks are remembered per session
+func (r *RedisStore) Limit() int {
+	return r.size
+}
+
+func (r *RedisStore) key(sessionID string) string {
+	return redisKeyPrefix + sessionID
+}
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"errors"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+)
+
+const (
+	// DefaultHistorySize is used when no positive history size is configured
+	DefaultHistorySize = 3
+
+	// ModeExclude removes recently picked maps from the candidates
+	ModeExclude = "exclude"
+
+	// ModeDownweight keeps recently picked maps but lowers their weight
+	ModeDownweight = "downweight"
+)
+
+var (
+	ErrMissingSessionID = errors.New("session ID is required")
+	ErrStoreUnavailable = errors.New("session store unavailable")
+)
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// Store remembers the most recent picks of each roulette session
+type Store interface {
+	// Recent returns the session's remembered map UUIDs, newest first
+	Recent(ctx ctx.CTX, sessionID string) ([]string, error)
+
+	// Push records a pick, dropping the oldest entries beyond the history size
+	Push(ctx ctx.CTX, sessionID string, mapUUID string) error
+
+	// Reset forgets the session's history
+	Reset(ctx ctx.CTX, sessionID string) error
+
+	// Limit returns how many picks are remembered per session
+	Limit() int
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestContext creates a context for testing
+func setupTestContext() ctx.CTX {
+	logger := logrus.New()
+	logger.Out = io.Discard // Suppress logging during tests
+	return ctx.CTX{FieldLogger: logrus.NewEntry(logger)}
+}
+
+// newRedisStore starts an in-process Redis server for the store under test
+func newRedisStore(t *testing.T, size int, ttl time.Duration) (*RedisStore, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client, size, ttl), server
+}
+
+// testStoreBehaviour checks the contract shared by every Store implementation
+func testStoreBehaviour(t *testing.T, store Store) {
+	testCtx := setupTestContext()
+
+	recent, err := store.Recent(testCtx, "team-a")
+	require.NoError(t, err)
+	assert.Empty(t, recent)
+
+	for _, uuid := range []string{"ascent", "bind", "haven", "split"} {
+		require.NoError(t, store.Push(testCtx, "team-a", uuid))
+	}
+	require.NoError(t, store.Push(testCtx, "team-b", "lotus"))
+
+	// Only the newest picks are kept, newest first
+	recent, err = store.Recent(testCtx, "team-a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"split", "haven", "bind"}, recent)
+	assert.Equal(t, 3, store.Limit())
+
+	// Resetting one session leaves others alone
+	require.NoError(t, store.Reset(testCtx, "team-a"))
+	recent, err = store.Recent(testCtx, "team-a")
+	require.NoError(t, err)
+	assert.Empty(t, recent)
+
+	recent, err = store.Recent(testCtx, "team-b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lotus"}, recent)
+
+	// A session ID is required
+	_, err = store.Recent(testCtx, "")
+	assert.ErrorIs(t, err, ErrMissingSessionID)
+	assert.ErrorIs(t, store.Push(testCtx, "", "ascent"), ErrMissingSessionID)
+	assert.ErrorIs(t, store.Reset(testCtx, ""), ErrMissingSessionID)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStoreBehaviour(t, NewMemoryStore(3, time.Hour))
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	store := NewMemoryStore(0, time.Minute)
+	assert.Equal(t, DefaultHistorySize, store.Limit())
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	testCtx := setupTestContext()
+	require.NoError(t, store.Push(testCtx, "team-a", "ascent"))
+
+	// Idle sessions are forgotten after the TTL
+	now = now.Add(2 * time.Minute)
+	recent, err := store.Recent(testCtx, "team-a")
+	require.NoError(t, err)
+	assert.Empty(t, recent)
+
+	// Pushing to another session evicts expired ones
+	require.NoError(t, store.Push(testCtx, "team-b", "bind"))
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, store.Push(testCtx, "team-c", "split"))
+	assert.Len(t, store.sessions, 1)
+}
+
+func TestRedisStore(t *testing.T) {
+	store, _ := newRedisStore(t, 3, time.Hour)
+	testStoreBehaviour(t, store)
+}
+
+func TestRedisStore_Expiry(t *testing.T) {
+	store, server := newRedisStore(t, 3, time.Minute)
+	testCtx := setupTestContext()
+
+	require.NoError(t, store.Push(testCtx, "team-a", "ascent"))
+	assert.Equal(t, time.Minute, server.TTL(redisKeyPrefix+"team-a"))
+
+	server.FastForward(2 * time.Minute)
+	recent, err := store.Recent(testCtx, "team-a")
+	require.NoError(t, err)
+	assert.Empty(t, recent)
+}
+
+func TestRedisStore_Unavailable(t *testing.T) {
+	store, server := newRedisStore(t, 3, time.Hour)
+	server.Close()
+
+	testCtx := setupTestContext()
+	_, err := store.Recent(testCtx, "team-a")
+	assert.ErrorIs(t, err, ErrStoreUnavailable)
+	assert.ErrorIs(t, store.Push(testCtx, "team-a", "ascent"), ErrStoreUnavailable)
+	assert.ErrorIs(t, store.Reset(testCtx, "team-a"), ErrStoreUnavailable)
+}
@@ -0,0 +1,60 @@
+package source
+
+import (
+	"fmt"
+
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/sirupsen/logrus"
+)
+
+// Chain tries each source in order and returns the first successful result
+type Chain struct {
+	sources []MapSource
+}
+
+// NewChain creates a source that falls back through the given sources in order
+func NewChain(sources ...MapSource) *Chain {
+	return &Chain{sources: sources}
+}
+
+// Name identifies the source in logs
+func (c *Chain) Name() string {
+	return "chain"
+}
+
+// FetchMaps returns maps from the first source that succeeds. Maps from a
+// fallback source come with an error wrapping both ErrFallback and the primary
+// source's error, so that callers can tell them from fresh upstream data.
+// If every source fails, the error from the primary source is returned.
+func (c *Chain) FetchMaps(ctx ctx.CTX) ([]domain.Map, error) {
+	if len(c.sources) == 0 {
+		return nil, ErrNoSources
+	}
+
+	var firstErr error
+	for i, src := range c.sources {
+		maps, err := src.FetchMaps(ctx)
+		if err == nil {
+			if i > 0 {
+				ctx.FieldLogger.WithFields(logrus.Fields{
+					"source":    src.Name(),
+					"map_count": len(maps),
+				}).Warn("Serving maps from fallback source")
+				return maps, fmt.Errorf("%w from %s: %w", ErrFallback, src.Name(), firstErr)
+			}
+			return maps, nil
+		}
+
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"source": src.Name(),
+			"error":  err,
+		}).Warn("Map source failed")
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return nil, firstErr
+}
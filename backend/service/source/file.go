@@ -0,0 +1,119 @@
+package source
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/sirupsen/logrus"
+)
+
+// FileSource reads maps from a JSON snapshot on disk
+type FileSource struct {
+	path string
+}
+
+// NewFileSource creates a snapshot source stored at the given path
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+// Name identifies the source in logs
+func (f *FileSource) Name() string {
+	return "snapshot"
+}
+
+// Path returns the snapshot file location
+func (f *FileSource) Path() string {
+	return f.path
+}
+
+// FetchMaps reads the snapshot from disk
+func (f *FileSource) FetchMaps(ctx ctx.CTX) ([]domain.Map, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map snapshot: %w", err)
+	}
+
+	var mapResp domain.MapResponse
+	if err := json.Unmarshal(data, &mapResp); err != nil {
+		return nil, fmt.Errorf("failed to decode map snapshot: %w", err)
+	}
+	if len(mapResp.Data) == 0 {
+		return nil, ErrNoSnapshot
+	}
+
+	return mapResp.Data, nil
+}
+
+// Save writes the maps to disk, replacing any previous snapshot atomically
+func (f *FileSource) Save(maps []domain.Map) error {
+	data, err := json.Marshal(domain.MapResponse{Status: 200, Data: maps})
+	if err != nil {
+		return fmt.Errorf("failed to encode map snapshot: %w", err)
+	}
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write map snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write map snapshot: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// snapshottingSource records every successful upstream fetch to a snapshot file
+type snapshottingSource struct {
+	upstream MapSource
+	snapshot *FileSource
+}
+
+// WithSnapshot wraps an upstream source so that successful fetches are saved to the snapshot
+func WithSnapshot(upstream MapSource, snapshot *FileSource) MapSource {
+	return &snapshottingSource{
+		upstream: upstream,
+		snapshot: snapshot,
+	}
+}
+
+// Name identifies the source in logs
+func (s *snapshottingSource) Name() string {
+	return s.upstream.Name()
+}
+
+// FetchMaps fetches from upstream and saves the result
+func (s *snapshottingSource) FetchMaps(ctx ctx.CTX) ([]domain.Map, error) {
+	maps, err := s.upstream.FetchMaps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.snapshot.Save(maps); err != nil {
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"error": err,
+			"path":  s.snapshot.Path(),
+		}).Warn("Failed to save map snapshot")
+	}
+
+	return maps, nil
+}
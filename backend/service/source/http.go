@@ -0,0 +1,102 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultMapAPIURL = "https://valorant-api.com/v1/maps"
+)
+
+// HTTPSource fetches maps from the valorant-api.com compatible upstream
+type HTTPSource struct {
+	client  *http.Client
+	url     string
+	timeout time.Duration
+}
+
+// NewHTTPSource creates an upstream source; an empty URL falls back to the public API
+func NewHTTPSource(client *http.Client, url string, timeout time.Duration) *HTTPSource {
+	if url == "" {
+		url = DefaultMapAPIURL
+	}
+	return &HTTPSource{
+		client:  client,
+		url:     url,
+		timeout: timeout,
+	}
+}
+
+// Name identifies the source in logs
+func (s *HTTPSource) Name() string {
+	return "upstream"
+}
+
+// URL returns the upstream endpoint
+func (s *HTTPSource) URL() string {
+	return s.url
+}
+
+// FetchMaps fetches all maps from the API
+func (s *HTTPSource) FetchMaps(ctx ctx.CTX) ([]domain.Map, error) {
+	// Bound the upstream call by the configured timeout, on top of the caller's own deadline
+	reqCtx := ctx.Context
+	if reqCtx == nil {
+		reqCtx = context.Background()
+	}
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(reqCtx, s.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAPIRequest, err)
+	}
+
+	// Make the API request
+	resp, err := s.client.Do(req)
+	if err != nil {
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"error": err,
+			"url":   s.url,
+		}).Error("Failed to fetch maps from API")
+		return nil, fmt.Errorf("%w: %v", ErrAPIRequest, err)
+	}
+	defer resp.Body.Close()
+
+	// Check for non-200 status code
+	if resp.StatusCode != http.StatusOK {
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"status_code": resp.StatusCode,
+			"url":         s.url,
+		}).Error("Received non-OK status code from API")
+		return nil, fmt.Errorf("%w: status code %d", ErrAPIResponse, resp.StatusCode)
+	}
+
+	// Parse the response body
+	var mapResp domain.MapResponse
+	if err := json.NewDecoder(resp.Body).Decode(&mapResp); err != nil {
+		ctx.FieldLogger.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to decode API response")
+		return nil, fmt.Errorf("%w: %v", ErrAPIResponse, err)
+	}
+
+	// Validate the response data
+	if len(mapResp.Data) == 0 {
+		ctx.FieldLogger.Error("Received empty map list from API")
+		return nil, ErrEmptyMapList
+	}
+
+	return mapResp.Data, nil
+}
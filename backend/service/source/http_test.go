@@ -0,0 +1,127 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestContext creates a context for testing
+func setupTestContext() ctx.CTX {
+	logger := logrus.New()
+	logger.Out = io.Discard // Suppress logging during tests
+	return ctx.CTX{FieldLogger: logrus.NewEntry(logger)}
+}
+
+// testMaps returns a small map list for source tests
+func testMaps() []domain.Map {
+	return []domain.Map{
+		{UUID: "map1", DisplayName: "Map One", TacticalDescription: "A/B Sites"},
+		{UUID: "map2", DisplayName: "Map Two", TacticalDescription: "A/B Sites"},
+	}
+}
+
+// newMapServer starts a stub map API answering with the given status and maps
+func newMapServer(status int, maps []domain.Map) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(domain.MapResponse{Status: status, Data: maps})
+	}))
+}
+
+func TestNewHTTPSource(t *testing.T) {
+	src := NewHTTPSource(http.DefaultClient, "", time.Second)
+	assert.Equal(t, DefaultMapAPIURL, src.URL())
+	assert.Equal(t, "upstream", src.Name())
+
+	src = NewHTTPSource(http.DefaultClient, "http://mirror.local/maps", time.Second)
+	assert.Equal(t, "http://mirror.local/maps", src.URL())
+}
+
+func TestHTTPSource_FetchMaps(t *testing.T) {
+	server := newMapServer(http.StatusOK, testMaps())
+	defer server.Close()
+
+	src := NewHTTPSource(server.Client(), server.URL, time.Second)
+	maps, err := src.FetchMaps(setupTestContext())
+	require.NoError(t, err)
+	assert.Len(t, maps, 2)
+}
+
+func TestHTTPSource_Errors(t *testing.T) {
+	testCtx := setupTestContext()
+
+	// Non-OK status
+	server := newMapServer(http.StatusInternalServerError, nil)
+	src := NewHTTPSource(server.Client(), server.URL, time.Second)
+	_, err := src.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrAPIResponse)
+	server.Close()
+
+	// Empty map list
+	server = newMapServer(http.StatusOK, []domain.Map{})
+	src = NewHTTPSource(server.Client(), server.URL, time.Second)
+	_, err = src.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrEmptyMapList)
+	server.Close()
+
+	// Invalid JSON
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("invalid json"))
+	}))
+	src = NewHTTPSource(server.Client(), server.URL, time.Second)
+	_, err = src.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrAPIResponse)
+	server.Close()
+
+	// Unreachable upstream
+	_, err = src.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrAPIRequest)
+}
+
+func TestHTTPSource_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	src := NewHTTPSource(server.Client(), server.URL, 50*time.Millisecond)
+
+	start := time.Now()
+	maps, err := src.FetchMaps(setupTestContext())
+	assert.ErrorIs(t, err, ErrAPIRequest)
+	assert.Nil(t, maps)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestHTTPSource_HonorsCallerContext(t *testing.T) {
+	server := newMapServer(http.StatusOK, testMaps())
+	defer server.Close()
+
+	src := NewHTTPSource(server.Client(), server.URL, 0)
+
+	testCtx := setupTestContext()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	testCtx.Context = cancelled
+
+	maps, err := src.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrAPIRequest)
+	assert.Nil(t, maps)
+}
@@ -0,0 +1,35 @@
+package source
+
+import (
+	"errors"
+
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+)
+
+var (
+	ErrEmptyMapList = errors.New("received empty map list from API")
+	ErrAPIRequest   = errors.New("failed to make API request")
+	ErrAPIResponse  = errors.New("received invalid API response")
+	ErrNoSnapshot   = errors.New("no map snapshot available")
+	ErrNoSources    = errors.New("no map sources configured")
+
+	// ErrFallback accompanies maps served by a fallback source after the primary failed
+	ErrFallback = errors.New("serving fallback maps")
+)
+
+var (
+	_ MapSource = (*HTTPSource)(nil)
+	_ MapSource = (*FileSource)(nil)
+	_ MapSource = (*StaticSource)(nil)
+	_ MapSource = (*Chain)(nil)
+)
+
+// MapSource provides the full list of Valorant maps
+type MapSource interface {
+	// Name identifies the source in logs
+	Name() string
+
+	// FetchMaps returns all maps known to the source
+	FetchMaps(ctx ctx.CTX) ([]domain.Map, error)
+}
@@ -0,0 +1,156 @@
+package source
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSource is a MapSource returning fixed results
+type stubSource struct {
+	name  string
+	maps  []domain.Map
+	err   error
+	calls int
+}
+
+func (s *stubSource) Name() string { return s.name }
+
+func (s *stubSource) FetchMaps(ctx ctx.CTX) ([]domain.Map, error) {
+	s.calls++
+	return s.maps, s.err
+}
+
+func TestFileSource_SaveAndFetch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "maps.json")
+	src := NewFileSource(path)
+	testCtx := setupTestContext()
+
+	// Missing snapshot
+	_, err := src.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrNoSnapshot)
+
+	// Save then read back
+	require.NoError(t, src.Save(testMaps()))
+	maps, err := src.FetchMaps(testCtx)
+	require.NoError(t, err)
+	assert.Equal(t, testMaps(), maps)
+
+	// No temp files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Corrupt snapshot
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	_, err = src.FetchMaps(testCtx)
+	assert.Error(t, err)
+}
+
+func TestWithSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maps.json")
+	snapshot := NewFileSource(path)
+	testCtx := setupTestContext()
+
+	// Successful upstream fetches are recorded
+	upstream := &stubSource{name: "upstream", maps: testMaps()}
+	src := WithSnapshot(upstream, snapshot)
+	assert.Equal(t, "upstream", src.Name())
+
+	maps, err := src.FetchMaps(testCtx)
+	require.NoError(t, err)
+	assert.Len(t, maps, 2)
+
+	saved, err := snapshot.FetchMaps(testCtx)
+	require.NoError(t, err)
+	assert.Equal(t, testMaps(), saved)
+
+	// Failed fetches leave the snapshot untouched
+	upstream.maps = nil
+	upstream.err = ErrAPIRequest
+	_, err = src.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrAPIRequest)
+
+	saved, err = snapshot.FetchMaps(testCtx)
+	require.NoError(t, err)
+	assert.Len(t, saved, 2)
+}
+
+func TestStaticSource(t *testing.T) {
+	pool := domain.MapPool{Maps: testMaps()}
+	src := NewStaticSource(pool)
+	assert.Equal(t, "static", src.Name())
+
+	maps, err := src.FetchMaps(setupTestContext())
+	require.NoError(t, err)
+	assert.Equal(t, testMaps(), maps)
+
+	// Modifying the result must not change the pool
+	maps[0].DisplayName = "Modified"
+	assert.Equal(t, "Map One", pool.Maps[0].DisplayName)
+
+	_, err = NewStaticSource(domain.MapPool{}).FetchMaps(setupTestContext())
+	assert.ErrorIs(t, err, ErrEmptyMapList)
+}
+
+func TestChain(t *testing.T) {
+	testCtx := setupTestContext()
+
+	primary := &stubSource{name: "primary", err: ErrAPIRequest}
+	secondary := &stubSource{name: "secondary", err: ErrNoSnapshot}
+	last := &stubSource{name: "last", maps: testMaps()}
+
+	// Falls through to the first working source, reporting the fallback
+	chain := NewChain(primary, secondary, last)
+	maps, err := chain.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrFallback)
+	assert.ErrorIs(t, err, ErrAPIRequest)
+	assert.Len(t, maps, 2)
+	assert.Equal(t, 1, primary.calls)
+	assert.Equal(t, 1, secondary.calls)
+
+	// Later sources are not consulted once one succeeds
+	primary.err = nil
+	primary.maps = testMaps()[:1]
+	maps, err = chain.FetchMaps(testCtx)
+	require.NoError(t, err)
+	assert.Len(t, maps, 1)
+	assert.Equal(t, 1, secondary.calls)
+
+	// The primary error is reported when everything fails
+	primary.err = ErrAPIRequest
+	last.err = errors.New("broken")
+	_, err = chain.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrAPIRequest)
+
+	// An empty chain is an error
+	_, err = NewChain().FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrNoSources)
+}
+
+func TestChain_OfflineFallback(t *testing.T) {
+	testCtx := setupTestContext()
+	snapshot := NewFileSource(filepath.Join(t.TempDir(), "maps.json"))
+	pool := domain.MapPool{Maps: []domain.Map{{UUID: "static", DisplayName: "Static"}}}
+
+	// Upstream is unreachable and no snapshot exists: the static pool answers
+	upstream := NewHTTPSource(http.DefaultClient, "http://127.0.0.1:1/maps", 0)
+	chain := NewChain(WithSnapshot(upstream, snapshot), snapshot, NewStaticSource(pool))
+
+	maps, err := chain.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrFallback)
+	assert.Equal(t, "static", maps[0].UUID)
+
+	// Once a snapshot exists it is preferred over the static pool
+	require.NoError(t, snapshot.Save(testMaps()))
+	maps, err = chain.FetchMaps(testCtx)
+	assert.ErrorIs(t, err, ErrFallback)
+	assert.Equal(t, "map1", maps[0].UUID)
+}
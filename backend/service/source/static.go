@@ -0,0 +1,29 @@
+package source
+
+import (
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+)
+
+// StaticSource serves a fixed map pool compiled into the binary
+type StaticSource struct {
+	pool domain.MapPool
+}
+
+// NewStaticSource creates a source backed by the given pool
+func NewStaticSource(pool domain.MapPool) *StaticSource {
+	return &StaticSource{pool: pool}
+}
+
+// Name identifies the source in logs
+func (s *StaticSource) Name() string {
+	return "static"
+}
+
+// FetchMaps returns a copy of the pool
+func (s *StaticSource) FetchMaps(ctx ctx.CTX) ([]domain.Map, error) {
+	if len(s.pool.Maps) == 0 {
+		return nil, ErrEmptyMapList
+	}
+	return s.pool.AvailableMaps(nil), nil
+}
@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"math"
+)
+
+const (
+	// gammaEpsilon is the relative precision of the incomplete gamma evaluation
+	gammaEpsilon = 1e-12
+
+	// gammaMaxIterations bounds the series and continued fraction loops
+	gammaMaxIterations = 500
+)
+
+// fairnessTest accumulates observed and expected pick counts for a chi-squared test.
+// A uniform draw from n candidates expects each of them to be picked 1/n times.
+type fairnessTest struct {
+	draws    int
+	observed map[string]float64
+	expected map[string]float64
+}
+
+func newFairnessTest() *fairnessTest {
+	return &fairnessTest{
+		observed: make(map[string]float64),
+		expected: make(map[string]float64),
+	}
+}
+
+// add records a uniform draw of picked from candidates; draws without candidates are skipped
+func (f *fairnessTest) add(picked string, candidates []string) {
+	if len(candidates) == 0 {
+		return
+	}
+
+	f.draws++
+	f.observed[picked]++
+	share := 1 / float64(len(candidates))
+	for _, uuid := range candidates {
+		f.expected[uuid] += share
+	}
+}
+
+// result computes the test statistic and its p-value
+func (f *fairnessTest) result() Fairness {
+	result := Fairness{Draws: f.draws, PValue: 1}
+
+	cells := 0
+	minExpected := math.Inf(1)
+	for uuid, expected := range f.expected {
+		diff := f.observed[uuid] - expected
+		result.ChiSquared += diff * diff / expected
+		minExpected = math.Min(minExpected, expected)
+		cells++
+	}
+
+	// A single possible outcome cannot be biased
+	if cells < 2 {
+		return result
+	}
+
+	result.DegreesOfFreedom = cells - 1
+	result.PValue = chiSquaredSurvival(result.ChiSquared, result.DegreesOfFreedom)
+	result.Reliable = minExpected >= MinExpected
+	result.Biased = result.PValue < SignificanceLevel
+	return result
+}
+
+// chiSquaredSurvival returns P(X >= x) for a chi-squared distribution with k degrees of freedom
+func chiSquaredSurvival(x float64, k int) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return upperGamma(float64(k)/2, x/2)
+}
+
+// upperGamma returns the regularized upper incomplete gamma function Q(a, x),
+// using the power series below a+1 and a continued fraction above it
+func upperGamma(a, x float64) float64 {
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(a*math.Log(x) - x - lgamma)
+
+	if x < a+1 {
+		sum := 1 / a
+		term := sum
+		for n := 1; n < gammaMaxIterations; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*gammaEpsilon {
+				break
+			}
+		}
+		return math.Max(0, 1-sum*prefix)
+	}
+
+	// Modified Lentz evaluation of the continued fraction
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < gammaMaxIterations; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < gammaEpsilon {
+			break
+		}
+	}
+	return math.Min(1, prefix*h)
+}
@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service"
+)
+
+const (
+	// SignificanceLevel is the p-value below which the roulette is reported as biased
+	SignificanceLevel = 0.01
+
+	// MinExpected is the smallest expected count per map for the chi-squared test to be reliable
+	MinExpected = 5
+)
+
+// Query selects the draws to aggregate. Zero values leave a filter unset.
+type Query struct {
+	SessionID string
+	// From and To bound the draw time; From is inclusive and To exclusive
+	From time.Time
+	To   time.Time
+}
+
+// MapStats counts how one map fared in the selected draws
+type MapStats struct {
+	MapUUID string `json:"mapUuid" example:"7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+	MapName string `json:"mapName,omitempty" example:"Ascent"`
+	// Drawn counts every time the map was drawn, including each map of a series
+	Drawn int `json:"drawn" example:"12"`
+	// Banned counts the draws whose ban list included the map
+	Banned int `json:"banned" example:"3"`
+	// DrawRate is the map's share of all drawn maps
+	DrawRate float64 `json:"drawRate" example:"0.12"`
+}
+
+// Fairness is a chi-squared goodness-of-fit test of observed picks against uniform selection.
+// Only unweighted draws count, and only the first map of a series, since later maps are drawn
+// from a shrinking pool; each draw's own candidate list determines its expected counts.
+type Fairness struct {
+	Draws            int     `json:"draws" example:"240"`
+	ChiSquared       float64 `json:"chiSquared" example:"6.4"`
+	DegreesOfFreedom int     `json:"degreesOfFreedom" example:"6"`
+	PValue           float64 `json:"pValue" example:"0.38"`
+	// Reliable is false when some map was expected fewer than MinExpected times
+	Reliable bool `json:"reliable" example:"true"`
+	// Biased is set when the p-value is below SignificanceLevel
+	Biased bool `json:"biased" example:"false"`
+}
+
+// Report aggregates draw statistics per map
+type Report struct {
+	SessionID string     `json:"sessionId,omitempty" example:"scrim-night"`
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+	// Draws counts roulette draws, a series counting once
+	Draws int `json:"draws" example:"80"`
+	// Picks counts drawn maps, each map of a series counting once
+	Picks    int        `json:"picks" example:"100"`
+	Maps     []MapStats `json:"maps"`
+	Fairness Fairness   `json:"fairness"`
+}
+
+var (
+	_ Service = (*StatsService)(nil)
+)
+
+type Service interface {
+	service.Service
+
+	// MapStats aggregates how often each map was drawn and banned
+	MapStats(ctx ctx.CTX, query Query) (*Report, error)
+}
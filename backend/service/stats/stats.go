@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/history"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrDisabled     = history.ErrDisabled
+	ErrInvalidQuery = history.ErrInvalidQuery
+)
+
+type StatsService struct {
+	store history.Store
+}
+
+// NewService creates a stats service reading the draw history; a nil store means history is disabled
+func NewService(store history.Store) Service {
+	return &StatsService{store: store}
+}
+
+// MapStats aggregates how often each map was drawn and banned
+func (s *StatsService) MapStats(ctx ctx.CTX, query Query) (*Report, error) {
+	if s.store == nil {
+		return nil, ErrDisabled
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+
+	report := &Report{SessionID: query.SessionID}
+	if !query.From.IsZero() {
+		from := query.From.UTC()
+		report.From = &from
+	}
+	if !query.To.IsZero() {
+		to := query.To.UTC()
+		report.To = &to
+	}
+
+	maps := make(map[string]*MapStats)
+	entry := func(uuid string) *MapStats {
+		m, ok := maps[uuid]
+		if !ok {
+			m = &MapStats{MapUUID: uuid}
+			maps[uuid] = m
+		}
+		return m
+	}
+	fairness := newFairnessTest()
+
+	err := s.store.Each(ctx, history.Query{SessionID: query.SessionID, From: query.From, To: query.To}, func(d history.Draw) error {
+		m := entry(d.MapUUID)
+		m.Drawn++
+		if m.MapName == "" {
+			m.MapName = d.MapName
+		}
+		report.Picks++
+
+		// The filter is shared by every map of a series, so it is counted once per draw
+		if d.Position != 1 {
+			return nil
+		}
+		report.Draws++
+		for _, uuid := range d.Banned {
+			entry(uuid).Banned++
+		}
+		if !d.Weighted {
+			fairness.add(d.MapUUID, d.Candidates)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Maps = make([]MapStats, 0, len(maps))
+	for _, m := range maps {
+		if report.Picks > 0 {
+			m.DrawRate = float64(m.Drawn) / float64(report.Picks)
+		}
+		report.Maps = append(report.Maps, *m)
+	}
+	sort.Slice(report.Maps, func(i, j int) bool {
+		if report.Maps[i].Drawn != report.Maps[j].Drawn {
+			return report.Maps[i].Drawn > report.Maps[j].Drawn
+		}
+		return report.Maps[i].MapUUID < report.Maps[j].MapUUID
+	})
+	report.Fairness = fairness.result()
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"session_id":  query.SessionID,
+		"draws":       report.Draws,
+		"chi_squared": report.Fairness.ChiSquared,
+		"p_value":     report.Fairness.PValue,
+	}).Debug("Computed map statistics")
+
+	return report, nil
+}
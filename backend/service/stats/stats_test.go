@@ -0,0 +1,165 @@
+package stats
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/history"
+	"github.com/stretchr/testify/assert"
+)
+
+// sliceStore serves draws from memory and remembers the last query
+type sliceStore struct {
+	draws []history.Draw
+	err   error
+	query history.Query
+}
+
+func (s *sliceStore) Record(ctx ctx.CTX, draws ...history.Draw) error {
+	s.draws = append(s.draws, draws...)
+	return nil
+}
+
+func (s *sliceStore) List(ctx ctx.CTX, query history.Query) ([]history.Draw, int, error) {
+	return s.draws, len(s.draws), nil
+}
+
+func (s *sliceStore) Each(ctx ctx.CTX, query history.Query, fn func(history.Draw) error) error {
+	s.query = query
+	if s.err != nil {
+		return s.err
+	}
+	for _, d := range s.draws {
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMapStats(t *testing.T) {
+	candidates := []string{"ascent", "bind", "haven"}
+	store := &sliceStore{draws: []history.Draw{
+		{MapUUID: "ascent", MapName: "Ascent", Position: 1, Candidates: candidates, Banned: []string{"split"}},
+		{MapUUID: "bind", MapName: "Bind", Position: 1, Candidates: candidates, Banned: []string{"split", "lotus"}},
+		// A series counts as one draw and its ban list once
+		{MapUUID: "haven", MapName: "Haven", Kind: history.KindSeries, Position: 1, Candidates: candidates, Banned: []string{"split"}},
+		{MapUUID: "ascent", MapName: "Ascent", Kind: history.KindSeries, Position: 2, Candidates: candidates, Banned: []string{"split"}},
+		// Weighted draws are counted but left out of the fairness test
+		{MapUUID: "ascent", MapName: "Ascent", Position: 1, Candidates: candidates, Weighted: true},
+	}}
+	service := NewService(store)
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	report, err := service.MapStats(ctx.Background(), Query{SessionID: "scrims", From: from})
+	assert.NoError(t, err)
+	assert.Equal(t, history.Query{SessionID: "scrims", From: from}, store.query)
+
+	assert.Equal(t, "scrims", report.SessionID)
+	assert.Equal(t, &from, report.From)
+	assert.Nil(t, report.To)
+	assert.Equal(t, 4, report.Draws)
+	assert.Equal(t, 5, report.Picks)
+	assert.Equal(t, []MapStats{
+		{MapUUID: "ascent", MapName: "Ascent", Drawn: 3, DrawRate: 0.6},
+		{MapUUID: "bind", MapName: "Bind", Drawn: 1, DrawRate: 0.2},
+		{MapUUID: "haven", MapName: "Haven", Drawn: 1, DrawRate: 0.2},
+		{MapUUID: "lotus", Banned: 1},
+		{MapUUID: "split", Banned: 3},
+	}, report.Maps)
+
+	// One pick each from three equally likely maps fits perfectly but proves nothing
+	assert.Equal(t, 3, report.Fairness.Draws)
+	assert.Equal(t, 2, report.Fairness.DegreesOfFreedom)
+	assert.InDelta(t, 0, report.Fairness.ChiSquared, 1e-9)
+	assert.InDelta(t, 1, report.Fairness.PValue, 1e-9)
+	assert.False(t, report.Fairness.Reliable)
+	assert.False(t, report.Fairness.Biased)
+}
+
+func TestMapStatsEmpty(t *testing.T) {
+	report, err := NewService(&sliceStore{}).MapStats(ctx.Background(), Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Draws)
+	assert.Empty(t, report.Maps)
+	assert.NotNil(t, report.Maps)
+	assert.Equal(t, Fairness{PValue: 1}, report.Fairness)
+}
+
+func TestMapStatsErrors(t *testing.T) {
+	c := ctx.Background()
+
+	_, err := NewService(nil).MapStats(c, Query{})
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	now := time.Now()
+	_, err = NewService(&sliceStore{}).MapStats(c, Query{From: now, To: now})
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+
+	_, err = NewService(&sliceStore{err: errors.New("connection refused")}).MapStats(c, Query{})
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestFairnessDetectsBias(t *testing.T) {
+	candidates := []string{"a", "b", "c", "d", "e"}
+	r := rand.New(rand.NewPCG(1, 2))
+
+	// A uniform roulette passes
+	uniform := newFairnessTest()
+	for i := 0; i < 5000; i++ {
+		uniform.add(candidates[r.IntN(len(candidates))], candidates)
+	}
+	result := uniform.result()
+	assert.Equal(t, 5000, result.Draws)
+	assert.Equal(t, 4, result.DegreesOfFreedom)
+	assert.True(t, result.Reliable)
+	assert.False(t, result.Biased, "p-value %v", result.PValue)
+
+	// A roulette favouring one map fails
+	biased := newFairnessTest()
+	for i := 0; i < 5000; i++ {
+		picked := candidates[r.IntN(len(candidates))]
+		if r.Float64() < 0.1 {
+			picked = "a"
+		}
+		biased.add(picked, candidates)
+	}
+	result = biased.result()
+	assert.True(t, result.Biased, "p-value %v", result.PValue)
+
+	// Draws from smaller candidate lists raise those maps' expected counts
+	varying := newFairnessTest()
+	for i := 0; i < 3000; i++ {
+		pool := candidates
+		if i%2 == 0 {
+			pool = candidates[:2]
+		}
+		varying.add(pool[r.IntN(len(pool))], pool)
+	}
+	result = varying.result()
+	assert.False(t, result.Biased, "p-value %v", result.PValue)
+
+	// Draws without candidates are ignored
+	empty := newFairnessTest()
+	empty.add("a", nil)
+	assert.Equal(t, Fairness{PValue: 1}, empty.result())
+}
+
+func TestChiSquaredSurvival(t *testing.T) {
+	// Critical values at the 5% and 1% levels
+	assert.InDelta(t, 0.05, chiSquaredSurvival(3.841, 1), 1e-4)
+	assert.InDelta(t, 0.05, chiSquaredSurvival(11.070, 5), 1e-4)
+	assert.InDelta(t, 0.01, chiSquaredSurvival(23.209, 10), 1e-4)
+	assert.InDelta(t, 0.01, chiSquaredSurvival(6.635, 1), 1e-4)
+
+	// With two degrees of freedom the survival function is exp(-x/2)
+	for _, x := range []float64{0.5, 2, 10} {
+		assert.InDelta(t, math.Exp(-x/2), chiSquaredSurvival(x, 2), 1e-9)
+	}
+
+	assert.Equal(t, 1.0, chiSquaredSurvival(0, 3))
+}
@@ -0,0 +1,79 @@
+package veto
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultFormat is used when a session is created without a format
+	DefaultFormat = "bo3"
+)
+
+// Presets maps format shorthands to their step lists
+var Presets = map[string]string{
+	"bo1": "ban-ban-ban-ban-ban-ban-decider",
+	"bo3": "ban-ban-pick-pick-ban-ban-decider",
+	"bo5": "ban-ban-pick-pick-pick-pick-decider",
+}
+
+// ParseFormat expands a format into the turns of a session.
+// Bans and picks alternate between the teams, starting with the first team. Each pick is
+// followed by the other team choosing a side; the decider is drawn by the server and its
+// side is chosen by the team that did not take the last ban or pick.
+func ParseFormat(format string, teams [2]string) (string, []Step, error) {
+	if format == "" {
+		format = DefaultFormat
+	}
+	if preset, ok := Presets[strings.ToLower(format)]; ok {
+		format = preset
+	}
+
+	tokens := strings.Split(strings.ToLower(format), "-")
+	var steps []Step
+	turn := 0
+	chosen := 0
+
+	for i, token := range tokens {
+		switch token {
+		case ActionBan:
+			steps = append(steps, Step{Action: ActionBan, Team: teams[turn%2]})
+			turn++
+			chosen++
+		case ActionPick:
+			steps = append(steps,
+				Step{Action: ActionPick, Team: teams[turn%2]},
+				Step{Action: ActionSide, Team: teams[(turn+1)%2]},
+			)
+			turn++
+			chosen++
+		case ActionDecider:
+			if i != len(tokens)-1 {
+				return "", nil, fmt.Errorf("%w: the decider must be the last step", ErrInvalidFormat)
+			}
+			steps = append(steps,
+				Step{Action: ActionDecider, Team: DeciderTeam},
+				Step{Action: ActionSide, Team: teams[turn%2]},
+			)
+		default:
+			return "", nil, fmt.Errorf("%w: unknown step %q", ErrInvalidFormat, token)
+		}
+	}
+
+	if chosen == len(steps) {
+		return "", nil, fmt.Errorf("%w: a format needs at least one pick or a decider", ErrInvalidFormat)
+	}
+
+	return strings.Join(tokens, "-"), steps, nil
+}
+
+// mapsNeeded returns how many distinct maps a format consumes
+func mapsNeeded(steps []Step) int {
+	n := 0
+	for _, step := range steps {
+		if step.Action != ActionSide {
+			n++
+		}
+	}
+	return n
+}
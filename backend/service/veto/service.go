@@ -0,0 +1,114 @@
+package veto
+
+import (
+	"time"
+
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service"
+)
+
+const (
+	ActionBan     = "ban"
+	ActionPick    = "pick"
+	ActionSide    = "side"
+	ActionDecider = "decider"
+
+	SideAttack  = "attack"
+	SideDefense = "defense"
+
+	StatusInProgress = "in_progress"
+	StatusComplete   = "complete"
+
+	// DeciderTeam is recorded as the team for server-drawn decider maps
+	DeciderTeam = "decider"
+)
+
+// CreateRequest describes a new veto session
+type CreateRequest struct {
+	// Teams are the two team names; the first team acts first
+	Teams [2]string
+	// Format is a preset (bo1, bo3, bo5) or a dash-separated list of ban, pick and decider steps
+	Format string
+	// Pool is the map pool to veto from; empty means competitive
+	Pool string
+	// Seed makes the decider draw reproducible; generated when nil
+	Seed *int64
+}
+
+// Turn is one team's action in a veto session
+type Turn struct {
+	Team string
+	// Token is the secret returned for the acting team when the session was created
+	Token   string
+	Action  string
+	MapUUID string
+	Side    string
+}
+
+// Step is one planned turn of a veto format
+type Step struct {
+	Action string `json:"action" example:"ban"`
+	Team   string `json:"team" example:"Team Liquid"`
+}
+
+// Action is a turn that has been taken
+type Action struct {
+	Step    int       `json:"step" example:"0"`
+	Team    string    `json:"team" example:"Team Liquid"`
+	Action  string    `json:"action" example:"ban"`
+	MapUUID string    `json:"mapUuid,omitempty" example:"7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+	Side    string    `json:"side,omitempty" example:"attack"`
+	Seed    *int64    `json:"seed,string,omitempty" example:"8675309"`
+	At      time.Time `json:"at"`
+}
+
+// MapResult is one map of the final series in play order
+type MapResult struct {
+	Order        int    `json:"order" example:"1"`
+	MapUUID      string `json:"mapUuid" example:"7eaecc1b-4337-bbf6-6ab9-04b8f06b3319"`
+	DisplayName  string `json:"displayName" example:"Ascent"`
+	PickedBy     string `json:"pickedBy" example:"Team Liquid"`
+	SideChosenBy string `json:"sideChosenBy,omitempty" example:"Fnatic"`
+	Side         string `json:"side,omitempty" example:"defense"`
+}
+
+// Session is a snapshot of a veto session
+type Session struct {
+	ID        string       `json:"id" example:"0b8f3c2e-5d0a-4c55-9a53-2f9a8d1f6b7e"`
+	Teams     [2]string    `json:"teams"`
+	Format    string       `json:"format" example:"ban-ban-pick-pick-ban-ban-decider"`
+	Pool      string       `json:"pool" example:"competitive"`
+	Status    string       `json:"status" example:"in_progress"`
+	NextTurn  *Step        `json:"nextTurn,omitempty"`
+	Steps     []Step       `json:"steps"`
+	Actions   []Action     `json:"actions"`
+	Available []domain.Map `json:"available"`
+	Result    []MapResult  `json:"result"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+// Created is a new veto session together with the tokens that let each team act
+type Created struct {
+	Session
+	// Tokens holds one secret per team, in the order of Teams
+	Tokens [2]string `json:"tokens" example:"5f1d7c9e-3b0a-4a8e-9d55-6c2f0b7a1e34,9a0e4b6c-2d1f-4e7a-8c3b-1f5d6e7a8b9c"`
+}
+
+var (
+	_ Service = (*VetoService)(nil)
+)
+
+type Service interface {
+	service.Service
+
+	// Create starts a veto session and returns it with one token per team
+	Create(ctx ctx.CTX, req CreateRequest) (*Created, error)
+
+	// Get returns the current state of a veto session, drawing a pending decider first
+	Get(ctx ctx.CTX, id string) (*Session, error)
+
+	// Act takes the next turn of a veto session for the team holding the turn's token
+	Act(ctx ctx.CTX, id string, turn Turn) (*Session, error)
+}
@@ -0,0 +1,401 @@
+package veto
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/roulette"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SessionTTL is how long an untouched veto session is kept
+	SessionTTL = 6 * time.Hour
+)
+
+var (
+	ErrSessionNotFound = errors.New("veto session not found")
+	ErrInvalidTeams    = errors.New("a veto needs two distinct team names")
+	ErrInvalidFormat   = errors.New("invalid veto format")
+	ErrPoolTooSmall    = errors.New("map pool is too small for the veto format")
+	ErrVetoComplete    = errors.New("veto session is already complete")
+	ErrNotYourTurn     = errors.New("it is not this team's turn")
+	ErrWrongToken      = errors.New("token does not belong to the acting team")
+	ErrWrongAction     = errors.New("this action is not expected now")
+	ErrMapUnavailable  = errors.New("map is not available in this veto")
+	ErrInvalidSide     = errors.New("side must be attack or defense")
+)
+
+// vetoSession is a session together with the lock serialising its turns
+type vetoSession struct {
+	mutex sync.Mutex
+	// closed is set once the session expired and is being dropped
+	closed bool
+	state  Session
+	// tokens authorise the turns of each team, in the order of state.Teams
+	tokens [2]string
+	maps   domain.MapPool
+	seed   *int64
+}
+
+type VetoService struct {
+	roulette roulette.Service
+
+	mutex    sync.Mutex
+	sessions map[string]*vetoSession
+	now      func() time.Time
+}
+
+// NewService creates a veto service that draws decider maps through the roulette service
+func NewService(rouletteService roulette.Service) Service {
+	return &VetoService{
+		roulette: rouletteService,
+		sessions: make(map[string]*vetoSession),
+		now:      time.Now,
+	}
+}
+
+// Create starts a veto session and returns it with one token per team. Only the
+// holder of a team's token can take that team's turns.
+func (v *VetoService) Create(ctx ctx.CTX, req CreateRequest) (*Created, error) {
+	if req.Teams[0] == "" || req.Teams[1] == "" || req.Teams[0] == req.Teams[1] || req.Teams[0] == DeciderTeam || req.Teams[1] == DeciderTeam {
+		return nil, ErrInvalidTeams
+	}
+
+	format, steps, err := ParseFormat(req.Format, req.Teams)
+	if err != nil {
+		return nil, err
+	}
+
+	poolName := req.Pool
+	if poolName == "" {
+		poolName = domain.PoolCompetitive
+	}
+	pool, err := v.roulette.GetPool(ctx, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only standard maps can be vetoed, which matters for the "all" pool
+	var maps []domain.Map
+	for _, m := range pool.Maps {
+		if m.TacticalDescription != "" {
+			maps = append(maps, m)
+		}
+	}
+	if needed := mapsNeeded(steps); needed > len(maps) {
+		return nil, fmt.Errorf("%w: format %s needs %d maps but the %s pool has %d",
+			ErrPoolTooSmall, format, needed, poolName, len(maps))
+	}
+
+	now := v.now()
+	sess := &vetoSession{
+		state: Session{
+			ID:        uuid.NewString(),
+			Teams:     req.Teams,
+			Format:    format,
+			Pool:      poolName,
+			Status:    StatusInProgress,
+			Steps:     steps,
+			Actions:   []Action{},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		tokens: [2]string{uuid.NewString(), uuid.NewString()},
+		maps:   domain.MapPool{Name: poolName, Maps: maps},
+		seed:   req.Seed,
+	}
+
+	// A format without bans or picks goes straight to the decider
+	if err := v.advance(ctx, sess); err != nil {
+		return nil, err
+	}
+
+	v.pruneExpired(now)
+	v.mutex.Lock()
+	v.sessions[sess.state.ID] = sess
+	v.mutex.Unlock()
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"veto_id": sess.state.ID,
+		"format":  format,
+		"pool":    poolName,
+		"maps":    len(maps),
+	}).Info("Created veto session")
+
+	return &Created{Session: *sess.snapshot(), Tokens: sess.tokens}, nil
+}
+
+// Get returns the current state of a veto session. A decider draw that failed
+// after the last turn is retried first, so a session waiting only on its decider
+// completes without another turn.
+func (v *VetoService) Get(ctx ctx.CTX, id string) (*Session, error) {
+	sess, err := v.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+	if sess.closed {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+
+	if err := v.advance(ctx, sess); err != nil {
+		ctx.FieldLogger.WithError(err).WithField("veto_id", id).Warn("Failed to draw decider map")
+	}
+
+	return sess.snapshot(), nil
+}
+
+// Act takes the next turn of a veto session
+func (v *VetoService) Act(ctx ctx.CTX, id string, turn Turn) (*Session, error) {
+	sess, err := v.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+	if sess.closed {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+
+	// Retry a decider draw that failed after the previous turn
+	if err := v.advance(ctx, sess); err != nil {
+		return nil, err
+	}
+
+	step, ok := sess.nextStep()
+	if !ok {
+		return nil, ErrVetoComplete
+	}
+	if turn.Team != step.Team {
+		return nil, fmt.Errorf("%w: waiting for %s", ErrNotYourTurn, step.Team)
+	}
+	if !sess.authorises(step.Team, turn.Token) {
+		return nil, fmt.Errorf("%w: %s", ErrWrongToken, step.Team)
+	}
+	if turn.Action != step.Action {
+		return nil, fmt.Errorf("%w: %s must %s", ErrWrongAction, step.Team, step.Action)
+	}
+
+	action := Action{
+		Step:   len(sess.state.Actions),
+		Team:   turn.Team,
+		Action: turn.Action,
+		At:     v.now(),
+	}
+
+	switch turn.Action {
+	case ActionBan, ActionPick:
+		if !sess.isAvailable(turn.MapUUID) {
+			return nil, fmt.Errorf("%w: %s", ErrMapUnavailable, turn.MapUUID)
+		}
+		action.MapUUID = turn.MapUUID
+	case ActionSide:
+		if turn.Side != SideAttack && turn.Side != SideDefense {
+			return nil, ErrInvalidSide
+		}
+		action.Side = turn.Side
+	}
+
+	sess.record(action)
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"veto_id":  id,
+		"team":     turn.Team,
+		"action":   turn.Action,
+		"map_uuid": turn.MapUUID,
+		"side":     turn.Side,
+	}).Info("Recorded veto turn")
+
+	// The user's turn is kept even if the decider draw fails; the next call retries it
+	if err := v.advance(ctx, sess); err != nil {
+		ctx.FieldLogger.WithError(err).WithField("veto_id", id).Warn("Failed to draw decider map")
+	}
+
+	return sess.snapshot(), nil
+}
+
+// advance draws the decider map when it is next; callers must hold the session lock
+func (v *VetoService) advance(ctx ctx.CTX, sess *vetoSession) error {
+	step, ok := sess.nextStep()
+	if !ok || step.Action != ActionDecider {
+		return nil
+	}
+
+	// The decider comes from the maps still available, drawn like a roulette spin but
+	// kept out of the draw history, as it is part of the veto rather than a draw of its own
+	result, err := v.roulette.DrawMapUnrecorded(ctx, roulette.MapFilter{
+		StandardOnly: true,
+		BannedMapIDs: sess.usedMaps(),
+		Pool:         sess.state.Pool,
+	}, sess.seed)
+	if err != nil {
+		return err
+	}
+	if !sess.isAvailable(result.Map.UUID) {
+		return fmt.Errorf("%w: decider %s is not in the veto pool", ErrMapUnavailable, result.Map.UUID)
+	}
+
+	seed := result.Seed
+	sess.record(Action{
+		Step:    len(sess.state.Actions),
+		Team:    DeciderTeam,
+		Action:  ActionDecider,
+		MapUUID: result.Map.UUID,
+		Seed:    &seed,
+		At:      v.now(),
+	})
+
+	ctx.FieldLogger.WithFields(logrus.Fields{
+		"veto_id":  sess.state.ID,
+		"map_uuid": result.Map.UUID,
+		"seed":     seed,
+	}).Info("Drew veto decider")
+
+	return nil
+}
+
+// lookup finds a live session by ID
+func (v *VetoService) lookup(id string) (*vetoSession, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	sess, ok := v.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return sess, nil
+}
+
+// pruneExpired drops sessions untouched for longer than SessionTTL. Sessions are
+// checked one at a time without holding the service lock, so a slow turn cannot
+// stall every other session; an expired session is closed under its own lock so
+// that a turn waiting for it fails instead of acting on a dropped session.
+func (v *VetoService) pruneExpired(now time.Time) {
+	v.mutex.Lock()
+	sessions := make([]*vetoSession, 0, len(v.sessions))
+	for _, sess := range v.sessions {
+		sessions = append(sessions, sess)
+	}
+	v.mutex.Unlock()
+
+	var expired []string
+	for _, sess := range sessions {
+		sess.mutex.Lock()
+		if !sess.closed && now.Sub(sess.state.UpdatedAt) > SessionTTL {
+			sess.closed = true
+			expired = append(expired, sess.state.ID)
+		}
+		sess.mutex.Unlock()
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for _, id := range expired {
+		delete(v.sessions, id)
+	}
+}
+
+// authorises reports whether token is the secret of the named team
+func (s *vetoSession) authorises(team, token string) bool {
+	for i, name := range s.state.Teams {
+		if name == team {
+			return subtle.ConstantTimeCompare([]byte(token), []byte(s.tokens[i])) == 1
+		}
+	}
+	return false
+}
+
+// nextStep returns the step waiting to be taken, if any
+func (s *vetoSession) nextStep() (Step, bool) {
+	if len(s.state.Actions) >= len(s.state.Steps) {
+		return Step{}, false
+	}
+	return s.state.Steps[len(s.state.Actions)], true
+}
+
+// record appends a taken turn and marks the session complete after the last step
+func (s *vetoSession) record(action Action) {
+	s.state.Actions = append(s.state.Actions, action)
+	s.state.UpdatedAt = action.At
+	if len(s.state.Actions) == len(s.state.Steps) {
+		s.state.Status = StatusComplete
+	}
+}
+
+// usedMaps lists the UUIDs of maps already banned, picked or drawn
+func (s *vetoSession) usedMaps() []string {
+	var used []string
+	for _, a := range s.state.Actions {
+		if a.MapUUID != "" {
+			used = append(used, a.MapUUID)
+		}
+	}
+	return used
+}
+
+// available returns the maps that can still be banned, picked or drawn
+func (s *vetoSession) available() []domain.Map {
+	return s.maps.AvailableMaps(s.usedMaps())
+}
+
+// isAvailable reports whether the map can still be banned, picked or drawn
+func (s *vetoSession) isAvailable(uuid string) bool {
+	for _, m := range s.available() {
+		if m.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot copies the session state for callers, filling in derived fields
+func (s *vetoSession) snapshot() *Session {
+	state := s.state
+	state.Steps = append([]Step(nil), s.state.Steps...)
+	state.Actions = append([]Action(nil), s.state.Actions...)
+	state.Available = s.available()
+	if state.Available == nil {
+		state.Available = []domain.Map{}
+	}
+	if step, ok := s.nextStep(); ok {
+		state.NextTurn = &step
+	}
+
+	names := make(map[string]string, len(s.maps.Maps))
+	for _, m := range s.maps.Maps {
+		names[m.UUID] = m.DisplayName
+	}
+
+	state.Result = []MapResult{}
+	for _, a := range s.state.Actions {
+		switch a.Action {
+		case ActionPick, ActionDecider:
+			state.Result = append(state.Result, MapResult{
+				Order:       len(state.Result) + 1,
+				MapUUID:     a.MapUUID,
+				DisplayName: names[a.MapUUID],
+				PickedBy:    a.Team,
+			})
+		case ActionSide:
+			last := &state.Result[len(state.Result)-1]
+			last.SideChosenBy = a.Team
+			last.Side = a.Side
+		}
+	}
+
+	return &state
+}
@@ -0,0 +1,318 @@
+package veto
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jungtechou/valomap/config"
+	domain "github.com/jungtechou/valomap/domain/map"
+	"github.com/jungtechou/valomap/pkg/ctx"
+	"github.com/jungtechou/valomap/service/rotation"
+	"github.com/jungtechou/valomap/service/roulette"
+	"github.com/jungtechou/valomap/service/source"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestContext creates a context for testing
+func setupTestContext() ctx.CTX {
+	logger := logrus.New()
+	logger.Out = io.Discard // Suppress logging during tests
+	return ctx.CTX{FieldLogger: logrus.NewEntry(logger)}
+}
+
+// newTestService creates a veto service over a seven-map competitive pool
+func newTestService(t *testing.T) *VetoService {
+	var maps []domain.Map
+	var uuids []string
+	for i := 1; i <= 7; i++ {
+		uuid := fmt.Sprintf("map%d", i)
+		maps = append(maps, domain.Map{UUID: uuid, DisplayName: fmt.Sprintf("Map %d", i), TacticalDescription: "A/B Sites"})
+		uuids = append(uuids, uuid)
+	}
+	maps = append(maps, domain.Map{UUID: "range", DisplayName: "The Range"})
+
+	rot, err := rotation.New([]domain.Season{{Name: "Test", Pool: domain.PoolCompetitive, MapUUIDs: uuids[:6]}})
+	require.NoError(t, err)
+
+	rouletteService, cleanup := roulette.NewService(source.NewStaticSource(domain.MapPool{Maps: maps}), nil, nil, nil, rot, &config.Config{
+		Catalog: config.CatalogConfig{TTL: time.Hour},
+	})
+	t.Cleanup(cleanup)
+
+	return NewService(rouletteService).(*VetoService)
+}
+
+func TestParseFormat(t *testing.T) {
+	teams := [2]string{"Alpha", "Bravo"}
+
+	format, steps, err := ParseFormat("", teams)
+	require.NoError(t, err)
+	assert.Equal(t, Presets[DefaultFormat], format)
+	assert.Equal(t, []Step{
+		{Action: ActionBan, Team: "Alpha"},
+		{Action: ActionBan, Team: "Bravo"},
+		{Action: ActionPick, Team: "Alpha"},
+		{Action: ActionSide, Team: "Bravo"},
+		{Action: ActionPick, Team: "Bravo"},
+		{Action: ActionSide, Team: "Alpha"},
+		{Action: ActionBan, Team: "Alpha"},
+		{Action: ActionBan, Team: "Bravo"},
+		{Action: ActionDecider, Team: DeciderTeam},
+		{Action: ActionSide, Team: "Alpha"},
+	}, steps)
+	assert.Equal(t, 7, mapsNeeded(steps))
+
+	// Custom formats are accepted case-insensitively
+	format, steps, err = ParseFormat("Ban-Pick", teams)
+	require.NoError(t, err)
+	assert.Equal(t, "ban-pick", format)
+	assert.Len(t, steps, 3)
+
+	for _, bad := range []string{"ban-ban", "ban-decider-ban", "ban-veto-pick", "ban--pick"} {
+		_, _, err := ParseFormat(bad, teams)
+		assert.ErrorIs(t, err, ErrInvalidFormat, bad)
+	}
+}
+
+// signed adds the token of the turn's team, as that team's client would
+func signed(created *Created, turn Turn) Turn {
+	for i, team := range created.Teams {
+		if team == turn.Team {
+			turn.Token = created.Tokens[i]
+		}
+	}
+	return turn
+}
+
+func TestVeto_FullBo3(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+
+	// The rotation has six maps, so a bo3 (seven maps) does not fit
+	_, err := service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "bo3"})
+	assert.ErrorIs(t, err, ErrPoolTooSmall)
+
+	// The "all" pool offers the seven standard maps but never The Range
+	seed := int64(42)
+	created, err := service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "bo3", Pool: domain.PoolAll, Seed: &seed})
+	require.NoError(t, err)
+	sess := &created.Session
+	assert.Equal(t, StatusInProgress, sess.Status)
+	assert.Len(t, sess.Available, 7)
+	assert.Equal(t, &Step{Action: ActionBan, Team: "Alpha"}, sess.NextTurn)
+
+	turns := []Turn{
+		{Team: "Alpha", Action: ActionBan, MapUUID: "map1"},
+		{Team: "Bravo", Action: ActionBan, MapUUID: "map2"},
+		{Team: "Alpha", Action: ActionPick, MapUUID: "map3"},
+		{Team: "Bravo", Action: ActionSide, Side: SideAttack},
+		{Team: "Bravo", Action: ActionPick, MapUUID: "map4"},
+		{Team: "Alpha", Action: ActionSide, Side: SideDefense},
+		{Team: "Alpha", Action: ActionBan, MapUUID: "map5"},
+		{Team: "Bravo", Action: ActionBan, MapUUID: "map6"},
+	}
+	for _, turn := range turns {
+		sess, err = service.Act(testCtx, sess.ID, signed(created, turn))
+		require.NoError(t, err, "%+v", turn)
+	}
+
+	// The decider is drawn from the last map standing
+	require.Len(t, sess.Actions, 9)
+	decider := sess.Actions[8]
+	assert.Equal(t, ActionDecider, decider.Action)
+	assert.Equal(t, "map7", decider.MapUUID)
+	assert.Equal(t, &seed, decider.Seed)
+	assert.Equal(t, &Step{Action: ActionSide, Team: "Alpha"}, sess.NextTurn)
+
+	sess, err = service.Act(testCtx, sess.ID, signed(created, Turn{Team: "Alpha", Action: ActionSide, Side: SideAttack}))
+	require.NoError(t, err)
+	assert.Equal(t, StatusComplete, sess.Status)
+	assert.Nil(t, sess.NextTurn)
+	assert.Empty(t, sess.Available)
+	assert.Equal(t, []MapResult{
+		{Order: 1, MapUUID: "map3", DisplayName: "Map 3", PickedBy: "Alpha", SideChosenBy: "Bravo", Side: SideAttack},
+		{Order: 2, MapUUID: "map4", DisplayName: "Map 4", PickedBy: "Bravo", SideChosenBy: "Alpha", Side: SideDefense},
+		{Order: 3, MapUUID: "map7", DisplayName: "Map 7", PickedBy: DeciderTeam, SideChosenBy: "Alpha", Side: SideAttack},
+	}, sess.Result)
+
+	// Nothing more can happen
+	_, err = service.Act(testCtx, sess.ID, signed(created, Turn{Team: "Bravo", Action: ActionBan, MapUUID: "map7"}))
+	assert.ErrorIs(t, err, ErrVetoComplete)
+
+	got, err := service.Get(testCtx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sess.Result, got.Result)
+}
+
+func TestVeto_TurnValidation(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+
+	created, err := service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "ban-pick-decider"})
+	require.NoError(t, err)
+	sess := &created.Session
+	assert.Equal(t, domain.PoolCompetitive, sess.Pool)
+	assert.Len(t, sess.Available, 6)
+
+	testCases := []struct {
+		name string
+		turn Turn
+		err  error
+	}{
+		{"wrong team", Turn{Team: "Bravo", Action: ActionBan, MapUUID: "map1"}, ErrNotYourTurn},
+		{"wrong action", Turn{Team: "Alpha", Action: ActionPick, MapUUID: "map1"}, ErrWrongAction},
+		{"unknown map", Turn{Team: "Alpha", Action: ActionBan, MapUUID: "map7"}, ErrMapUnavailable},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.Act(testCtx, sess.ID, signed(created, tc.turn))
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	// Naming the team whose turn it is takes that team's token
+	assert.NotEqual(t, created.Tokens[0], created.Tokens[1])
+	for _, token := range []string{"", created.Tokens[1]} {
+		_, err = service.Act(testCtx, sess.ID, Turn{Team: "Alpha", Token: token, Action: ActionBan, MapUUID: "map1"})
+		assert.ErrorIs(t, err, ErrWrongToken)
+	}
+
+	_, err = service.Act(testCtx, sess.ID, signed(created, Turn{Team: "Alpha", Action: ActionBan, MapUUID: "map1"}))
+	require.NoError(t, err)
+
+	// Banned maps cannot be picked
+	_, err = service.Act(testCtx, sess.ID, signed(created, Turn{Team: "Bravo", Action: ActionPick, MapUUID: "map1"}))
+	assert.ErrorIs(t, err, ErrMapUnavailable)
+
+	_, err = service.Act(testCtx, sess.ID, signed(created, Turn{Team: "Bravo", Action: ActionPick, MapUUID: "map2"}))
+	require.NoError(t, err)
+
+	_, err = service.Act(testCtx, sess.ID, signed(created, Turn{Team: "Alpha", Action: ActionSide, Side: "middle"}))
+	assert.ErrorIs(t, err, ErrInvalidSide)
+
+	// The decider is drawn right after the side choice, excluding used maps
+	sess, err = service.Act(testCtx, sess.ID, signed(created, Turn{Team: "Alpha", Action: ActionSide, Side: SideDefense}))
+	require.NoError(t, err)
+	decider := sess.Actions[len(sess.Actions)-1]
+	assert.Equal(t, ActionDecider, decider.Action)
+	assert.NotContains(t, []string{"map1", "map2"}, decider.MapUUID)
+	assert.NotNil(t, decider.Seed)
+	assert.Equal(t, &Step{Action: ActionSide, Team: "Alpha"}, sess.NextTurn)
+
+	_, err = service.Get(testCtx, "missing")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = service.Act(testCtx, "missing", Turn{})
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestVeto_Create(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+
+	for _, teams := range [][2]string{{"", "Bravo"}, {"Alpha", "Alpha"}, {"Alpha", DeciderTeam}} {
+		_, err := service.Create(testCtx, CreateRequest{Teams: teams})
+		assert.ErrorIs(t, err, ErrInvalidTeams)
+	}
+
+	_, err := service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "ban-veto"})
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+
+	_, err = service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "decider", Pool: domain.PoolPremier})
+	assert.True(t, errors.Is(err, roulette.ErrNoActivePool))
+
+	// A decider-only format is resolved on creation
+	sess, err := service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "decider"})
+	require.NoError(t, err)
+	require.Len(t, sess.Result, 1)
+	assert.Equal(t, &Step{Action: ActionSide, Team: "Alpha"}, sess.NextTurn)
+
+	// Idle sessions are pruned when new ones are created
+	now := time.Now()
+	service.now = func() time.Time { return now.Add(SessionTTL + time.Minute) }
+	_, err = service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "decider"})
+	require.NoError(t, err)
+	_, err = service.Get(testCtx, sess.ID)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestVeto_PruneDoesNotBlockLookups(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+
+	busy, err := service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "ban-pick"})
+	require.NoError(t, err)
+	idle, err := service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "ban-pick"})
+	require.NoError(t, err)
+	sess, err := service.lookup(busy.ID)
+	require.NoError(t, err)
+
+	// While pruning waits for a session busy with a turn, other sessions stay reachable
+	now := time.Now().Add(SessionTTL + time.Minute)
+	sess.mutex.Lock()
+	done := make(chan struct{})
+	go func() {
+		service.pruneExpired(now)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	_, err = service.lookup(idle.ID)
+	assert.NoError(t, err)
+	sess.mutex.Unlock()
+	<-done
+
+	_, err = service.Get(testCtx, idle.ID)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// A turn that found the session before it was pruned does not act on it
+	service.mutex.Lock()
+	service.sessions[busy.ID] = sess
+	service.mutex.Unlock()
+	_, err = service.Act(testCtx, busy.ID, Turn{Team: "Alpha", Action: ActionBan, MapUUID: "map1"})
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+// failingDecider fails decider draws while fail is set
+type failingDecider struct {
+	roulette.Service
+	fail bool
+}
+
+func (f *failingDecider) DrawMapUnrecorded(ctx ctx.CTX, filter roulette.MapFilter, seed *int64) (*roulette.DrawResult, error) {
+	if f.fail {
+		return nil, roulette.ErrAPIRequest
+	}
+	return f.Service.DrawMapUnrecorded(ctx, filter, seed)
+}
+
+func TestVeto_GetRetriesDecider(t *testing.T) {
+	service := newTestService(t)
+	testCtx := setupTestContext()
+	draws := &failingDecider{Service: service.roulette}
+	service.roulette = draws
+
+	created, err := service.Create(testCtx, CreateRequest{Teams: [2]string{"Alpha", "Bravo"}, Format: "ban-decider"})
+	require.NoError(t, err)
+
+	// The turn is kept when the decider draw after it fails
+	draws.fail = true
+	sess, err := service.Act(testCtx, created.ID, signed(created, Turn{Team: "Alpha", Action: ActionBan, MapUUID: "map1"}))
+	require.NoError(t, err)
+	assert.Equal(t, &Step{Action: ActionDecider, Team: DeciderTeam}, sess.NextTurn)
+
+	sess, err = service.Get(testCtx, created.ID)
+	require.NoError(t, err)
+	assert.Len(t, sess.Actions, 1)
+
+	// Reading the session draws the decider once upstream recovers
+	draws.fail = false
+	sess, err = service.Get(testCtx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, sess.Actions, 2)
+	assert.Equal(t, ActionDecider, sess.Actions[1].Action)
+	assert.Equal(t, &Step{Action: ActionSide, Team: "Bravo"}, sess.NextTurn)
+}